@@ -1,6 +1,9 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -12,150 +15,370 @@ import (
 	"strings"
 )
 
-func main() {
+const (
+	FORMAT_TEXT string = "text"
+	FORMAT_JSON string = "json"
+	FORMAT_CSV  string = "csv"
+)
+
+var errUsage = errors.New("usage")
 
-	downServers := flag.String("downServers", "", "Comma-separated list of server IDs that have failed")
-	flag.Parse()
+// blockLocation is the server a block is stored on. ServerID is the server's position in
+// the cluster, and Server its name on the hash ring: its address if the servers were
+// read from a cluster config, or its BlockServerName otherwise.
+//
+// If blocks are replicated, Replicas are the servers the block is stored on, and the block
+// is read from the first of them that is not down. ServerID is -1 if all of them are down.
+type blockLocation struct {
+	BlockHash string            `json:"blockHash"`
+	ServerID  int               `json:"serverId"`
+	Server    string            `json:"server"`
+	Replicas  []replicaLocation `json:"replicas,omitempty"`
+}
+
+type replicaLocation struct {
+	ServerID int    `json:"serverId"`
+	Server   string `json:"server"`
+	Down     bool   `json:"down,omitempty"`
+}
 
-	if flag.NArg() != 3 {
-		fmt.Printf("Usage: %s numServers blockSize inpFilename\n", os.Args[0])
-		flag.PrintDefaults()
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if errors.Is(err, errUsage) {
 		os.Exit(1)
 	}
-
-	numServers, err := strconv.Atoi(flag.Arg(0))
 	if err != nil {
-		log.Fatal("Invalid number of servers argument: ", flag.Arg(0))
+		log.Fatal(err)
 	}
+}
 
-	blockSize, err := strconv.Atoi(flag.Arg(1))
-	if err != nil {
-		log.Fatal("Invalid block size argument: ", flag.Arg(0))
+// run maps the blocks of the input file to the servers they are stored on,
+// and prints the mapping to stdout
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	downServers := flags.String("downServers", "", "Comma-separated list of server IDs that have failed")
+	configPath := flags.String("config", "", "Cluster config file to read the server addresses from, instead of numServers")
+	format := flags.String("format", FORMAT_TEXT, "Output format: text, json or csv")
+	virtualNodes := flags.Int("virtualNodes", 0, "Points on the hash ring for every unit of a server's weight (default from the cluster config, or 1)")
+	report := flags.Bool("report", false, "Print the load on each server and the blocks moved when a server is added or removed, instead of the mapping")
+	addServer := flags.String("addServer", "", "Server added to the ring in the report (default blockserver<numServers>)")
+	replicas := flags.Int("replicas", 0, "Number of servers each block is stored on (default from the cluster config, or 1)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s numServers blockSize inpFilename\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "       %s -config cluster.json blockSize inpFilename\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	numArgs := 3
+	if *configPath != "" {
+		numArgs = 2
+	}
+	if flags.NArg() != numArgs {
+		flags.Usage()
+		return errUsage
+	}
+	switch *format {
+	case FORMAT_TEXT, FORMAT_JSON:
+	case FORMAT_CSV:
+		if *report {
+			return fmt.Errorf("the report cannot be printed as %s", *format)
+		}
+	default:
+		return fmt.Errorf("invalid format argument: %s", *format)
+	}
+	if *virtualNodes < 0 {
+		return fmt.Errorf("invalid virtual nodes argument: %d", *virtualNodes)
+	}
+
+	c := &cluster{virtualNodes: 1, replicas: 1}
+	if *configPath != "" {
+		config, err := servestore.LoadClusterConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if len(config.BlockStoreAddrs) == 0 {
+			return fmt.Errorf("cluster config %s lists no BlockStore addresses", *configPath)
+		}
+		for _, addr := range config.BlockStoreAddrs {
+			c.servers = append(c.servers, addr)
+			c.weights = append(c.weights, config.Weight(addr))
+		}
+		if config.VirtualNodes > 0 {
+			c.virtualNodes = config.VirtualNodes
+		}
+		c.replicas = config.Replicas()
+	} else {
+		numServers, err := strconv.Atoi(flags.Arg(0))
+		if err != nil || numServers <= 0 {
+			return fmt.Errorf("invalid number of servers argument: %s", flags.Arg(0))
+		}
+		for i := 0; i < numServers; i++ {
+			c.servers = append(c.servers, servestore.BlockServerName(i))
+			c.weights = append(c.weights, 1)
+		}
+	}
+	if *virtualNodes > 0 {
+		c.virtualNodes = *virtualNodes
+	}
+	if *replicas != 0 {
+		c.replicas = *replicas
+	}
+	if c.replicas < 1 || c.replicas > len(c.servers) {
+		return fmt.Errorf("invalid replicas argument: %d, must be between 1 and the number of servers", c.replicas)
 	}
 
-	inpFilename := flag.Arg(2)
+	blockSize, err := strconv.Atoi(flags.Arg(numArgs - 2))
+	if err != nil || blockSize <= 0 {
+		return fmt.Errorf("invalid block size argument: %s", flags.Arg(numArgs-2))
+	}
+
+	inpFilename := flags.Arg(numArgs - 1)
 
-	log.Println("Total number of blockStore servers: ", numServers)
+	log.Println("Total number of blockStore servers: ", len(c.servers))
 	log.Println("Block size: ", blockSize)
 	log.Println("Processing input data filename: ", inpFilename)
 
-	downServersMap := make(map[int]int)
+	downServerIDs, err := parseDownServers(*downServers, len(c.servers))
+	if err != nil {
+		return err
+	}
+	if len(downServerIDs) == 0 {
+		log.Println("No servers are in a failed state")
+	}
+
+	blockHashes, err := getFileBlockHashes(inpFilename, blockSize)
+	if err != nil {
+		return err
+	}
+
+	// Blocks stored on a single server move to the next server when it is down. Replicated
+	// blocks stay on their replicas, and are read from the first replica that is up.
+	ring := c.hashRing(downServerIDs)
+	if len(ring.ServerMap) == 0 {
+		return errors.New("all servers are down")
+	}
 
-	if *downServers != "" {
-		for _, downServer := range strings.Split(*downServers, ",") {
-			server, err := strconv.Atoi(downServer)
-			if err != nil {
-				log.Println("Error converting", downServer, "to int")
-			}
-			downServersMap[server] = server
+	if *report {
+		if *addServer == "" {
+			*addServer = servestore.BlockServerName(len(c.servers))
 		}
+		loadReport, err := c.loadReport(blockHashes, ring, *addServer)
+		if err != nil {
+			return err
+		}
+		if *format == FORMAT_JSON {
+			return writeJSON(stdout, loadReport)
+		}
+		return writeReport(stdout, loadReport)
+	}
+
+	var locations []blockLocation
+	if c.replicas == 1 {
+		locations = c.locateBlocks(blockHashes, ring)
 	} else {
-		log.Println("No servers are in a failed state")
+		locations = c.locateReplicas(blockHashes, c.hashRing(nil), downServerIDs)
 	}
 
-	/**
-	 * Steps:
-	 * 1. Break `inpFilename` into blocks of size `blockSize`
-	 * 2. Compute hash for each block (SHA-256)
-	 * 3. Compute hash for each server (blockstore0, blockstore1, ..., blockstore`n-1`)
-	 * 3. Use consistent hashing algorithm in lecture to map blocks to server number
-	 * 4. Return pairs where first is block hash and second is blockstore server number
-	 **/
-
-	blockHashes := getFileBlockHashes(inpFilename, blockSize)
-	serverHashes, serverHashesMap := getServerHashes(numServers)
-
-	sort.Strings(serverHashes)
-	sort.Strings(blockHashes)
-	// fmt.Println("Server Hashes:", serverHashes)
-	// fmt.Println("Server Hashes Map:", serverHashesMap)
-
-	pairs := "{"
-	for _, blockHash := range blockHashes {
-		server := serverHashesMap[serverHashes[0]]
-
-		serverIndex := 0
-		for s, serverHash := range serverHashes {
-			// fmt.Println("blockHash", blockHash, "serverHash", serverHash)
-			if blockHash < serverHash {
-				server = serverHashesMap[serverHash]
-				serverIndex = s
-				// fmt.Println("server", server, "serverIndex", serverIndex)
-				break
-			}
+	switch *format {
+	case FORMAT_JSON:
+		return writeJSON(stdout, locations)
+	case FORMAT_CSV:
+		return writeCSV(stdout, locations)
+	}
+	return writeText(stdout, locations, *configPath != "")
+}
+
+// parseDownServers parses the comma-separated IDs of failed servers
+func parseDownServers(downServers string, numServers int) ([]int, error) {
+	if downServers == "" {
+		return nil, nil
+	}
+
+	ids := make([]int, 0)
+	for _, downServer := range strings.Split(downServers, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(downServer))
+		if err != nil || id < 0 || id >= numServers {
+			return nil, fmt.Errorf("invalid down server ID: %q", downServer)
 		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
 
-		for {
-			_, exists := downServersMap[server]
-			if !exists {
-				break
-			}
+// cluster is the servers blocks are located on, by ID
+type cluster struct {
+	servers      []string
+	weights      []int
+	virtualNodes int
+	replicas     int
+}
 
-			if serverIndex == len(serverHashes)-1 {
-				serverIndex = 0
-			} else {
-				serverIndex += 1
-			}
+// hashRing returns the hash ring of the servers that are not down
+func (c *cluster) hashRing(downServerIDs []int) *servestore.ConsistentHashRing {
+	down := make(map[int]bool)
+	for _, id := range downServerIDs {
+		down[id] = true
+	}
 
-			server = serverHashesMap[serverHashes[serverIndex]]
+	ring := servestore.NewWeightedConsistentHashRing(c.virtualNodes)
+	for id, server := range c.servers {
+		if !down[id] {
+			ring.InsertWeightedServer(server, c.weights[id])
 		}
+	}
+	return ring
+}
 
-		pairs += "{"
-		pairs += blockHash
-		pairs += ", "
-		pairs += strconv.Itoa(server)
-		pairs += "}, "
+func (c *cluster) serverIDs() map[string]int {
+	serverIDs := make(map[string]int)
+	for id, server := range c.servers {
+		serverIDs[server] = id
 	}
-	pairs = pairs[:len(pairs)-2] + "}"
+	return serverIDs
+}
 
-	fmt.Println(pairs)
+// locateBlocks maps each block, in order of block hash, to the server responsible
+// for it on the ring
+func (c *cluster) locateBlocks(blockHashes []string, ring *servestore.ConsistentHashRing) []blockLocation {
+	serverIDs := c.serverIDs()
 
-	// This is an example of the format of the output
-	// Your program will emit pairs for each block has where the
-	// first part of the pair is the block hash, and the second
-	// element is the server number that the block resides on
-	//
-	// This output is simply to show the format, the actual mapping
-	// isn't based on consistent hashing necessarily
-	// fmt.Println("{{672e9bff6a0bc59669954be7b2c2726a74163455ca18664cc350030bc7eca71e, 7}, {31f28d5a995dcdb7c5358fcfa8b9c93f2b8e421fb4a268ca5dc01ca4619dfe5f,2}, {172baa036a7e9f8321cb23a1144787ba1a0727b40cb6283dbb5cba20b84efe50,1}, {745378a914d7bcdc26d3229f98fc2c6887e7d882f42d8491530dfaf4effef827,5}, {912b9d7afecb114fdaefecfa24572d052dde4e1ad2360920ebfe55ebf2e1818e,0}}")
+	sortedBlockHashes := append([]string(nil), blockHashes...)
+	sort.Strings(sortedBlockHashes)
+
+	servers := ring.GetResponsibleServers(sortedBlockHashes)
+	locations := make([]blockLocation, 0, len(sortedBlockHashes))
+	for i, blockHash := range sortedBlockHashes {
+		locations = append(locations, blockLocation{
+			BlockHash: blockHash,
+			ServerID:  serverIDs[servers[i]],
+			Server:    servers[i],
+		})
+	}
+	return locations
 }
 
-func getServerHashes(numServers int) ([]string, map[string]int) {
-	hashes := make([]string, 0)
-	hashesMap := make(map[string]int)
-	for i := 0; i < numServers; i++ {
-		hash := servestore.GetBlockHashString([]byte("blockserver" + strconv.Itoa(i)))
-		hashes = append(hashes, hash)
-		hashesMap[hash] = i
+// locateReplicas maps each block, in order of block hash, to the replicas it is stored on
+// and the first of them that is not down
+func (c *cluster) locateReplicas(blockHashes []string, ring *servestore.ConsistentHashRing, downServerIDs []int) []blockLocation {
+	serverIDs := c.serverIDs()
+	down := make(map[int]bool)
+	for _, id := range downServerIDs {
+		down[id] = true
+	}
+
+	sortedBlockHashes := append([]string(nil), blockHashes...)
+	sort.Strings(sortedBlockHashes)
+
+	locations := make([]blockLocation, 0, len(sortedBlockHashes))
+	for _, blockHash := range sortedBlockHashes {
+		location := blockLocation{BlockHash: blockHash, ServerID: -1}
+		for _, server := range ring.GetReplicaServers(blockHash, c.replicas) {
+			id := serverIDs[server]
+			location.Replicas = append(location.Replicas, replicaLocation{ServerID: id, Server: server, Down: down[id]})
+			if location.ServerID == -1 && !down[id] {
+				location.ServerID = id
+				location.Server = server
+			}
+		}
+		locations = append(locations, location)
+	}
+	return locations
+}
+
+// liveReplicas returns the IDs, or names if byAddr, of the replicas of the block that are
+// not down, in the order they are read from
+func (location *blockLocation) liveReplicas(byAddr bool) []string {
+	replicas := make([]string, 0, len(location.Replicas))
+	for _, replica := range location.Replicas {
+		if replica.Down {
+			continue
+		}
+		if byAddr {
+			replicas = append(replicas, replica.Server)
+		} else {
+			replicas = append(replicas, strconv.Itoa(replica.ServerID))
+		}
+	}
+	return replicas
+}
+
+// writeText prints pairs of block hash and server: the server's ID, or its address
+// if the servers were read from a cluster config. Replicated blocks are paired with
+// the replicas that are not down instead.
+//
+//	{{672e9bff..., 7}, {31f28d5a..., 2}}
+//	{{672e9bff..., [7 0]}, {31f28d5a..., [2 5]}}
+func writeText(w io.Writer, locations []blockLocation, byAddr bool) error {
+	pairs := make([]string, 0, len(locations))
+	for _, location := range locations {
+		server := strconv.Itoa(location.ServerID)
+		if byAddr {
+			server = location.Server
+		}
+		if location.Replicas != nil {
+			server = "[" + strings.Join(location.liveReplicas(byAddr), " ") + "]"
+		}
+		pairs = append(pairs, "{"+location.BlockHash+", "+server+"}")
 	}
 
-	return hashes, hashesMap
+	_, err := fmt.Fprintln(w, "{"+strings.Join(pairs, ", ")+"}")
+	return err
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeCSV prints a row per block. Replicated blocks have a replicas column
+// listing the replicas that are not down.
+func writeCSV(w io.Writer, locations []blockLocation) error {
+	replicated := len(locations) > 0 && locations[0].Replicas != nil
+
+	csvWriter := csv.NewWriter(w)
+	header := []string{"block_hash", "server_id", "server"}
+	if replicated {
+		header = append(header, "replicas")
+	}
+	csvWriter.Write(header)
+	for _, location := range locations {
+		record := []string{location.BlockHash, strconv.Itoa(location.ServerID), location.Server}
+		if replicated {
+			record = append(record, strings.Join(location.liveReplicas(true), " "))
+		}
+		csvWriter.Write(record)
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
 }
 
-func getFileBlockHashes(filename string, blockSize int) []string {
-	// Begin algorithm to scan each file and compute hashes.s
+// getFileBlockHashes returns the hashes of the blocks of the file, in file order
+func getFileBlockHashes(filename string, blockSize int) ([]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		log.Fatalf("File open error: %v", err)
+		return nil, fmt.Errorf("file open error: %w", err)
 	}
 	defer file.Close()
 
 	buf := make([]byte, blockSize)
 	hashes := make([]string, 0)
-	// For each block in the file, calculate the hash and block size and add to files list
+	// For each block in the file, calculate the hash and add it to the list
 	for {
-		blockSize, err := file.Read(buf)
-		if err != nil {
-			if err != io.EOF {
-				log.Fatalf("File read error: %v", err)
-			}
-
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			hashes = append(hashes, servestore.GetBlockHashString(buf[:n]))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			break
 		}
-
-		blockData := buf[:blockSize]
-		hashes = append(hashes, servestore.GetBlockHashString(blockData))
+		if err != nil {
+			return nil, fmt.Errorf("file read error: %w", err)
+		}
 	}
 
-	return hashes
+	return hashes, nil
 }
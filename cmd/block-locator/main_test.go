@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"rcjng/pkg/servestore"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+const testInput = "testdata/input.txt"
+const testBlockSize = 128
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// The text golden files for numbered servers were written by the block-locator
+// that predates the shared hash ring, and must not change
+var goldenTests = []struct {
+	golden string
+	args   []string
+}{
+	{"servers8.golden", []string{"8", "128", testInput}},
+	{"servers8_down.golden", []string{"-downServers", "1,3,4", "8", "128", testInput}},
+	{"servers3.golden", []string{"3", "128", testInput}},
+	{"servers8_json.golden", []string{"-format", "json", "8", "128", testInput}},
+	{"servers8_down_csv.golden", []string{"-format", "csv", "-downServers", "1,3,4", "8", "128", testInput}},
+	{"cluster.golden", []string{"-config", "testdata/cluster.json", "128", testInput}},
+	{"cluster_down.golden", []string{"-config", "testdata/cluster.json", "-downServers", "2", "128", testInput}},
+	{"cluster_down_json.golden", []string{"-config", "testdata/cluster.json", "-downServers", "2", "-format", "json", "128", testInput}},
+	{"cluster_weighted.golden", []string{"-config", "testdata/cluster_weighted.json", "128", testInput}},
+	{"servers8_vnodes.golden", []string{"-virtualNodes", "100", "8", "128", testInput}},
+	{"servers8_replicas_down.golden", []string{"-replicas", "2", "-downServers", "1,3,4", "8", "128", testInput}},
+	{"cluster_replicated_down_json.golden", []string{"-config", "testdata/cluster_replicated.json", "-downServers", "0,2", "-format", "json", "128", testInput}},
+	{"cluster_replicated_down_csv.golden", []string{"-config", "testdata/cluster_replicated.json", "-downServers", "0,2", "-format", "csv", "128", testInput}},
+	{"cluster_replicated_report.golden", []string{"-report", "-config", "testdata/cluster_replicated.json", "-addServer", "10.0.0.6:8081", "128", testInput}},
+	{"servers8_report.golden", []string{"-report", "8", "128", testInput}},
+	{"cluster_weighted_report_json.golden", []string{"-report", "-format", "json", "-config", "testdata/cluster_weighted.json", "-addServer", "10.0.0.5:8081", "128", testInput}},
+}
+
+func TestGoldenOutput(t *testing.T) {
+	for _, test := range goldenTests {
+		t.Run(test.golden, func(t *testing.T) {
+			var stdout bytes.Buffer
+			if err := run(test.args, &stdout); err != nil {
+				t.Fatalf("run %v: %v", test.args, err)
+			}
+
+			goldenPath := filepath.Join("testdata", test.golden)
+			if *update {
+				if err := os.WriteFile(goldenPath, stdout.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(stdout.Bytes(), want) {
+				t.Errorf("output of %v differs from %s:\n%s", test.args, goldenPath, stdout.String())
+			}
+		})
+	}
+}
+
+// parseTextOutput parses the {{hash, server}, ...} pairs printed in the text format
+func parseTextOutput(t *testing.T, output string) [][2]string {
+	output = strings.TrimSpace(output)
+	if !strings.HasPrefix(output, "{{") || !strings.HasSuffix(output, "}}") {
+		t.Fatalf("malformed output %q", output)
+	}
+
+	var pairs [][2]string
+	for _, pair := range strings.Split(output[2:len(output)-2], "}, {") {
+		fields := strings.Split(pair, ", ")
+		if len(fields) != 2 {
+			t.Fatalf("malformed pair %q", pair)
+		}
+		pairs = append(pairs, [2]string{fields[0], fields[1]})
+	}
+	return pairs
+}
+
+func TestLibraryMatchesGoldenMappings(t *testing.T) {
+	blockHashes, err := getFileBlockHashes(testInput, testBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := servestore.LoadClusterConfig("testdata/cluster.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downCluster := config.HashRing()
+	downCluster.DeleteServer(config.BlockStoreAddrs[2])
+
+	weightedConfig, err := servestore.LoadClusterConfig("testdata/cluster_weighted.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vnodes := servestore.NewWeightedConsistentHashRing(100)
+	for i := 0; i < 8; i++ {
+		vnodes.InsertServer(servestore.BlockServerName(i))
+	}
+
+	tests := []struct {
+		golden     string
+		ring       *servestore.ConsistentHashRing
+		serverName func(server string) string
+	}{
+		{"servers8.golden", servestore.NewConsistentHashRing(8, nil), blockServerName},
+		{"servers8_down.golden", servestore.NewConsistentHashRing(8, []int{1, 3, 4}), blockServerName},
+		{"servers3.golden", servestore.NewConsistentHashRing(3, nil), blockServerName},
+		{"cluster.golden", config.HashRing(), addrServerName},
+		{"cluster_down.golden", downCluster, addrServerName},
+		{"cluster_weighted.golden", weightedConfig.HashRing(), addrServerName},
+		{"servers8_vnodes.golden", vnodes, blockServerName},
+	}
+
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			output, err := os.ReadFile(filepath.Join("testdata", test.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pairs := parseTextOutput(t, string(output))
+			if len(pairs) != len(blockHashes) {
+				t.Fatalf("%d blocks mapped, want %d", len(pairs), len(blockHashes))
+			}
+
+			mapped := make(map[string]bool)
+			for _, blockHash := range blockHashes {
+				mapped[blockHash] = true
+			}
+			for _, pair := range pairs {
+				if !mapped[pair[0]] {
+					t.Fatalf("unknown block %s", pair[0])
+				}
+				server := test.ring.GetResponsibleServer(pair[0])
+				if got := test.serverName(server); got != pair[1] {
+					t.Errorf("block %s: library maps to %s, CLI to %s", pair[0], got, pair[1])
+				}
+			}
+		})
+	}
+}
+
+func TestLibraryMatchesReplicaSets(t *testing.T) {
+	config, err := servestore.LoadClusterConfig("testdata/cluster_replicated.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	placement := servestore.NewBlockPlacement(config)
+
+	output, err := os.ReadFile("testdata/cluster_replicated_down_json.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locations []blockLocation
+	if err := json.Unmarshal(output, &locations); err != nil {
+		t.Fatal(err)
+	}
+
+	down := map[string]bool{config.BlockStoreAddrs[0]: true, config.BlockStoreAddrs[2]: true}
+	for _, location := range locations {
+		replicas := placement.Replicas(location.BlockHash)
+		if len(replicas) != len(location.Replicas) {
+			t.Fatalf("block %s: library places %d replicas, CLI %d", location.BlockHash, len(replicas), len(location.Replicas))
+		}
+
+		readFrom := ""
+		for i, server := range replicas {
+			if location.Replicas[i].Server != server || location.Replicas[i].Down != down[server] {
+				t.Errorf("block %s: library places replica %d on %s, CLI on %+v", location.BlockHash, i, server, location.Replicas[i])
+			}
+			if readFrom == "" && !down[server] {
+				readFrom = server
+			}
+		}
+		if location.Server != readFrom {
+			t.Errorf("block %s: read from %q, want %q", location.BlockHash, location.Server, readFrom)
+		}
+	}
+}
+
+func blockServerName(server string) string {
+	return strings.TrimPrefix(server, servestore.BLOCK_SERVER_PREFIX)
+}
+
+func addrServerName(server string) string {
+	return server
+}
+
+func TestInvalidArguments(t *testing.T) {
+	tests := [][]string{
+		{"8", "128"},
+		{"eight", "128", testInput},
+		{"8", "0", testInput},
+		{"-format", "xml", "8", "128", testInput},
+		{"-downServers", "1,", "8", "128", testInput},
+		{"-downServers", "8", "8", "128", testInput},
+		{"-downServers", "0,1", "2", "128", testInput},
+		{"-config", "testdata/missing.json", "128", testInput},
+		{"-virtualNodes", "-1", "8", "128", testInput},
+		{"-report", "-format", "csv", "8", "128", testInput},
+		{"-report", "-addServer", "blockserver1", "8", "128", testInput},
+		{"-replicas", "9", "8", "128", testInput},
+		{"-replicas", "-1", "8", "128", testInput},
+		{"8", "128", "testdata/missing.txt"},
+	}
+
+	for _, args := range tests {
+		var stdout bytes.Buffer
+		if err := run(args, &stdout); err == nil {
+			t.Errorf("run %v succeeded, want error", args)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("run %v printed %q", args, stdout.String())
+		}
+	}
+}
+
+func TestBlockHashesMatchBlockCount(t *testing.T) {
+	info, err := os.Stat(testInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockHashes, err := getFileBlockHashes(testInput, testBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := int((info.Size() + testBlockSize - 1) / testBlockSize)
+	if len(blockHashes) != want {
+		t.Errorf("%d block hashes, want %d", len(blockHashes), want)
+	}
+}
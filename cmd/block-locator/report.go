@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"rcjng/pkg/servestore"
+	"text/tabwriter"
+)
+
+// serverLoad is the number of blocks on a server, counting each replica. ExpectedBlocks is
+// the server's share of the blocks by weight, and BlocksMovedOnRemoval the number of
+// blocks that would move to another server if the server was removed from the ring.
+type serverLoad struct {
+	ServerID             int     `json:"serverId"`
+	Server               string  `json:"server"`
+	Weight               int     `json:"weight"`
+	Blocks               int     `json:"blocks"`
+	ExpectedBlocks       float64 `json:"expectedBlocks"`
+	BlocksMovedOnRemoval int     `json:"blocksMovedOnRemoval"`
+}
+
+// loadReport describes how evenly blocks are spread over the servers that are not down.
+// Replicas is the number of servers each block is stored on.
+// StdDev is the standard deviation of the servers' blocks from their expected blocks,
+// and BlocksMovedOnAdd the number of blocks that would move to AddedServer if it was
+// added to the ring with weight 1.
+type loadReport struct {
+	Blocks           int          `json:"blocks"`
+	VirtualNodes     int          `json:"virtualNodes"`
+	Replicas         int          `json:"replicas"`
+	Servers          []serverLoad `json:"servers"`
+	StdDev           float64      `json:"stdDev"`
+	AddedServer      string       `json:"addedServer"`
+	BlocksMovedOnAdd int          `json:"blocksMovedOnAdd"`
+}
+
+func (c *cluster) loadReport(blockHashes []string, ring *servestore.ConsistentHashRing, addedServer string) (*loadReport, error) {
+	if _, ok := ring.Weights[addedServer]; ok {
+		return nil, fmt.Errorf("server %s is already on the ring", addedServer)
+	}
+
+	servers := c.placeReplicas(blockHashes, ring)
+	blocks := make(map[string]int)
+	for _, replicas := range servers {
+		for _, server := range replicas {
+			blocks[server]++
+		}
+	}
+
+	totalWeight := 0
+	for _, weight := range ring.Weights {
+		totalWeight += weight
+	}
+
+	report := &loadReport{
+		Blocks:       len(blockHashes),
+		VirtualNodes: c.virtualNodes,
+		Replicas:     c.replicas,
+		Servers:      make([]serverLoad, 0, len(ring.Weights)),
+		AddedServer:  addedServer,
+	}
+
+	sumSquares := 0.0
+	for id, server := range c.servers {
+		weight, ok := ring.Weights[server]
+		if !ok {
+			continue
+		}
+
+		load := serverLoad{
+			ServerID:       id,
+			Server:         server,
+			Weight:         weight,
+			Blocks:         blocks[server],
+			ExpectedBlocks: float64(len(blockHashes)*c.replicas*weight) / float64(totalWeight),
+		}
+
+		ring.DeleteServer(server)
+		load.BlocksMovedOnRemoval = countMoved(servers, c.placeReplicas(blockHashes, ring))
+		ring.InsertWeightedServer(server, weight)
+
+		deviation := float64(load.Blocks) - load.ExpectedBlocks
+		sumSquares += deviation * deviation
+		report.Servers = append(report.Servers, load)
+	}
+	report.StdDev = math.Sqrt(sumSquares / float64(len(report.Servers)))
+
+	ring.InsertServer(addedServer)
+	report.BlocksMovedOnAdd = countMoved(servers, c.placeReplicas(blockHashes, ring))
+	ring.DeleteServer(addedServer)
+
+	return report, nil
+}
+
+// placeReplicas returns the servers each block is stored on
+func (c *cluster) placeReplicas(blockHashes []string, ring *servestore.ConsistentHashRing) [][]string {
+	if c.replicas == 1 {
+		servers := make([][]string, len(blockHashes))
+		for i, server := range ring.GetResponsibleServers(blockHashes) {
+			servers[i] = []string{server}
+		}
+		return servers
+	}
+
+	servers := make([][]string, len(blockHashes))
+	for i, blockHash := range blockHashes {
+		servers[i] = ring.GetReplicaServers(blockHash, c.replicas)
+	}
+	return servers
+}
+
+// countMoved counts the replicas of blocks stored on a server in after that did not store
+// them in before, which have to be copied there
+func countMoved(before [][]string, after [][]string) int {
+	moved := 0
+	for i := range before {
+		stored := make(map[string]bool)
+		for _, server := range before[i] {
+			stored[server] = true
+		}
+		for _, server := range after[i] {
+			if !stored[server] {
+				moved++
+			}
+		}
+	}
+	return moved
+}
+
+// writeReport prints the report as a table of servers followed by a summary
+func writeReport(w io.Writer, report *loadReport) error {
+	fmt.Fprintf(w, "Blocks: %d, replicas: %d, virtual nodes per unit of weight: %d\n\n", report.Blocks, report.Replicas, report.VirtualNodes)
+
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tSERVER\tWEIGHT\tBLOCKS\tEXPECTED\tMOVED IF REMOVED")
+	for _, load := range report.Servers {
+		fmt.Fprintf(table, "%d\t%s\t%d\t%d\t%.2f\t%d\n",
+			load.ServerID, load.Server, load.Weight, load.Blocks, load.ExpectedBlocks, load.BlocksMovedOnRemoval)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nStandard deviation from expected blocks: %.2f\n", report.StdDev)
+	_, err := fmt.Fprintf(w, "Blocks moved if %s is added: %d\n", report.AddedServer, report.BlocksMovedOnAdd)
+	return err
+}
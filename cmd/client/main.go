@@ -1,24 +1,100 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"rcjng/pkg/servestore"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // Arguments
 const ARG_COUNT int = 3
 
+// Single-file commands and the arguments they take after host:port
+const PUSH_COMMAND = "push"
+const PULL_COMMAND = "pull"
+const LS_COMMAND = "ls"
+const RM_COMMAND = "rm"
+const MV_COMMAND = "mv"
+
+var COMMAND_ARGS = map[string][]string{
+	PUSH_COMMAND: {"localPath", "remoteName"},
+	PULL_COMMAND: {"remoteName", "localPath"},
+	LS_COMMAND:   {"[prefix]"},
+	RM_COMMAND:   {"remoteName"},
+	MV_COMMAND:   {"remoteName", "newRemoteName"},
+}
+
 // Usage strings
-const USAGE_STRING = "./run-client.sh -d host:port baseDir blockSize"
+const USAGE_STRING = "./run-client.sh -d -json -dry-run -xattrs -links policy -rehash -concurrency n -buffer-size bytes -exclude pattern -include pattern -atomic -progress -limits file -upload-limit bytes -download-limit bytes host:port baseDir blockSize"
+const COMMAND_USAGE_STRING = "./run-client.sh -d -json -xattrs -block-size bytes -limits file -upload-limit bytes -download-limit bytes push|pull|ls|rm|mv host:port args"
 
 const DEBUG_NAME = "d"
 const DEBUG_USAGE = "Output log statements"
 
+const JSON_NAME = "json"
+const JSON_USAGE = "Output the sync report as JSON"
+
+const DRYRUN_NAME = "dry-run"
+const DRYRUN_USAGE = "Print the planned uploads, downloads, deletions and conflicts without syncing"
+
+const XATTRS_NAME = "xattrs"
+const XATTRS_USAGE = "Sync extended attributes in the user namespace (Linux only)"
+
+const LINKS_NAME = "links"
+const LINKS_USAGE = "How symbolic links are synced: preserve (as links), follow (as the files they point to) or skip"
+
+const REHASH_NAME = "rehash"
+const REHASH_USAGE = "Read and hash every file instead of skipping files unchanged since the last sync"
+
+const CONCURRENCY_NAME = "concurrency"
+const CONCURRENCY_USAGE = "Number of files hashed or synced, and of blocks of a file transferred, at once"
+
+const BUFFER_SIZE_NAME = "buffer-size"
+const BUFFER_SIZE_USAGE = "Maximum bytes of blocks held in memory at once while hashing, uploading and downloading"
+
+const EXCLUDE_NAME = "exclude"
+const EXCLUDE_USAGE = "Gitignore-style pattern of paths to leave out of the sync (repeatable)"
+
+const INCLUDE_NAME = "include"
+const INCLUDE_USAGE = "Gitignore-style pattern of paths to sync even if ignored (repeatable)"
+
+const ATOMIC_NAME = "atomic"
+const ATOMIC_USAGE = "Commit the changes to the files of each directory in a single transaction"
+
+const PROGRESS_NAME = "progress"
+const PROGRESS_USAGE = "Show a progress bar with the throughput and estimated time left on standard error"
+
+const LIMITS_NAME = "limits"
+const LIMITS_USAGE = "JSON file of transfer limits, reloaded on SIGHUP while syncing. The limit flags override it"
+
+const UPLOAD_LIMIT_NAME = "upload-limit"
+const UPLOAD_LIMIT_USAGE = "Maximum bytes per second uploaded, across all transfers (default = unlimited)"
+
+const DOWNLOAD_LIMIT_NAME = "download-limit"
+const DOWNLOAD_LIMIT_USAGE = "Maximum bytes per second downloaded, across all transfers (default = unlimited)"
+
+const METERED_UPLOAD_LIMIT_NAME = "metered-upload-limit"
+const METERED_UPLOAD_LIMIT_USAGE = "Maximum bytes per second uploaded while the MetaStore is reached through a metered interface (default = -upload-limit)"
+
+const METERED_DOWNLOAD_LIMIT_NAME = "metered-download-limit"
+const METERED_DOWNLOAD_LIMIT_USAGE = "Maximum bytes per second downloaded while the MetaStore is reached through a metered interface (default = -download-limit)"
+
+const METERED_INTERFACES_NAME = "metered-interfaces"
+const METERED_INTERFACES_USAGE = "Comma-separated names of the network interfaces the metered limits apply on"
+
+const PUSH_BLOCK_SIZE_NAME = "block-size"
+const PUSH_BLOCK_SIZE_USAGE = "Size of the blocks a pushed file is cut into (default: that of the remote file it replaces, or 4096)"
+
 const ADDR_NAME = "host:port"
 const ADDR_USAGE = "IP address and port of the MetaStore the client is syncing to"
 
@@ -29,7 +105,24 @@ const BLOCK_NAME = "blockSize"
 const BLOCK_USAGE = "Size of the blocks used to fragment files"
 
 // Exit codes
+const EX_OK int = 0
+const EX_CONFLICT int = 1 // Synced, but local changes to some files lost to newer remote versions
 const EX_USAGE int = 64
+const EX_NOINPUT int = 66     // The file to push, pull, remove or move does not exist
+const EX_UNAVAILABLE int = 69 // The sync could not be run (e.g. MetaStore unreachable)
+const EX_TEMPFAIL int = 75    // Some files failed to sync and should be retried
+
+// patternList collects the values of a repeatable flag
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
 
 func main() {
 	// Custom flag Usage message
@@ -37,18 +130,90 @@ func main() {
 		w := flag.CommandLine.Output()
 		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
 		fmt.Fprintf(w, "  -%s: %v\n", DEBUG_NAME, DEBUG_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", JSON_NAME, JSON_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", DRYRUN_NAME, DRYRUN_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", XATTRS_NAME, XATTRS_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", LINKS_NAME, LINKS_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", REHASH_NAME, REHASH_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", CONCURRENCY_NAME, CONCURRENCY_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", BUFFER_SIZE_NAME, BUFFER_SIZE_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", EXCLUDE_NAME, EXCLUDE_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", INCLUDE_NAME, INCLUDE_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", ATOMIC_NAME, ATOMIC_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", PROGRESS_NAME, PROGRESS_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", LIMITS_NAME, LIMITS_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", UPLOAD_LIMIT_NAME, UPLOAD_LIMIT_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", DOWNLOAD_LIMIT_NAME, DOWNLOAD_LIMIT_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", METERED_UPLOAD_LIMIT_NAME, METERED_UPLOAD_LIMIT_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", METERED_DOWNLOAD_LIMIT_NAME, METERED_DOWNLOAD_LIMIT_USAGE)
+		fmt.Fprintf(w, "  -%s: %v\n", METERED_INTERFACES_NAME, METERED_INTERFACES_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", ADDR_NAME, ADDR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BASEDIR_NAME, BASEDIR_USAGE)
 		fmt.Fprintf(w, "  %s: %v\n", BLOCK_NAME, BLOCK_USAGE)
+		fmt.Fprintf(w, "Usage of %s:\n", COMMAND_USAGE_STRING)
+		fmt.Fprintf(w, "  -%s: %v\n", PUSH_BLOCK_SIZE_NAME, PUSH_BLOCK_SIZE_USAGE)
+		for _, command := range []string{PUSH_COMMAND, PULL_COMMAND, LS_COMMAND, RM_COMMAND, MV_COMMAND} {
+			fmt.Fprintf(w, "  %s host:port %s\n", command, strings.Join(COMMAND_ARGS[command], " "))
+		}
 	}
 
 	// Parse command-line arguments and flags
-	debug := flag.Bool("d", false, DEBUG_USAGE)
+	debug := flag.Bool(DEBUG_NAME, false, DEBUG_USAGE)
+	jsonOutput := flag.Bool(JSON_NAME, false, JSON_USAGE)
+	dryRun := flag.Bool(DRYRUN_NAME, false, DRYRUN_USAGE)
+	xattrs := flag.Bool(XATTRS_NAME, false, XATTRS_USAGE)
+	links := flag.String(LINKS_NAME, string(servestore.LINK_PRESERVE), LINKS_USAGE)
+	rehash := flag.Bool(REHASH_NAME, false, REHASH_USAGE)
+	concurrency := flag.Int(CONCURRENCY_NAME, servestore.DEFAULT_CONCURRENCY, CONCURRENCY_USAGE)
+	bufferSize := flag.Int64(BUFFER_SIZE_NAME, servestore.DEFAULT_BUFFER_SIZE, BUFFER_SIZE_USAGE)
+	var excludes, includes patternList
+	flag.Var(&excludes, EXCLUDE_NAME, EXCLUDE_USAGE)
+	flag.Var(&includes, INCLUDE_NAME, INCLUDE_USAGE)
+	atomic := flag.Bool(ATOMIC_NAME, false, ATOMIC_USAGE)
+	progress := flag.Bool(PROGRESS_NAME, false, PROGRESS_USAGE)
+	pushBlockSize := flag.Int(PUSH_BLOCK_SIZE_NAME, 0, PUSH_BLOCK_SIZE_USAGE)
+	limitFlags := transferLimitFlags{}
+	flag.StringVar(&limitFlags.path, LIMITS_NAME, "", LIMITS_USAGE)
+	flag.Int64Var(&limitFlags.limits.UploadBytesPerSecond, UPLOAD_LIMIT_NAME, 0, UPLOAD_LIMIT_USAGE)
+	flag.Int64Var(&limitFlags.limits.DownloadBytesPerSecond, DOWNLOAD_LIMIT_NAME, 0, DOWNLOAD_LIMIT_USAGE)
+	flag.Int64Var(&limitFlags.limits.MeteredUploadBytesPerSecond, METERED_UPLOAD_LIMIT_NAME, 0, METERED_UPLOAD_LIMIT_USAGE)
+	flag.Int64Var(&limitFlags.limits.MeteredDownloadBytesPerSecond, METERED_DOWNLOAD_LIMIT_NAME, 0, METERED_DOWNLOAD_LIMIT_USAGE)
+	meteredInterfaces := flag.String(METERED_INTERFACES_NAME, "", METERED_INTERFACES_USAGE)
 	flag.Parse()
+	if *meteredInterfaces != "" {
+		limitFlags.limits.MeteredInterfaces = strings.Split(*meteredInterfaces, ",")
+	}
 
 	// Use tail arguments to hold non-flag arguments
 	args := flag.Args()
 
+	// Disable log outputs if debug flag is missing
+	if !(*debug) {
+		log.SetFlags(0)
+		log.SetOutput(ioutil.Discard)
+	}
+
+	if len(args) > 0 && COMMAND_ARGS[args[0]] != nil {
+		command := args[0]
+		maxArgs := len(COMMAND_ARGS[command])
+		minArgs := maxArgs
+		if command == LS_COMMAND {
+			minArgs = 0
+		}
+		if len(args) < 2+minArgs || len(args) > 2+maxArgs || *pushBlockSize < 0 {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+
+		rpcClient := servestore.NewServeStoreRPCClient(args[1], "", *pushBlockSize)
+		rpcClient.Xattrs = *xattrs
+		if err := applyTransferLimits(&rpcClient, limitFlags); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid transfer limits: %v\n", err)
+			os.Exit(EX_USAGE)
+		}
+		os.Exit(runCommand(rpcClient, command, args[2:], *jsonOutput))
+	}
+
 	if len(args) != ARG_COUNT {
 		flag.Usage()
 		os.Exit(EX_USAGE)
@@ -62,12 +227,342 @@ func main() {
 		os.Exit(EX_USAGE)
 	}
 
-	// Disable log outputs if debug flag is missing
-	if !(*debug) {
-		log.SetFlags(0)
-		log.SetOutput(ioutil.Discard)
+	linkPolicy, err := servestore.ParseLinkPolicy(*links)
+	if err != nil {
+		flag.Usage()
+		os.Exit(EX_USAGE)
+	}
+
+	if *concurrency < 1 || *bufferSize < 1 {
+		flag.Usage()
+		os.Exit(EX_USAGE)
 	}
 
 	rpcClient := servestore.NewServeStoreRPCClient(hostPort, baseDir, blockSize)
-	servestore.ClientSync(rpcClient)
+	rpcClient.Excludes = excludes
+	rpcClient.Includes = includes
+	rpcClient.Xattrs = *xattrs
+	rpcClient.LinkPolicy = linkPolicy
+	rpcClient.Rehash = *rehash
+	rpcClient.Concurrency = *concurrency
+	rpcClient.BufferSize = *bufferSize
+	rpcClient.Atomic = *atomic
+	if err := applyTransferLimits(&rpcClient, limitFlags); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid transfer limits: %v\n", err)
+		os.Exit(EX_USAGE)
+	}
+	go reloadTransferLimits(&rpcClient, limitFlags)
+
+	var progressBar *progressBar
+	if *progress {
+		progressBar = newProgressBar(os.Stderr)
+		rpcClient.Events = progressBar.event
+	}
+
+	if *dryRun {
+		plan, err := servestore.ClientPlan(rpcClient)
+		if progressBar != nil {
+			progressBar.finish()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Sync plan failed: %v\n", err)
+			os.Exit(EX_UNAVAILABLE)
+		}
+
+		if *jsonOutput {
+			printJSON(plan)
+		} else {
+			printPlan(plan)
+		}
+		os.Exit(EX_OK)
+	}
+
+	report, err := servestore.ClientSync(rpcClient)
+	if progressBar != nil {
+		progressBar.finish()
+	}
+	if report == nil {
+		fmt.Fprintf(os.Stderr, "Sync failed: %v\n", err)
+		os.Exit(EX_UNAVAILABLE)
+	}
+
+	if *jsonOutput {
+		printJSON(report)
+	} else {
+		printReport(report)
+	}
+
+	os.Exit(exitCode(report, err))
+}
+
+// transferLimitFlags is the transfer limits file, and the limits set by flags, which
+// override it
+type transferLimitFlags struct {
+	path   string
+	limits servestore.TransferLimits
+}
+
+// transferLimits reads the transfer limits file, if any, and overrides it with the limit
+// flags that were set
+func transferLimits(limitFlags transferLimitFlags) (*servestore.TransferLimits, error) {
+	limits := &servestore.TransferLimits{}
+	if limitFlags.path != "" {
+		loaded, err := servestore.LoadTransferLimits(limitFlags.path)
+		if err != nil {
+			return nil, err
+		}
+		limits = loaded
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case UPLOAD_LIMIT_NAME:
+			limits.UploadBytesPerSecond = limitFlags.limits.UploadBytesPerSecond
+		case DOWNLOAD_LIMIT_NAME:
+			limits.DownloadBytesPerSecond = limitFlags.limits.DownloadBytesPerSecond
+		case METERED_UPLOAD_LIMIT_NAME:
+			limits.MeteredUploadBytesPerSecond = limitFlags.limits.MeteredUploadBytesPerSecond
+		case METERED_DOWNLOAD_LIMIT_NAME:
+			limits.MeteredDownloadBytesPerSecond = limitFlags.limits.MeteredDownloadBytesPerSecond
+		case METERED_INTERFACES_NAME:
+			limits.MeteredInterfaces = limitFlags.limits.MeteredInterfaces
+		}
+	})
+	return limits, nil
+}
+
+func applyTransferLimits(rpcClient *servestore.RPCClient, limitFlags transferLimitFlags) error {
+	limits, err := transferLimits(limitFlags)
+	if err != nil {
+		return err
+	}
+	_, err = rpcClient.SetTransferLimits(limits)
+	return err
+}
+
+// reloadTransferLimits applies the transfer limits again on every SIGHUP, so they can be
+// changed, or follow a change of network, during a long sync. Transfers already waiting
+// pick up the new limits.
+func reloadTransferLimits(rpcClient *servestore.RPCClient, limitFlags transferLimitFlags) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		if err := applyTransferLimits(rpcClient, limitFlags); err != nil {
+			fmt.Fprintf(os.Stderr, "Keeping the previous transfer limits: %v\n", err)
+		}
+	}
+}
+
+// exitCode maps the outcome of a sync to the client's exit code
+func exitCode(report *servestore.SyncReport, err error) int {
+	if errors.Is(err, servestore.ErrSyncIncomplete) {
+		return EX_TEMPFAIL
+	}
+	if report.Count(servestore.ACTION_CONFLICT) > 0 {
+		return EX_CONFLICT
+	}
+	return EX_OK
+}
+
+// runCommand runs a single-file command and returns the client's exit code
+func runCommand(rpcClient servestore.RPCClient, command string, args []string, jsonOutput bool) int {
+	if command == LS_COMMAND {
+		prefix := ""
+		if len(args) > 0 {
+			prefix = args[0]
+		}
+		files, err := servestore.ListFiles(rpcClient, prefix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "List failed: %v\n", err)
+			return EX_UNAVAILABLE
+		}
+		if jsonOutput {
+			printJSON(listing(files))
+		} else {
+			printListing(files)
+		}
+		return EX_OK
+	}
+
+	var fileReport *servestore.FileReport
+	var err error
+	switch {
+	case command == PUSH_COMMAND:
+		fileReport, err = servestore.PushFile(rpcClient, args[0], args[1])
+	case command == PULL_COMMAND && args[1] == "-":
+		fileReport, err = servestore.CatFile(rpcClient, args[0], os.Stdout)
+	case command == PULL_COMMAND:
+		fileReport, err = servestore.PullFile(rpcClient, args[0], args[1])
+	case command == RM_COMMAND:
+		fileReport, err = servestore.RemoveFile(rpcClient, args[0])
+	case command == MV_COMMAND:
+		fileReport, err = servestore.MoveFile(rpcClient, args[0], args[1])
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Command %s failed: %v\n", command, err)
+		if errors.Is(err, servestore.ErrRemoteFileNotFound) || errors.Is(err, os.ErrNotExist) {
+			return EX_NOINPUT
+		}
+		return EX_UNAVAILABLE
+	}
+
+	report := &servestore.SyncReport{Files: []*servestore.FileReport{fileReport}}
+	if jsonOutput {
+		printJSON(report)
+	} else if command != PULL_COMMAND || args[1] != "-" {
+		printReport(report)
+	}
+	return exitCode(report, nil)
+}
+
+// listedFile is a remote file as listed by `ls -json`
+type listedFile struct {
+	Filename      string `json:"filename"`
+	Version       int32  `json:"version"`
+	Size          int64  `json:"size"`
+	ModTime       string `json:"modTime,omitempty"`
+	SymlinkTarget string `json:"symlinkTarget,omitempty"`
+}
+
+func listing(files []*servestore.FileMetaData) []*listedFile {
+	listed := make([]*listedFile, 0, len(files))
+	for _, fileMetaData := range files {
+		file := &listedFile{
+			Filename:      fileMetaData.GetFilename(),
+			Version:       fileMetaData.GetVersion(),
+			Size:          fileMetaData.GetSize(),
+			SymlinkTarget: fileMetaData.GetSymlinkTarget(),
+		}
+		if fileMetaData.GetMtime() != 0 {
+			file.ModTime = time.Unix(0, fileMetaData.GetMtime()).UTC().Format(time.RFC3339)
+		}
+		listed = append(listed, file)
+	}
+	return listed
+}
+
+func printListing(files []*servestore.FileMetaData) {
+	for _, file := range listing(files) {
+		modTime := file.ModTime
+		if modTime == "" {
+			modTime = "-"
+		}
+		fmt.Printf("%12d  %-20s  v%-4d %s", file.Size, modTime, file.Version, file.Filename)
+		if file.SymlinkTarget != "" {
+			fmt.Printf(" -> %s", file.SymlinkTarget)
+		}
+		fmt.Println()
+	}
+}
+
+func printReport(report *servestore.SyncReport) {
+	for _, fileReport := range report.Files {
+		if fileReport.Error != "" {
+			fmt.Printf("%-17s %s: %s\n", "failed", fileReport.Filename, fileReport.Error)
+			continue
+		}
+		if fileReport.Action == servestore.ACTION_SKIPPED {
+			continue
+		}
+
+		action := string(fileReport.Action)
+		isDeleteOrRename := fileReport.Action == servestore.ACTION_DELETED || fileReport.Action == servestore.ACTION_RENAMED
+		if isDeleteOrRename && fileReport.Direction == servestore.DIRECTION_UP {
+			action += " remotely"
+		} else if isDeleteOrRename {
+			action += " locally"
+		}
+
+		filename := fileReport.Filename
+		if fileReport.RenamedFrom != "" {
+			filename = fileReport.RenamedFrom + " -> " + filename
+		}
+
+		fmt.Printf("%-17s %s (v%d", action, filename, fileReport.Version)
+		if fileReport.BytesUploaded > 0 {
+			fmt.Printf(", %d bytes uploaded", fileReport.BytesUploaded)
+		}
+		if fileReport.BytesDownloaded > 0 {
+			fmt.Printf(", %d bytes downloaded", fileReport.BytesDownloaded)
+		}
+		fmt.Println(")")
+	}
+
+	fmt.Printf("%d uploaded, %d downloaded, %d deleted, %d renamed, %d conflicts, %d unchanged, %d failed\n",
+		report.Count(servestore.ACTION_UPLOADED),
+		report.Count(servestore.ACTION_DOWNLOADED),
+		report.Count(servestore.ACTION_DELETED),
+		report.Count(servestore.ACTION_RENAMED),
+		report.Count(servestore.ACTION_CONFLICT),
+		report.Count(servestore.ACTION_SKIPPED),
+		len(report.Failed()))
+}
+
+func printPlan(plan *servestore.SyncPlan) {
+	var bytesUp, bytesDown int64
+	for _, plannedFile := range plan.Files {
+		if plannedFile.Error != "" {
+			fmt.Printf("%-17s %s: %s\n", "unreadable", plannedFile.Filename, plannedFile.Error)
+			continue
+		}
+
+		var action string
+		switch {
+		case plannedFile.Action == servestore.ACTION_SKIPPED:
+			continue
+		case plannedFile.Action == servestore.ACTION_UPLOADED:
+			action = "upload"
+			bytesUp += plannedFile.Size
+		case plannedFile.Action == servestore.ACTION_DOWNLOADED:
+			action = "download"
+			bytesDown += plannedFile.Size
+		case plannedFile.Action == servestore.ACTION_DELETED && plannedFile.Direction == servestore.DIRECTION_UP:
+			action = "delete remotely"
+		case plannedFile.Action == servestore.ACTION_DELETED:
+			action = "delete locally"
+		case plannedFile.Action == servestore.ACTION_RENAMED && plannedFile.Direction == servestore.DIRECTION_UP:
+			action = "rename remotely"
+		case plannedFile.Action == servestore.ACTION_RENAMED:
+			action = "rename locally"
+		case plannedFile.Action == servestore.ACTION_CONFLICT:
+			action = "conflict"
+			bytesDown += plannedFile.Size
+		}
+
+		if plannedFile.RenamedFrom != "" {
+			fmt.Printf("%-17s %s -> %s\n", action, plannedFile.RenamedFrom, plannedFile.Filename)
+			continue
+		}
+
+		fmt.Printf("%-17s %s", action, plannedFile.Filename)
+		if plannedFile.Action == servestore.ACTION_CONFLICT {
+			fmt.Printf(" (local changes replaced by remote version")
+			if plannedFile.NumBlocks > 0 {
+				fmt.Printf(", %d bytes, %d blocks", plannedFile.Size, plannedFile.NumBlocks)
+			}
+			fmt.Printf(")")
+		} else if plannedFile.MetadataOnly {
+			fmt.Printf(" (attributes only)")
+		} else if plannedFile.Action != servestore.ACTION_DELETED {
+			fmt.Printf(" (%d bytes, %d blocks)", plannedFile.Size, plannedFile.NumBlocks)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("%d to upload (%d bytes), %d to download (%d bytes), %d to delete, %d to rename, %d conflicts, %d unchanged\n",
+		plan.Count(servestore.ACTION_UPLOADED), bytesUp,
+		plan.Count(servestore.ACTION_DOWNLOADED), bytesDown,
+		plan.Count(servestore.ACTION_DELETED),
+		plan.Count(servestore.ACTION_RENAMED),
+		plan.Count(servestore.ACTION_CONFLICT),
+		plan.Count(servestore.ACTION_SKIPPED))
+}
+
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "JSON encode error: %v\n", err)
+	}
 }
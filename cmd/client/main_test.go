@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"rcjng/pkg/servestore"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	synced := &servestore.SyncReport{Files: []*servestore.FileReport{
+		{Filename: "a.txt", Action: servestore.ACTION_UPLOADED, Direction: servestore.DIRECTION_UP},
+	}}
+	conflicted := &servestore.SyncReport{Files: []*servestore.FileReport{
+		{Filename: "a.txt", Action: servestore.ACTION_UPLOADED, Direction: servestore.DIRECTION_UP},
+		{Filename: "b.txt", Action: servestore.ACTION_CONFLICT, Direction: servestore.DIRECTION_DOWN},
+	}}
+	incomplete := fmt.Errorf("%w: 1 file(s) failed to sync", servestore.ErrSyncIncomplete)
+
+	tests := []struct {
+		name   string
+		report *servestore.SyncReport
+		err    error
+		want   int
+	}{
+		{"synced", synced, nil, EX_OK},
+		{"nothing to sync", &servestore.SyncReport{}, nil, EX_OK},
+		{"conflict", conflicted, nil, EX_CONFLICT},
+		{"failed files", synced, incomplete, EX_TEMPFAIL},
+		{"failed files and a conflict", conflicted, incomplete, EX_TEMPFAIL},
+	}
+	for _, test := range tests {
+		if code := exitCode(test.report, test.err); code != test.want {
+			t.Errorf("%s: exit code %d, want %d", test.name, code, test.want)
+		}
+	}
+}
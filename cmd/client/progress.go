@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"rcjng/pkg/servestore"
+	"strings"
+	"time"
+)
+
+// Redraw the progress bar at most this often
+const PROGRESS_REDRAW_INTERVAL time.Duration = 100 * time.Millisecond
+const PROGRESS_BAR_WIDTH int = 30
+
+// progressBar draws the progress of a sync on a single terminal line from its events.
+// Events are passed to it one at a time, so it needs no locking.
+type progressBar struct {
+	out      io.Writer
+	lastDraw time.Time
+
+	scannedFiles int
+	scannedBytes int64
+
+	planned    bool
+	start      time.Time // When the plan was built and transfers started
+	totalFiles int
+	totalBytes int64
+	doneFiles  int
+	doneBytes  int64
+	failed     int
+}
+
+func newProgressBar(out io.Writer) *progressBar {
+	return &progressBar{out: out}
+}
+
+func (p *progressBar) event(event servestore.SyncEvent) {
+	switch event.Type {
+	case servestore.EVENT_FILE_SCANNED:
+		p.scannedFiles++
+		p.scannedBytes += event.Bytes
+	case servestore.EVENT_PLANNED:
+		p.planned, p.start = true, event.Time
+		p.totalFiles, p.totalBytes = event.TotalFiles, event.TotalBytes
+	case servestore.EVENT_BLOCK_UPLOADED, servestore.EVENT_BLOCK_DOWNLOADED:
+		p.doneBytes += event.Bytes
+	case servestore.EVENT_FILE_COMMITTED, servestore.EVENT_CONFLICT:
+		p.doneFiles++
+	case servestore.EVENT_FILE_FAILED:
+		p.doneFiles++
+		p.failed++
+	}
+
+	if event.Time.Sub(p.lastDraw) >= PROGRESS_REDRAW_INTERVAL || event.Type == servestore.EVENT_PLANNED {
+		p.lastDraw = event.Time
+		p.draw(event.Time)
+	}
+}
+
+func (p *progressBar) draw(now time.Time) {
+	if !p.planned {
+		fmt.Fprintf(p.out, "\r\033[KScanning: %d files, %s", p.scannedFiles, formatBytes(p.scannedBytes))
+		return
+	}
+
+	// Blocks already held by their replicas are not transferred, so the bytes may fall short of the total
+	fraction := 1.0
+	if p.totalBytes > 0 {
+		fraction = float64(p.doneBytes) / float64(p.totalBytes)
+	} else if p.totalFiles > 0 {
+		fraction = float64(p.doneFiles) / float64(p.totalFiles)
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * float64(PROGRESS_BAR_WIDTH))
+
+	line := fmt.Sprintf("[%s%s] %3.0f%%  %s/%s  %d/%d files",
+		strings.Repeat("#", filled), strings.Repeat(".", PROGRESS_BAR_WIDTH-filled), fraction*100,
+		formatBytes(p.doneBytes), formatBytes(p.totalBytes), p.doneFiles, p.totalFiles)
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 && p.doneBytes > 0 {
+		rate := float64(p.doneBytes) / elapsed
+		line += fmt.Sprintf("  %s/s", formatBytes(int64(rate)))
+		if remaining := p.totalBytes - p.doneBytes; remaining > 0 {
+			line += "  ETA " + formatDuration(time.Duration(float64(remaining)/rate*float64(time.Second)))
+		}
+	}
+	if p.failed > 0 {
+		line += fmt.Sprintf("  %d failed", p.failed)
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s", line)
+}
+
+// finish clears the progress bar, so the report is printed on a clean line
+func (p *progressBar) finish() {
+	fmt.Fprint(p.out, "\r\033[K")
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exponent := float64(n)/unit, 0
+	for value >= unit && exponent < 4 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exponent])
+}
+
+// formatDuration formats a duration as h:mm:ss, or m:ss under an hour
+func formatDuration(d time.Duration) string {
+	seconds := int64(d.Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
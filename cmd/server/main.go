@@ -7,23 +7,34 @@ import (
 	"io/ioutil"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"rcjng/pkg/servestore"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"google.golang.org/grpc"
 )
 
 // Usage String
-const USAGE_STRING = "./run-server.sh -s <service_type> -p <port> -l -d (blockStoreAddr*)"
+const USAGE_STRING = "./run-server.sh -s <service_type> -p <port> -l -d -c <cluster_config> -m <meta_store_addr> -bucket <bucket> -block-size <bytes> (blockStoreAddr*)"
 
 // Set of valid services
-var SERVICE_TYPES = map[string]bool{"meta": true, "block": true, "both": true}
+var SERVICE_TYPES = map[string]bool{"meta": true, "block": true, "both": true, "gateway": true, "s3": true}
 
 // Exit codes
 const EX_USAGE int = 64
 
+// s3Config is the bucket, region, credentials and block size of the S3 server
+type s3Config struct {
+	bucket      string
+	region      string
+	credentials map[string]string
+	blockSize   int
+}
+
 func main() {
 	// Custom flag Usage message
 	flag.Usage = func() {
@@ -32,22 +43,30 @@ func main() {
 		flag.VisitAll(func(f *flag.Flag) {
 			fmt.Fprintf(w, "  -%s: %v\n", f.Name, f.Usage)
 		})
-		fmt.Fprintf(w, "  (blockStoreAddr*): BlockStore Address (include self if service type is both)\n")
+		fmt.Fprintf(w, "  (blockStoreAddr*): BlockStore Addresses (include self if service type is both), unless a cluster config is given. Any BlockStore can register if none are given\n")
 	}
 
 	// Parse command-line argument flags
-	service := flag.String("s", "", "(required) Service Type of the Server: meta, block, both")
+	service := flag.String("s", "", "(required) Service Type of the Server: meta, block, both, gateway, s3")
 	port := flag.Int("p", 8080, "(default = 8080) Port to accept connections")
 	localOnly := flag.Bool("l", false, "Only listen on localhost")
 	debug := flag.Bool("d", false, "Output log statements")
+	configPath := flag.String("c", "", "Cluster config file listing the BlockStore addresses and how blocks are replicated on them, reloaded on SIGHUP")
+	rebalanceRate := flag.Int64("rebalance-rate", 0, "Bytes per second copied at most when rebalancing blocks after the cluster changes (default = unlimited)")
+	metaStoreAddr := flag.String("m", "", "MetaStore address a BlockStore registers with and sends heartbeats to, or whose files the gateway or S3 server serves")
+	advertiseAddr := flag.String("advertise", "", "Address the BlockStore registers as (default = first BlockStore address if service type is both, else this host and port)")
+	weight := flag.Int("weight", 1, "Weight the BlockStore registers with, unless the cluster config sets it")
+	s3 := s3Config{}
+	flag.StringVar(&s3.bucket, "bucket", "servestore", "Bucket the S3 server serves the files as")
+	flag.StringVar(&s3.region, "region", "us-east-1", "Region S3 requests are signed for")
+	s3AccessKey := flag.String("access-key", os.Getenv("AWS_ACCESS_KEY_ID"), "Access key S3 requests are signed with (default = $AWS_ACCESS_KEY_ID)")
+	// The secret key is only read from the environment, so that it does not show in process listings
+	s3SecretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	flag.IntVar(&s3.blockSize, "block-size", 4096, "Size of the blocks the S3 server cuts objects into")
 	flag.Parse()
 
-	// Use tail arguments to hold BlockStore address
-	args := flag.Args()
-	blockStoreAddr := ""
-	if len(args) == 1 {
-		blockStoreAddr = args[0]
-	}
+	// Use tail arguments to hold BlockStore addresses
+	blockStoreAddrs := flag.Args()
 
 	// Valid service type argument
 	if _, ok := SERVICE_TYPES[strings.ToLower(*service)]; !ok {
@@ -55,6 +74,22 @@ func main() {
 		os.Exit(EX_USAGE)
 	}
 
+	// The gateway and S3 server serve the files of a MetaStore, and S3 requests are signed
+	switch strings.ToLower(*service) {
+	case "gateway":
+		if *metaStoreAddr == "" {
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+	case "s3":
+		if *metaStoreAddr == "" || *s3AccessKey == "" || s3SecretKey == "" || s3.blockSize <= 0 {
+			fmt.Fprintln(os.Stderr, "The S3 server needs -m, -access-key and $AWS_SECRET_ACCESS_KEY, and a positive -block-size")
+			flag.Usage()
+			os.Exit(EX_USAGE)
+		}
+		s3.credentials = map[string]string{*s3AccessKey: s3SecretKey}
+	}
+
 	// Add localhost if necessary
 	addr := ""
 	if *localOnly {
@@ -68,10 +103,60 @@ func main() {
 		log.SetOutput(ioutil.Discard)
 	}
 
-	log.Fatal(startServer(addr, strings.ToLower(*service), blockStoreAddr))
+	// The MetaStore admits the BlockStores of the cluster config, or any if it has none
+	cluster := servestore.NewClusterConfig(blockStoreAddrs)
+	if *configPath != "" {
+		config, err := servestore.LoadClusterConfig(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(EX_USAGE)
+		}
+		cluster = config
+	}
+
+	// A BlockStore registers with its MetaStore, which is itself if service type is both
+	switch strings.ToLower(*service) {
+	case "block":
+		if *metaStoreAddr != "" {
+			go sendHeartbeats(*metaStoreAddr, blockStoreAdvertiseAddr(*advertiseAddr, nil, *localOnly, *port), *weight)
+		}
+	case "both":
+		go sendHeartbeats(addr, blockStoreAdvertiseAddr(*advertiseAddr, cluster, *localOnly, *port), *weight)
+	}
+
+	log.Fatal(startServer(addr, strings.ToLower(*service), cluster, *configPath, *rebalanceRate, *metaStoreAddr, s3))
+}
+
+// blockStoreAdvertiseAddr returns the address a BlockStore registers as: the given one,
+// else the first BlockStore of the cluster, else this host and port
+func blockStoreAdvertiseAddr(advertiseAddr string, cluster *servestore.ClusterConfig, localOnly bool, port int) string {
+	if advertiseAddr != "" {
+		return advertiseAddr
+	}
+	if cluster != nil && len(cluster.BlockStoreAddrs) > 0 {
+		return cluster.BlockStoreAddrs[0]
+	}
+
+	host := "localhost"
+	if !localOnly {
+		if hostname, err := os.Hostname(); err == nil {
+			host = hostname
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
 }
 
-func startServer(hostAddr string, serviceType string, blockStoreAddr string) error {
+// sendHeartbeats registers the BlockStore at blockStoreAddr with the MetaStore at
+// metaStoreAddr, and keeps sending it heartbeats
+func sendHeartbeats(metaStoreAddr string, blockStoreAddr string, weight int) {
+	client := servestore.NewServeStoreRPCClient(metaStoreAddr, "", 0)
+	servestore.SendHeartbeats(&client, blockStoreAddr, weight, nil)
+}
+
+// startServer serves the service on hostAddr. A MetaStore reloads the cluster config from
+// configPath on SIGHUP if it is set, and copies at most rebalanceRate bytes per second
+// while rebalancing. The S3 server is configured by s3.
+func startServer(hostAddr string, serviceType string, cluster *servestore.ClusterConfig, configPath string, rebalanceRate int64, metaStoreAddr string, s3 s3Config) error {
 	listener, err := net.Listen("tcp", hostAddr)
 	if err != nil {
 		fmt.Printf("Failed to listen: %v", err)
@@ -80,11 +165,15 @@ func startServer(hostAddr string, serviceType string, blockStoreAddr string) err
 
 	switch serviceType {
 	case "meta":
-		return startMetaServer(listener, blockStoreAddr)
+		return startMetaServer(listener, newMetaStore(cluster, configPath, rebalanceRate))
 	case "block":
 		return startBlockServer(listener)
 	case "both":
-		return startBothServers(listener, blockStoreAddr)
+		return startBothServers(listener, newMetaStore(cluster, configPath, rebalanceRate))
+	case "gateway":
+		return startGatewayServer(listener, metaStoreAddr)
+	case "s3":
+		return startS3Server(listener, metaStoreAddr, s3)
 	}
 
 	return errors.New("unknown service type")
@@ -100,24 +189,69 @@ func startBlockServer(listener net.Listener) error {
 	return grpcServer.Serve(listener)
 }
 
-func startMetaServer(listener net.Listener, blockStoreAddr string) error {
+func startMetaServer(listener net.Listener, metaStoreServer *servestore.MetaStore) error {
 	fmt.Println("Starting MetaStore server!")
 
 	var opts []grpc.ServerOption
 	grpcServer := grpc.NewServer(opts...)
-	metaStoreServer := servestore.NewMetaStore(blockStoreAddr)
 	servestore.RegisterMetaStoreServer(grpcServer, metaStoreServer)
 	return grpcServer.Serve(listener)
 }
 
-func startBothServers(listener net.Listener, blockStoreAddr string) error {
+func startBothServers(listener net.Listener, metaStoreServer *servestore.MetaStore) error {
 	fmt.Println("Starting both servers!")
 
 	var opts []grpc.ServerOption
 	grpcServer := grpc.NewServer(opts...)
 	blockStoreServer := servestore.NewBlockStore()
 	servestore.RegisterBlockStoreServer(grpcServer, blockStoreServer)
-	metaStoreServer := servestore.NewMetaStore(blockStoreAddr)
 	servestore.RegisterMetaStoreServer(grpcServer, metaStoreServer)
 	return grpcServer.Serve(listener)
 }
+
+// startGatewayServer serves the files of the MetaStore at metaStoreAddr read-only over
+// HTTP and WebDAV
+func startGatewayServer(listener net.Listener, metaStoreAddr string) error {
+	fmt.Println("Starting gateway server!")
+
+	client := servestore.NewServeStoreRPCClient(metaStoreAddr, "", 0)
+	return http.Serve(listener, servestore.NewGateway(&client))
+}
+
+// startS3Server serves the files of the MetaStore at metaStoreAddr as the S3 bucket of s3
+func startS3Server(listener net.Listener, metaStoreAddr string, s3 s3Config) error {
+	fmt.Println("Starting S3 server!")
+
+	client := servestore.NewServeStoreRPCClient(metaStoreAddr, "", s3.blockSize)
+	return http.Serve(listener, servestore.NewS3Server(&client, s3.bucket, s3.region, s3.credentials, s3.blockSize))
+}
+
+// newMetaStore returns a MetaStore for the cluster, which is changed whenever the
+// cluster config at configPath is reloaded
+func newMetaStore(cluster *servestore.ClusterConfig, configPath string, rebalanceRate int64) *servestore.MetaStore {
+	metaStore := servestore.NewMetaStoreFromConfig(cluster)
+	metaStore.RebalanceBytesPerSecond = rebalanceRate
+	go metaStore.MonitorMembership(nil)
+
+	if configPath != "" {
+		go reloadClusterConfig(metaStore, configPath)
+	}
+	return metaStore
+}
+
+// reloadClusterConfig reloads the cluster config from configPath on every SIGHUP. A config that
+// cannot be loaded is ignored.
+func reloadClusterConfig(metaStore *servestore.MetaStore, configPath string) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	for range hangups {
+		log.Println("Reloading cluster config", configPath)
+		cluster, err := servestore.LoadClusterConfig(configPath)
+		if err != nil {
+			log.Printf("Reload cluster config: %v", err)
+			continue
+		}
+		metaStore.UpdateCluster(cluster)
+	}
+}
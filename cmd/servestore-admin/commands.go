@@ -0,0 +1,401 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"rcjng/pkg/servestore"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockInfo is a block of a file, the BlockStores it is placed on and those holding it
+type blockInfo struct {
+	Index    int      `json:"index"`
+	Hash     string   `json:"hash"`
+	Size     int64    `json:"size"`
+	Replicas []string `json:"replicas"`
+	Held     []string `json:"held"`
+}
+
+// blockStoreInfo is the state of a BlockStore and the blocks it holds. Blocks and Bytes
+// are left out if it could not be reached.
+type blockStoreInfo struct {
+	Addr   string `json:"addr"`
+	State  string `json:"state"`
+	OnRing bool   `json:"onRing"`
+	Blocks *int   `json:"blocks,omitempty"`
+	Bytes  *int64 `json:"bytes,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// memberInfo is a BlockStore of the cluster as printed by the members command
+type memberInfo struct {
+	Addr          string `json:"addr"`
+	State         string `json:"state"`
+	OnRing        bool   `json:"onRing"`
+	Weight        int32  `json:"weight"`
+	LastHeartbeat string `json:"lastHeartbeat"`
+}
+
+type membershipInfo struct {
+	Epoch   int64         `json:"epoch"`
+	Members []*memberInfo `json:"members"`
+}
+
+func (a *admin) ls(args []string) error {
+	flags := flag.NewFlagSet("ls", flag.ContinueOnError)
+	all := flags.Bool("a", false, "Include deleted files")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 1 {
+		return errUsage
+	}
+	prefix := flags.Arg(0)
+
+	fileInfoMap, err := a.fileInfoMap()
+	if err != nil {
+		return err
+	}
+
+	files := make([]*fileInfo, 0)
+	for filename, fileMetaData := range fileInfoMap {
+		if !strings.HasPrefix(filename, prefix) || (deleted(fileMetaData) && !*all) {
+			continue
+		}
+		files = append(files, newFileInfo(fileMetaData, false))
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	if a.jsonOutput {
+		return writeJSON(a.stdout, files)
+	}
+	table := tabwriter.NewWriter(a.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tTYPE\tVERSION\tSIZE\tBLOCKS\tMODE\tMODIFIED")
+	for _, file := range files {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			strconv.Quote(file.Name), file.Type, file.Version, file.Size, file.Blocks, orDash(file.Mode), orDash(file.Modified))
+	}
+	return table.Flush()
+}
+
+func (a *admin) stat(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	fileMetaData, err := a.file(args[0])
+	if err != nil {
+		return err
+	}
+	file := newFileInfo(fileMetaData, true)
+
+	if a.jsonOutput {
+		return writeJSON(a.stdout, file)
+	}
+	table := tabwriter.NewWriter(a.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(table, "Name:\t%s\n", strconv.Quote(file.Name))
+	fmt.Fprintf(table, "Type:\t%s\n", file.Type)
+	fmt.Fprintf(table, "Version:\t%d\n", file.Version)
+	fmt.Fprintf(table, "Size:\t%d\n", file.Size)
+	fmt.Fprintf(table, "Blocks:\t%d\n", file.Blocks)
+	if file.BlockSize != 0 {
+		fmt.Fprintf(table, "Block size:\t%d\n", file.BlockSize)
+	}
+	fmt.Fprintf(table, "Mode:\t%s\n", orDash(file.Mode))
+	fmt.Fprintf(table, "Modified:\t%s\n", orDash(file.Modified))
+	if file.SymlinkTarget != "" {
+		fmt.Fprintf(table, "Symlink target:\t%s\n", strconv.Quote(file.SymlinkTarget))
+	}
+	if file.HardlinkTarget != "" {
+		fmt.Fprintf(table, "Hardlink target:\t%s\n", strconv.Quote(file.HardlinkTarget))
+	}
+	if file.RenamedFrom != "" {
+		fmt.Fprintf(table, "Renamed from:\t%s\n", strconv.Quote(file.RenamedFrom))
+	}
+	if file.RenamedTo != "" {
+		fmt.Fprintf(table, "Renamed to:\t%s\n", strconv.Quote(file.RenamedTo))
+	}
+	if len(file.Xattrs) > 0 {
+		fmt.Fprintf(table, "Xattrs:\t%s\n", xattrNames(file.Xattrs))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	for i, blockHash := range file.BlockHashes {
+		fmt.Fprintf(a.stdout, "%6d %s\n", i, blockHash)
+	}
+	return nil
+}
+
+// cat writes the blocks of a file to stdout in order. Each block is checked against its
+// hash, and read from another replica if it does not match.
+func (a *admin) cat(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	fileMetaData, err := a.file(args[0])
+	if err != nil {
+		return err
+	}
+	if deleted(fileMetaData) {
+		return fmt.Errorf("%s is deleted", args[0])
+	}
+	placement, err := servestore.FetchBlockPlacement(a.client)
+	if err != nil {
+		return err
+	}
+
+	for _, blockHash := range fileMetaData.GetBlockHashList() {
+		block := &servestore.Block{}
+		if err := placement.GetBlock(a.client, blockHash, block); err != nil {
+			return fmt.Errorf("get block %s: %w", blockHash, err)
+		}
+		if _, err := a.stdout.Write(block.GetBlockData()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *admin) blocks(args []string) error {
+	switch len(args) {
+	case 0:
+		return a.blockStores()
+	case 1:
+		return a.fileBlocks(args[0])
+	default:
+		return errUsage
+	}
+}
+
+// fileBlocks lists the blocks of a file with their replicas and the replicas holding them
+func (a *admin) fileBlocks(filename string) error {
+	fileMetaData, err := a.file(filename)
+	if err != nil {
+		return err
+	}
+	if deleted(fileMetaData) {
+		return fmt.Errorf("%s is deleted", filename)
+	}
+	placement, err := servestore.FetchBlockPlacement(a.client)
+	if err != nil {
+		return err
+	}
+
+	// Ask each BlockStore once for the sizes of the blocks placed on it
+	blockHashesByServer := make(map[string][]string)
+	for _, blockHash := range fileMetaData.GetBlockHashList() {
+		for _, server := range placement.Replicas(blockHash) {
+			blockHashesByServer[server] = append(blockHashesByServer[server], blockHash)
+		}
+	}
+	sizesByServer := make(map[string]map[string]int64)
+	for server, blockHashes := range blockHashesByServer {
+		sizes := make(map[string]int64)
+		if err := a.client.GetBlockSizes(blockHashes, server, &sizes); err != nil {
+			log.Printf("Get block sizes from %s: %v", server, err)
+		}
+		sizesByServer[server] = sizes
+	}
+
+	blocks := make([]*blockInfo, 0)
+	for i, blockHash := range fileMetaData.GetBlockHashList() {
+		block := &blockInfo{Index: i, Hash: blockHash, Size: -1, Replicas: placement.Replicas(blockHash), Held: make([]string, 0)}
+		for _, server := range block.Replicas {
+			if size, ok := sizesByServer[server][blockHash]; ok {
+				block.Held = append(block.Held, server)
+				block.Size = size
+			}
+		}
+		blocks = append(blocks, block)
+	}
+
+	if a.jsonOutput {
+		return writeJSON(a.stdout, blocks)
+	}
+	table := tabwriter.NewWriter(a.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "INDEX\tHASH\tSIZE\tREPLICAS\tMISSING")
+	for _, block := range blocks {
+		size := "-"
+		if block.Size >= 0 {
+			size = strconv.FormatInt(block.Size, 10)
+		}
+		missing := make([]string, 0)
+		held := make(map[string]bool)
+		for _, server := range block.Held {
+			held[server] = true
+		}
+		for _, server := range block.Replicas {
+			if !held[server] {
+				missing = append(missing, server)
+			}
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\n",
+			block.Index, block.Hash, size, strings.Join(block.Replicas, ","), orDash(strings.Join(missing, ",")))
+	}
+	return table.Flush()
+}
+
+// blockStores lists the BlockStores of the cluster with the number and bytes of the blocks
+// each holds. Dead BlockStores are not asked.
+func (a *admin) blockStores() error {
+	membership, err := a.membership()
+	if err != nil {
+		return err
+	}
+
+	blockStores := make([]*blockStoreInfo, 0)
+	for _, member := range membership.GetMembers() {
+		info := &blockStoreInfo{Addr: member.GetAddr(), State: member.GetState().String(), OnRing: member.GetOnRing()}
+		blockStores = append(blockStores, info)
+		if member.GetState() == servestore.MemberState_DEAD {
+			continue
+		}
+
+		// A range that starts where it ends is the whole ring
+		var blockHashes []string
+		if err := a.client.ListBlocks("", "", member.GetAddr(), &blockHashes); err != nil {
+			info.Error = err.Error()
+			continue
+		}
+		sizes := make(map[string]int64)
+		if err := a.client.GetBlockSizes(blockHashes, member.GetAddr(), &sizes); err != nil {
+			info.Error = err.Error()
+			continue
+		}
+		blocks, bytes := len(sizes), int64(0)
+		for _, size := range sizes {
+			bytes += size
+		}
+		info.Blocks, info.Bytes = &blocks, &bytes
+	}
+
+	if a.jsonOutput {
+		return writeJSON(a.stdout, blockStores)
+	}
+	table := tabwriter.NewWriter(a.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "BLOCKSTORE\tSTATE\tON RING\tBLOCKS\tBYTES")
+	for _, info := range blockStores {
+		blocks, bytes := "-", "-"
+		if info.Blocks != nil {
+			blocks, bytes = strconv.Itoa(*info.Blocks), strconv.FormatInt(*info.Bytes, 10)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%t\t%s\t%s\n", info.Addr, info.State, info.OnRing, blocks, bytes)
+	}
+	return table.Flush()
+}
+
+func (a *admin) versions(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	var versions []*servestore.FileMetaData
+	if err := a.client.GetFileVersions(args[0], &versions); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%s does not exist", args[0])
+		}
+		return fmt.Errorf("get versions of %s: %w", args[0], err)
+	}
+
+	files := make([]*fileInfo, 0, len(versions))
+	for _, fileMetaData := range versions {
+		files = append(files, newFileInfo(fileMetaData, false))
+	}
+
+	if a.jsonOutput {
+		return writeJSON(a.stdout, files)
+	}
+	table := tabwriter.NewWriter(a.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "VERSION\tTYPE\tSIZE\tBLOCKS\tMODE\tMODIFIED")
+	for _, file := range files {
+		fmt.Fprintf(table, "%d\t%s\t%d\t%d\t%s\t%s\n",
+			file.Version, file.Type, file.Size, file.Blocks, orDash(file.Mode), orDash(file.Modified))
+	}
+	return table.Flush()
+}
+
+func (a *admin) members(args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	membership, err := a.membership()
+	if err != nil {
+		return err
+	}
+
+	info := &membershipInfo{Epoch: membership.GetEpoch(), Members: make([]*memberInfo, 0)}
+	for _, member := range membership.GetMembers() {
+		lastHeartbeat := ""
+		if member.GetLastHeartbeat() != 0 {
+			lastHeartbeat = time.Unix(0, member.GetLastHeartbeat()).UTC().Format(time.RFC3339)
+		}
+		info.Members = append(info.Members, &memberInfo{
+			Addr:          member.GetAddr(),
+			State:         member.GetState().String(),
+			OnRing:        member.GetOnRing(),
+			Weight:        member.GetWeight(),
+			LastHeartbeat: lastHeartbeat,
+		})
+	}
+
+	if a.jsonOutput {
+		return writeJSON(a.stdout, info)
+	}
+	fmt.Fprintf(a.stdout, "Ring epoch: %d\n\n", info.Epoch)
+	table := tabwriter.NewWriter(a.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "BLOCKSTORE\tSTATE\tON RING\tWEIGHT\tLAST HEARTBEAT")
+	for _, member := range info.Members {
+		fmt.Fprintf(table, "%s\t%s\t%t\t%d\t%s\n", member.Addr, member.State, member.OnRing, member.Weight, orDash(member.LastHeartbeat))
+	}
+	return table.Flush()
+}
+
+func (a *admin) fileInfoMap() (map[string]*servestore.FileMetaData, error) {
+	fileInfoMap := make(map[string]*servestore.FileMetaData)
+	if err := a.client.GetFileInfoMap(&fileInfoMap); err != nil {
+		return nil, fmt.Errorf("get remote index: %w", err)
+	}
+	return fileInfoMap, nil
+}
+
+// file returns the metadata of a remote file
+func (a *admin) file(filename string) (*servestore.FileMetaData, error) {
+	fileInfoMap, err := a.fileInfoMap()
+	if err != nil {
+		return nil, err
+	}
+	fileMetaData, exists := fileInfoMap[filename]
+	if !exists {
+		return nil, fmt.Errorf("%s does not exist", filename)
+	}
+	return fileMetaData, nil
+}
+
+// membership returns the BlockStores of the cluster. A MetaStore that does not track
+// membership reports the BlockStores on its ring as live.
+func (a *admin) membership() (*servestore.Membership, error) {
+	membership := &servestore.Membership{}
+	err := a.client.GetMembership(membership)
+	if status.Code(err) == codes.Unimplemented {
+		placement, err := servestore.FetchBlockPlacement(a.client)
+		if err != nil {
+			return nil, err
+		}
+		for _, server := range placement.Ring.Servers() {
+			membership.Members = append(membership.Members, &servestore.BlockStoreMember{
+				Addr:   server,
+				Weight: int32(placement.Ring.Weights[server]),
+				OnRing: true,
+			})
+		}
+		return membership, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get membership: %w", err)
+	}
+	return membership, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"rcjng/pkg/servestore"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Usage string
+const USAGE_STRING = "servestore-admin -d -json host:port command [arguments]"
+
+// Exit codes
+const EX_ERROR int = 1
+const EX_USAGE int = 64
+
+// File types shown for FileMetaData
+const (
+	TYPE_FILE     string = "file"
+	TYPE_SYMLINK  string = "symlink"
+	TYPE_HARDLINK string = "hardlink"
+	TYPE_DELETED  string = "deleted"
+)
+
+var errUsage = errors.New("usage")
+
+// command is a subcommand of the tool, run with the arguments that follow its name
+type command struct {
+	args        string
+	description string
+	run         func(a *admin, args []string) error
+}
+
+var commands = map[string]command{
+	"ls":       {"[-a] [prefix]", "List the files, or those whose name starts with prefix", (*admin).ls},
+	"stat":     {"file", "Print the metadata of a file", (*admin).stat},
+	"cat":      {"file", "Reassemble a file from its blocks and write it to stdout", (*admin).cat},
+	"blocks":   {"[file]", "List the blocks of a file and the BlockStores holding them, or the blocks on each BlockStore", (*admin).blocks},
+	"versions": {"file", "List the versions of a file the MetaStore kept", (*admin).versions},
+	"members":  {"", "List the BlockStores of the cluster and their state", (*admin).members},
+}
+
+// admin runs commands against the cluster of a MetaStore
+type admin struct {
+	client     *servestore.RPCClient
+	jsonOutput bool
+	stdout     io.Writer
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if errors.Is(err, errUsage) {
+		os.Exit(EX_USAGE)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "servestore-admin:", err)
+		os.Exit(EX_ERROR)
+	}
+}
+
+// run runs the command named in args against the MetaStore named in args
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	debug := flags.Bool("d", false, "Output log statements")
+	jsonOutput := flags.Bool("json", false, "Output JSON instead of tables")
+	flags.Usage = func() {
+		w := flags.Output()
+		fmt.Fprintf(w, "Usage of %s:\n", USAGE_STRING)
+		flags.PrintDefaults()
+		fmt.Fprintln(w, "Commands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s %s\n    \t%s\n", name, commands[name].args, commands[name].description)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return errUsage
+	}
+	cmd, ok := commands[flags.Arg(1)]
+	if !ok {
+		fmt.Fprintf(flags.Output(), "Unknown command %q\n", flags.Arg(1))
+		flags.Usage()
+		return errUsage
+	}
+
+	// Disable log outputs if debug flag is missing
+	if !(*debug) {
+		log.SetFlags(0)
+		log.SetOutput(ioutil.Discard)
+	}
+
+	client := servestore.NewServeStoreRPCClient(flags.Arg(0), "", 0)
+	a := &admin{client: &client, jsonOutput: *jsonOutput, stdout: stdout}
+	err := cmd.run(a, flags.Args()[2:])
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(flags.Output(), "Usage: %s host:port %s %s\n", os.Args[0], flags.Arg(1), cmd.args)
+	}
+	return err
+}
+
+// fileInfo is the metadata of a file as printed by the tool
+type fileInfo struct {
+	Name           string            `json:"name"`
+	Type           string            `json:"type"`
+	Version        int32             `json:"version"`
+	Size           int64             `json:"size"`
+	Blocks         int               `json:"blocks"`
+	BlockSize      int32             `json:"blockSize,omitempty"`
+	Mode           string            `json:"mode,omitempty"`
+	Modified       string            `json:"modified,omitempty"`
+	SymlinkTarget  string            `json:"symlinkTarget,omitempty"`
+	HardlinkTarget string            `json:"hardlinkTarget,omitempty"`
+	RenamedFrom    string            `json:"renamedFrom,omitempty"`
+	RenamedTo      string            `json:"renamedTo,omitempty"`
+	Xattrs         map[string][]byte `json:"xattrs,omitempty"`
+	BlockHashes    []string          `json:"blockHashes,omitempty"`
+}
+
+// newFileInfo returns the metadata of a file, with its block hashes if withHashes is set
+func newFileInfo(fileMetaData *servestore.FileMetaData, withHashes bool) *fileInfo {
+	info := &fileInfo{
+		Name:           fileMetaData.GetFilename(),
+		Type:           fileType(fileMetaData),
+		Version:        fileMetaData.GetVersion(),
+		Size:           fileMetaData.GetSize(),
+		Blocks:         len(fileMetaData.GetBlockHashList()),
+		BlockSize:      fileMetaData.GetBlockSize(),
+		SymlinkTarget:  fileMetaData.GetSymlinkTarget(),
+		HardlinkTarget: fileMetaData.GetHardlinkTarget(),
+		RenamedFrom:    fileMetaData.GetRenamedFrom(),
+		RenamedTo:      fileMetaData.GetRenamedTo(),
+		Xattrs:         fileMetaData.GetXattrs(),
+	}
+	if info.Type == TYPE_DELETED {
+		info.Blocks = 0
+		return info
+	}
+	if fileMetaData.GetMode() != 0 {
+		info.Mode = fmt.Sprintf("%04o", fileMetaData.GetMode())
+	}
+	if fileMetaData.GetMtime() != 0 {
+		info.Modified = time.Unix(0, fileMetaData.GetMtime()).UTC().Format(time.RFC3339)
+	}
+	if withHashes {
+		info.BlockHashes = fileMetaData.GetBlockHashList()
+	}
+	return info
+}
+
+func fileType(fileMetaData *servestore.FileMetaData) string {
+	switch {
+	case deleted(fileMetaData):
+		return TYPE_DELETED
+	case fileMetaData.GetSymlinkTarget() != "":
+		return TYPE_SYMLINK
+	case fileMetaData.GetHardlinkTarget() != "":
+		return TYPE_HARDLINK
+	default:
+		return TYPE_FILE
+	}
+}
+
+// deleted reports whether the file metadata marks a deleted file
+func deleted(fileMetaData *servestore.FileMetaData) bool {
+	blockHashList := fileMetaData.GetBlockHashList()
+	return len(blockHashList) == 1 && blockHashList[0] == servestore.TOMBSTONE_HASH
+}
+
+// orDash returns s, or "-" if it is empty, for table cells
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// xattrNames returns the sorted names of the extended attributes of a file
+func xattrNames(xattrs map[string][]byte) string {
+	names := make([]string, 0, len(xattrs))
+	for name := range xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"rcjng/pkg/servestore"
+	"rcjng/pkg/servestoretest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testCluster is a MetaStore and two BlockStores holding every block, served on localhost
+type testCluster struct {
+	metaStoreAddr   string
+	blockStoreAddrs []string
+}
+
+func startTestCluster(t *testing.T) *testCluster {
+	cluster := servestoretest.NewCluster(t, 2, 2)
+	return &testCluster{metaStoreAddr: cluster.MetaStoreAddr, blockStoreAddrs: cluster.BlockStoreAddrs}
+}
+
+// putFile stores content as a new version of filename on every BlockStore
+func (c *testCluster) putFile(t *testing.T, filename string, version int32, content string, blockSize int) {
+	client := servestore.NewServeStoreRPCClient(c.metaStoreAddr, "", blockSize)
+	fileMetaData := &servestore.FileMetaData{Filename: filename, Version: version, Size: int64(len(content)), Mode: 0644, Mtime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()}
+	for start := 0; start < len(content); start += blockSize {
+		end := start + blockSize
+		if end > len(content) {
+			end = len(content)
+		}
+		block := &servestore.Block{BlockData: []byte(content[start:end]), BlockSize: int32(end - start)}
+		for _, addr := range c.blockStoreAddrs {
+			var success bool
+			if err := client.PutBlock(block, addr, &success); err != nil || !success {
+				t.Fatalf("put block: %v", err)
+			}
+		}
+		fileMetaData.BlockHashList = append(fileMetaData.BlockHashList, servestore.GetBlockHashString(block.BlockData))
+	}
+	c.updateFile(t, fileMetaData)
+}
+
+func (c *testCluster) updateFile(t *testing.T, fileMetaData *servestore.FileMetaData) {
+	client := servestore.NewServeStoreRPCClient(c.metaStoreAddr, "", 0)
+	var latestVersion int32
+	if err := client.UpdateFile(fileMetaData, &latestVersion); err != nil || latestVersion != fileMetaData.GetVersion() {
+		t.Fatalf("update %s: version %d, %v", fileMetaData.GetFilename(), latestVersion, err)
+	}
+}
+
+// run runs a command against the cluster, with -json if args start with it
+func (c *testCluster) run(t *testing.T, args ...string) string {
+	runArgs := []string{c.metaStoreAddr}
+	if len(args) > 0 && args[0] == "-json" {
+		runArgs = []string{"-json", c.metaStoreAddr}
+		args = args[1:]
+	}
+
+	var stdout bytes.Buffer
+	if err := run(append(runArgs, args...), &stdout); err != nil {
+		t.Fatalf("run %v: %v", args, err)
+	}
+	return stdout.String()
+}
+
+func populatedTestCluster(t *testing.T) *testCluster {
+	c := startTestCluster(t)
+	c.putFile(t, "docs/a.txt", 1, "first version", 4)
+	c.putFile(t, "docs/a.txt", 2, "the second version of a", 4)
+	c.putFile(t, "b.txt", 1, "bbbb", 4)
+	c.updateFile(t, &servestore.FileMetaData{Filename: "gone.txt", Version: 1, BlockHashList: []string{servestore.TOMBSTONE_HASH}})
+	return c
+}
+
+func TestLs(t *testing.T) {
+	c := populatedTestCluster(t)
+
+	output := c.run(t, "ls")
+	if !strings.Contains(output, `"docs/a.txt"  file  2        23    6       0644  2026-01-02T03:04:05Z`) ||
+		!strings.Contains(output, `"b.txt"`) || strings.Contains(output, "gone.txt") {
+		t.Errorf("ls printed\n%s", output)
+	}
+
+	var files []*fileInfo
+	if err := json.Unmarshal([]byte(c.run(t, "-json", "ls", "-a", "docs/")), &files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "docs/a.txt" || files[0].Version != 2 || files[0].Blocks != 6 {
+		t.Errorf("ls -a docs/ listed %+v", files)
+	}
+
+	if err := json.Unmarshal([]byte(c.run(t, "-json", "ls", "-a")), &files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || files[2].Name != "gone.txt" || files[2].Type != TYPE_DELETED {
+		t.Errorf("ls -a listed %+v", files)
+	}
+}
+
+func TestStatAndCat(t *testing.T) {
+	c := populatedTestCluster(t)
+
+	var file fileInfo
+	if err := json.Unmarshal([]byte(c.run(t, "-json", "stat", "docs/a.txt")), &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Size != 23 || len(file.BlockHashes) != 6 || file.Mode != "0644" {
+		t.Errorf("stat printed %+v", file)
+	}
+
+	if output := c.run(t, "cat", "docs/a.txt"); output != "the second version of a" {
+		t.Errorf("cat printed %q", output)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	c := populatedTestCluster(t)
+
+	var blocks []*blockInfo
+	if err := json.Unmarshal([]byte(c.run(t, "-json", "blocks", "b.txt")), &blocks); err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].Size != 4 || len(blocks[0].Replicas) != 2 || len(blocks[0].Held) != 2 {
+		t.Errorf("blocks b.txt printed %+v", blocks)
+	}
+
+	// Both BlockStores hold every block of every version
+	var blockStores []*blockStoreInfo
+	if err := json.Unmarshal([]byte(c.run(t, "-json", "blocks")), &blockStores); err != nil {
+		t.Fatal(err)
+	}
+	if len(blockStores) != 2 {
+		t.Fatalf("blocks printed %+v", blockStores)
+	}
+	for _, info := range blockStores {
+		if info.Blocks == nil || *info.Blocks != 11 || *info.Bytes != 13+23+4 || !info.OnRing {
+			t.Errorf("BlockStore %+v, want 11 blocks of 40 bytes", info)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	c := populatedTestCluster(t)
+
+	var files []*fileInfo
+	if err := json.Unmarshal([]byte(c.run(t, "-json", "versions", "docs/a.txt")), &files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Version != 1 || files[0].Size != 13 || files[1].Version != 2 {
+		t.Errorf("versions printed %+v", files)
+	}
+
+	output := c.run(t, "versions", "gone.txt")
+	if !strings.Contains(output, "1        deleted") {
+		t.Errorf("versions gone.txt printed\n%s", output)
+	}
+}
+
+func TestMembers(t *testing.T) {
+	c := populatedTestCluster(t)
+
+	var membership membershipInfo
+	if err := json.Unmarshal([]byte(c.run(t, "-json", "members")), &membership); err != nil {
+		t.Fatal(err)
+	}
+	if membership.Epoch == 0 || len(membership.Members) != 2 || membership.Members[0].State != "LIVE" {
+		t.Errorf("members printed %+v", membership)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := populatedTestCluster(t)
+
+	tests := [][]string{
+		{c.metaStoreAddr},
+		{c.metaStoreAddr, "rm", "b.txt"},
+		{c.metaStoreAddr, "stat"},
+		{c.metaStoreAddr, "stat", "missing.txt"},
+		{c.metaStoreAddr, "cat", "gone.txt"},
+		{c.metaStoreAddr, "versions", "missing.txt"},
+		{c.metaStoreAddr, "members", "extra"},
+	}
+	for _, args := range tests {
+		var stdout bytes.Buffer
+		if err := run(args, &stdout); err == nil {
+			t.Errorf("run %v succeeded, want error", args)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("run %v printed %q", args, stdout.String())
+		}
+	}
+}
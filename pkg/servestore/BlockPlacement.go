@@ -0,0 +1,189 @@
+package servestore
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+var ErrQuorumNotReached = errors.New("ErrQuorumNotReached")
+
+// BlockPlacement decides which BlockStores a block is stored on, and how many of them
+// have to take part in a write or a read. Each block is stored on the ReplicationFactor
+// distinct servers that follow it clockwise on the ring, its replicas.
+//
+// While blocks are being moved after the cluster changed, Previous is the placement
+// before the change. Blocks that cannot be read from their replicas are read from their
+// previous replicas instead.
+//
+// Replicas in Suspect, which the MetaStore suspects to be down, are read from last.
+type BlockPlacement struct {
+	Ring              *ConsistentHashRing
+	ReplicationFactor int
+	WriteQuorum       int
+	ReadQuorum        int
+	Previous          *BlockPlacement
+	Suspect           map[string]bool
+
+	serverHashes []string
+}
+
+// NewBlockPlacement returns the placement of blocks on the BlockStores of the cluster
+func NewBlockPlacement(cluster *ClusterConfig) *BlockPlacement {
+	ring := cluster.HashRing()
+	return &BlockPlacement{
+		Ring:              ring,
+		ReplicationFactor: cluster.Replicas(),
+		WriteQuorum:       cluster.WriteQuorumSize(),
+		ReadQuorum:        cluster.ReadQuorumSize(),
+		serverHashes:      ring.sortedServerHashes(),
+	}
+}
+
+// Replicas returns the BlockStores that store the block, in the order they are read from
+func (p *BlockPlacement) Replicas(blockHash string) []string {
+	return p.Ring.replicaServers(p.serverHashes, blockHash, p.ReplicationFactor)
+}
+
+// HasBlocks asks the replicas of each block whether they hold it, and returns the replicas
+// holding each block. A BlockStore that cannot be reached is treated as holding none of its
+// blocks, so that writing them decides whether enough replicas are up.
+func (p *BlockPlacement) HasBlocks(client ClientInterface, blockHashes []string) map[string]map[string]bool {
+	blocksByServer := make(map[string][]string)
+	for _, blockHash := range blockHashes {
+		for _, server := range p.Replicas(blockHash) {
+			blocksByServer[server] = append(blocksByServer[server], blockHash)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	holders := make(map[string]map[string]bool)
+	for server, serverBlockHashes := range blocksByServer {
+		wg.Add(1)
+		go func(server string, serverBlockHashes []string) {
+			defer wg.Done()
+
+			commonBlocks := []string{}
+			if err := client.HasBlocks(serverBlockHashes, server, &commonBlocks); err != nil {
+				log.Printf("Check blocks on %s: %v", server, err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, blockHash := range commonBlocks {
+				if holders[blockHash] == nil {
+					holders[blockHash] = make(map[string]bool)
+				}
+				holders[blockHash][server] = true
+			}
+		}(server, serverBlockHashes)
+	}
+	wg.Wait()
+
+	return holders
+}
+
+// PutBlock writes the block to each of its replicas that does not hold it yet, given the
+// replicas that do. It succeeds once WriteQuorum replicas hold the block, even if writing
+// to the others failed, and returns the number of replicas written to.
+func (p *BlockPlacement) PutBlock(client ClientInterface, block *Block, blockHash string, holders map[string]bool) (int, error) {
+	replicas := p.Replicas(blockHash)
+
+	var wg sync.WaitGroup
+	results := make(chan error, len(replicas))
+	held := 0
+	for _, server := range replicas {
+		if holders[server] {
+			held++
+			continue
+		}
+
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+
+			var success bool
+			err := client.PutBlock(block, server, &success)
+			if err == nil && !success {
+				err = errors.New("rejected by BlockStore")
+			}
+			if err != nil {
+				err = fmt.Errorf("%s: %w", server, err)
+			}
+			results <- err
+		}(server)
+	}
+	wg.Wait()
+	close(results)
+
+	written := 0
+	var lastErr error
+	for err := range results {
+		if err != nil {
+			log.Printf("Put block %s: %v", blockHash, err)
+			lastErr = err
+			continue
+		}
+		written++
+	}
+
+	if held+written < p.WriteQuorum {
+		return written, fmt.Errorf("%w: %d of %d replicas hold the block, %d needed: %v",
+			ErrQuorumNotReached, held+written, len(replicas), p.WriteQuorum, lastErr)
+	}
+	return written, nil
+}
+
+// GetBlock reads the block from its replicas in order, suspect replicas last, failing over
+// to the next replica when one cannot be reached, does not hold the block or returns
+// content that does not match blockHash, and then to its previous replicas. It succeeds
+// once ReadQuorum replicas returned the block.
+func (p *BlockPlacement) GetBlock(client ClientInterface, blockHash string, block *Block) error {
+	replicas := make([]string, 0)
+	suspect := make([]string, 0)
+	for _, server := range p.Replicas(blockHash) {
+		if p.Suspect[server] {
+			suspect = append(suspect, server)
+		} else {
+			replicas = append(replicas, server)
+		}
+	}
+	replicas = append(replicas, suspect...)
+	if p.Previous != nil {
+		replicas = append(replicas, addedServers(replicas, p.Previous.Replicas(blockHash))...)
+	}
+
+	read := 0
+	var lastErr error
+	for _, server := range replicas {
+		replicaBlock := &Block{}
+		if err := client.GetBlock(blockHash, server, replicaBlock); err != nil {
+			log.Printf("Get block %s from %s: %v", blockHash, server, err)
+			lastErr = fmt.Errorf("%s: %w", server, err)
+			continue
+		}
+		if GetBlockHashString(replicaBlock.GetBlockData()) != blockHash {
+			log.Printf("Get block %s from %s: content does not match hash", blockHash, server)
+			lastErr = fmt.Errorf("%s: content does not match hash", server)
+			continue
+		}
+
+		if read == 0 {
+			block.BlockData = replicaBlock.GetBlockData()
+			block.BlockSize = replicaBlock.GetBlockSize()
+		}
+		read++
+		if read >= p.ReadQuorum {
+			return nil
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no replicas")
+	}
+	return fmt.Errorf("%w: %d of %d replicas returned the block, %d needed: %v",
+		ErrQuorumNotReached, read, len(replicas), p.ReadQuorum, lastErr)
+}
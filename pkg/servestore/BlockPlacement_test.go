@@ -0,0 +1,88 @@
+package servestore
+
+import (
+	"errors"
+	"testing"
+)
+
+func testPlacement(replicationFactor, writeQuorum, readQuorum int) *BlockPlacement {
+	return NewBlockPlacement(&ClusterConfig{
+		BlockStoreAddrs:   []string{"a:1", "b:1", "c:1", "d:1", "e:1"},
+		VirtualNodes:      10,
+		ReplicationFactor: replicationFactor,
+		WriteQuorum:       writeQuorum,
+		ReadQuorum:        readQuorum,
+	})
+}
+
+func TestPutBlockWritesEveryReplica(t *testing.T) {
+	stores := newFakeCluster()
+	placement := testPlacement(3, 2, 1)
+
+	block := &Block{BlockData: []byte("data"), BlockSize: 4}
+	hash := GetBlockHashString(block.BlockData)
+	written, err := placement.PutBlock(stores, block, hash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 3 || stores.holders(hash) != 3 {
+		t.Errorf("block written to %d replicas, held by %d, want 3", written, stores.holders(hash))
+	}
+
+	holders := placement.HasBlocks(stores, []string{hash})
+	if len(holders[hash]) != 3 {
+		t.Errorf("HasBlocks found %d replicas, want 3", len(holders[hash]))
+	}
+	if written, err := placement.PutBlock(stores, block, hash, holders[hash]); err != nil || written != 0 {
+		t.Errorf("rewriting a fully replicated block wrote %d replicas: %v", written, err)
+	}
+}
+
+func TestPutBlockQuorum(t *testing.T) {
+	block := &Block{BlockData: []byte("data"), BlockSize: 4}
+	hash := GetBlockHashString(block.BlockData)
+
+	stores := newFakeCluster()
+	placement := testPlacement(3, 2, 1)
+	replicas := placement.Replicas(hash)
+
+	stores.down[replicas[0]] = true
+	if _, err := placement.PutBlock(stores, block, hash, nil); err != nil {
+		t.Errorf("write with 2 of 3 replicas up: %v", err)
+	}
+
+	stores = newFakeCluster()
+	stores.down[replicas[0]], stores.down[replicas[2]] = true, true
+	if _, err := placement.PutBlock(stores, block, hash, nil); !errors.Is(err, ErrQuorumNotReached) {
+		t.Errorf("write with 1 of 3 replicas up: got %v, want ErrQuorumNotReached", err)
+	}
+}
+
+func TestGetBlockFailsOver(t *testing.T) {
+	block := &Block{BlockData: []byte("data"), BlockSize: 4}
+	hash := GetBlockHashString(block.BlockData)
+
+	stores := newFakeCluster()
+	placement := testPlacement(3, 3, 1)
+	if _, err := placement.PutBlock(stores, block, hash, nil); err != nil {
+		t.Fatal(err)
+	}
+	replicas := placement.Replicas(hash)
+
+	// The primary is down and the second replica holds corrupt data
+	stores.down[replicas[0]] = true
+	stores.blocks[replicas[1]][hash] = &Block{BlockData: []byte("corrupt"), BlockSize: 7}
+
+	got := &Block{}
+	if err := placement.GetBlock(stores, hash, got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got.BlockData) != "data" {
+		t.Errorf("read %q, want %q", got.BlockData, "data")
+	}
+
+	quorum := testPlacement(3, 3, 2)
+	if err := quorum.GetBlock(stores, hash, &Block{}); !errors.Is(err, ErrQuorumNotReached) {
+		t.Errorf("read with 1 of 3 good replicas and read quorum 2: got %v, want ErrQuorumNotReached", err)
+	}
+}
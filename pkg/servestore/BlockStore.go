@@ -3,10 +3,13 @@ package servestore
 import (
 	context "context"
 	"errors"
+	"sort"
+	"sync"
 )
 
 type BlockStore struct {
 	BlockMap map[string]*Block
+	mu       sync.RWMutex
 	UnimplementedBlockStoreServer
 }
 
@@ -15,6 +18,8 @@ func (bs *BlockStore) GetBlock(ctx context.Context, blockHash *BlockHash) (*Bloc
 		return nil, errors.New("ErrNilBlockHash")
 	}
 
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
 	if block, exists := bs.BlockMap[blockHash.GetHash()]; exists {
 		return block, nil
 	} else {
@@ -29,6 +34,8 @@ func (bs *BlockStore) PutBlock(ctx context.Context, block *Block) (*Success, err
 		return success, errors.New("ErrNilBlock")
 	}
 
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
 	bs.BlockMap[GetBlockHashString(block.GetBlockData())] = &Block{BlockData: block.GetBlockData(), BlockSize: block.GetBlockSize()}
 	success.Flag = true
 	return success, nil
@@ -41,6 +48,8 @@ func (bs *BlockStore) HasBlocks(ctx context.Context, blockHashesIn *BlockHashes)
 		return nil, errors.New("ErrNilBlockHashes")
 	}
 
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
 	blockHashes := &BlockHashes{Hashes: make([]string, 0)}
 	for _, hash := range blockHashesIn.GetHashes() {
 		if _, exists := bs.BlockMap[hash]; exists {
@@ -51,6 +60,53 @@ func (bs *BlockStore) HasBlocks(ctx context.Context, blockHashesIn *BlockHashes)
 	return blockHashes, nil
 }
 
+// Given a list of hashes, returns the sizes of the blocks among them that are stored
+func (bs *BlockStore) GetBlockSizes(ctx context.Context, blockHashesIn *BlockHashes) (*BlockSizes, error) {
+	if blockHashesIn == nil {
+		return nil, errors.New("ErrNilBlockHashes")
+	}
+
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	blockSizes := &BlockSizes{Sizes: make(map[string]int64)}
+	for _, hash := range blockHashesIn.GetHashes() {
+		if block, exists := bs.BlockMap[hash]; exists {
+			blockSizes.Sizes[hash] = int64(len(block.GetBlockData()))
+		}
+	}
+
+	return blockSizes, nil
+}
+
+// Returns the sorted hashes of the stored blocks in the range. See inHashRange.
+func (bs *BlockStore) ListBlocks(ctx context.Context, blockHashRange *BlockHashRange) (*BlockHashes, error) {
+	if blockHashRange == nil {
+		return nil, errors.New("ErrNilBlockHashRange")
+	}
+
+	bs.mu.RLock()
+	blockHashes := &BlockHashes{Hashes: make([]string, 0)}
+	for hash := range bs.BlockMap {
+		if inHashRange(hash, blockHashRange.GetStart(), blockHashRange.GetEnd()) {
+			blockHashes.Hashes = append(blockHashes.GetHashes(), hash)
+		}
+	}
+	bs.mu.RUnlock()
+
+	sort.Strings(blockHashes.Hashes)
+	return blockHashes, nil
+}
+
+// inHashRange reports whether hash is in the range of the hash ring from start, inclusive,
+// to end, exclusive. A range whose end is not after its start wraps around the ring,
+// so a range from a hash to itself covers the whole ring.
+func inHashRange(hash string, start string, end string) bool {
+	if start < end {
+		return start <= hash && hash < end
+	}
+	return hash >= start || hash < end
+}
+
 // This line guarantees all method for BlockStore are implemented
 var _ BlockStoreInterface = new(BlockStore)
 
@@ -0,0 +1,34 @@
+package servestore
+
+import (
+	"io"
+)
+
+// Chunker cuts the content of files into blocks. Every block but the last of a file holds
+// BlockSize bytes, which is recorded in the file's metadata so that readers can find the
+// block holding an offset.
+type Chunker interface {
+	// BlockSize returns the size of the blocks, and so of the buffer of a block being read
+	BlockSize() int
+
+	// ReadBlock reads the next block of r into buf, which holds BlockSize bytes, returning
+	// io.EOF after the last block
+	ReadBlock(r io.Reader, buf []byte) ([]byte, error)
+}
+
+// fixedSizeChunker cuts files into blocks of the same size
+type fixedSizeChunker struct {
+	blockSize int
+}
+
+func NewFixedSizeChunker(blockSize int) Chunker {
+	return &fixedSizeChunker{blockSize: blockSize}
+}
+
+func (c *fixedSizeChunker) BlockSize() int {
+	return c.blockSize
+}
+
+func (c *fixedSizeChunker) ReadBlock(r io.Reader, buf []byte) ([]byte, error) {
+	return readBlock(r, buf)
+}
@@ -0,0 +1,175 @@
+package servestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrInvalidClusterConfig = errors.New("ErrInvalidClusterConfig")
+
+// ClusterConfig describes the servers of a ServeStore cluster. It is stored as JSON:
+//
+//	{
+//	  "blockStoreAddrs": ["host1:8081", "host2:8081"],
+//	  "virtualNodes": 100,
+//	  "weights": {"host2:8081": 2},
+//	  "replicationFactor": 3,
+//	  "writeQuorum": 2,
+//	  "readQuorum": 1
+//	}
+//
+// virtualNodes is the number of points each unit of weight places on the hash ring,
+// 1 if left out. Servers left out of weights have weight 1.
+//
+// blockStoreAddrs may be left out, in which case any BlockStore that registers with the
+// MetaStore joins the cluster. Otherwise only the listed BlockStores can.
+//
+// Each block is stored on the replicationFactor distinct BlockStores that follow it
+// clockwise on the ring, 1 if left out. A write succeeds once writeQuorum of them hold
+// the block, a majority if left out, and a read once readQuorum of them returned it,
+// 1 if left out.
+type ClusterConfig struct {
+	BlockStoreAddrs   []string       `json:"blockStoreAddrs"`
+	VirtualNodes      int            `json:"virtualNodes,omitempty"`
+	Weights           map[string]int `json:"weights,omitempty"`
+	ReplicationFactor int            `json:"replicationFactor,omitempty"`
+	WriteQuorum       int            `json:"writeQuorum,omitempty"`
+	ReadQuorum        int            `json:"readQuorum,omitempty"`
+}
+
+// NewClusterConfig returns the config of a cluster of unweighted, unreplicated BlockStores
+func NewClusterConfig(blockStoreAddrs []string) *ClusterConfig {
+	return &ClusterConfig{BlockStoreAddrs: blockStoreAddrs}
+}
+
+// LoadClusterConfig reads a cluster config from the JSON file at path
+func LoadClusterConfig(path string) (*ClusterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cluster config: %w", err)
+	}
+
+	config := &ClusterConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidClusterConfig, path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidClusterConfig, path, err)
+	}
+	return config, nil
+}
+
+func (config *ClusterConfig) validate() error {
+	seen := make(map[string]bool)
+	for _, addr := range config.BlockStoreAddrs {
+		if addr == "" {
+			return errors.New("empty BlockStore address")
+		}
+		if seen[addr] {
+			return fmt.Errorf("duplicate BlockStore address %q", addr)
+		}
+		seen[addr] = true
+	}
+	if config.VirtualNodes < 0 {
+		return fmt.Errorf("negative virtualNodes %d", config.VirtualNodes)
+	}
+	for addr, weight := range config.Weights {
+		if !seen[addr] {
+			return fmt.Errorf("weight of unknown BlockStore address %q", addr)
+		}
+		if weight < 1 {
+			return fmt.Errorf("weight of %q is %d, must be at least 1", addr, weight)
+		}
+	}
+	if config.ReplicationFactor < 0 || (len(config.BlockStoreAddrs) > 0 && config.ReplicationFactor > len(config.BlockStoreAddrs)) {
+		return fmt.Errorf("replicationFactor %d, must be between 1 and the number of BlockStores", config.ReplicationFactor)
+	}
+	if config.WriteQuorum < 0 || config.WriteQuorum > config.Replicas() {
+		return fmt.Errorf("writeQuorum %d, must be between 1 and replicationFactor", config.WriteQuorum)
+	}
+	if config.ReadQuorum < 0 || config.ReadQuorum > config.Replicas() {
+		return fmt.Errorf("readQuorum %d, must be between 1 and replicationFactor", config.ReadQuorum)
+	}
+	return nil
+}
+
+// Replicas returns the number of BlockStores each block is stored on
+func (config *ClusterConfig) Replicas() int {
+	if config.ReplicationFactor < 1 {
+		return 1
+	}
+	return config.ReplicationFactor
+}
+
+// WriteQuorumSize returns the number of replicas that have to hold a block for a write to succeed
+func (config *ClusterConfig) WriteQuorumSize() int {
+	if config.WriteQuorum < 1 {
+		return config.Replicas()/2 + 1
+	}
+	return config.WriteQuorum
+}
+
+// ReadQuorumSize returns the number of replicas a block has to be read from for a read to succeed
+func (config *ClusterConfig) ReadQuorumSize() int {
+	if config.ReadQuorum < 1 {
+		return 1
+	}
+	return config.ReadQuorum
+}
+
+// Weight returns the weight of the BlockStore at addr
+func (config *ClusterConfig) Weight(addr string) int {
+	if weight, ok := config.Weights[addr]; ok {
+		return weight
+	}
+	return 1
+}
+
+// HashRing returns the consistent hash ring of the cluster's BlockStores
+func (config *ClusterConfig) HashRing() *ConsistentHashRing {
+	ring := NewWeightedConsistentHashRing(config.VirtualNodes)
+	for _, addr := range config.BlockStoreAddrs {
+		ring.InsertWeightedServer(addr, config.Weight(addr))
+	}
+	return ring
+}
+
+// BlockStoreRing returns the config as sent by the MetaStore to clients
+func (config *ClusterConfig) BlockStoreRing() *BlockStoreRing {
+	weights := make(map[string]int32)
+	for addr, weight := range config.Weights {
+		weights[addr] = int32(weight)
+	}
+	return &BlockStoreRing{
+		Addrs:             config.BlockStoreAddrs,
+		VirtualNodes:      int32(config.VirtualNodes),
+		Weights:           weights,
+		ReplicationFactor: int32(config.Replicas()),
+		WriteQuorum:       int32(config.WriteQuorumSize()),
+		ReadQuorum:        int32(config.ReadQuorumSize()),
+	}
+}
+
+// NewClusterConfigFromRing returns the config sent by a MetaStore
+func NewClusterConfigFromRing(ring *BlockStoreRing) (*ClusterConfig, error) {
+	config := &ClusterConfig{
+		BlockStoreAddrs:   ring.GetAddrs(),
+		VirtualNodes:      int(ring.GetVirtualNodes()),
+		Weights:           make(map[string]int),
+		ReplicationFactor: int(ring.GetReplicationFactor()),
+		WriteQuorum:       int(ring.GetWriteQuorum()),
+		ReadQuorum:        int(ring.GetReadQuorum()),
+	}
+	for addr, weight := range ring.GetWeights() {
+		config.Weights[addr] = int(weight)
+	}
+	if len(config.BlockStoreAddrs) == 0 {
+		return nil, fmt.Errorf("%w: no BlockStore addresses", ErrInvalidClusterConfig)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClusterConfig, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,92 @@
+package servestore
+
+import (
+	"sync"
+)
+
+// byteLimiter bounds the number of bytes of blocks being transferred at once
+type byteLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int64
+	inFlight int64
+}
+
+func newByteLimiter(limit int64) *byteLimiter {
+	limiter := &byteLimiter{limit: limit}
+	limiter.cond = sync.NewCond(&limiter.mu)
+	return limiter
+}
+
+// acquire waits until `n` more bytes can be in flight. A request larger than the limit
+// is let through once nothing else is in flight, so that it cannot wait forever.
+func (l *byteLimiter) acquire(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.inFlight > 0 && l.inFlight+n > l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight += n
+}
+
+func (l *byteLimiter) release(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight -= n
+	l.cond.Broadcast()
+}
+
+// runConcurrently calls `task` for every index in [0, count) using at most `workers`
+// goroutines. Once a task fails no further tasks are started, and the error of the
+// failed task with the lowest index is returned, so the result does not depend on
+// the order in which the tasks ran.
+func runConcurrently(count int, workers int, task func(i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > count {
+		workers = count
+	}
+
+	errs := make([]error, count)
+	indexes := make(chan int)
+	var failed bool
+	var mu sync.Mutex
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := task(i); err != nil {
+					errs[i] = err
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
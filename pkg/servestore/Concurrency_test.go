@@ -0,0 +1,100 @@
+package servestore
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestByteLimiterBoundsBytesInFlight(t *testing.T) {
+	limiter := newByteLimiter(10)
+	limiter.acquire(6)
+
+	acquired := make(chan int64)
+	go func() {
+		limiter.acquire(6)
+		acquired <- 6
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired 12 bytes with a limit of 10")
+	case <-time.After(20 * time.Millisecond):
+	}
+	limiter.acquire(4) // Fits alongside the first 6 bytes
+	limiter.release(4)
+
+	limiter.release(6)
+	<-acquired
+	limiter.release(6)
+
+	// A request larger than the limit goes through once nothing else is in flight
+	go func() {
+		limiter.acquire(25)
+		acquired <- 25
+	}()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("request larger than the limit never went through")
+	}
+	limiter.release(25)
+	if limiter.inFlight != 0 {
+		t.Errorf("%d bytes left in flight", limiter.inFlight)
+	}
+}
+
+func TestRunConcurrentlyReturnsFirstError(t *testing.T) {
+	errFailed := errors.New("failed")
+	var ran int32
+	err := runConcurrently(10, 3, func(i int) error {
+		atomic.AddInt32(&ran, 1)
+		if i == 2 {
+			return errFailed
+		}
+		return nil
+	})
+	if err != errFailed || atomic.LoadInt32(&ran) > 10 {
+		t.Errorf("runConcurrently returned %v after %d tasks", err, ran)
+	}
+}
+
+// slowBlockCluster records how many blocks are downloaded at once
+type slowBlockCluster struct {
+	*fakeCluster
+	inFlight    int32
+	maxInFlight int32
+}
+
+func (c *slowBlockCluster) GetBlock(blockHash string, blockStoreAddr string, block *Block) error {
+	inFlight := atomic.AddInt32(&c.inFlight, 1)
+	defer atomic.AddInt32(&c.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&c.maxInFlight)
+		if inFlight <= max || atomic.CompareAndSwapInt32(&c.maxInFlight, max, inFlight) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return c.fakeCluster.GetBlock(blockHash, blockStoreAddr, block)
+}
+
+func TestDownloadBufferCountsRemoteBlockSize(t *testing.T) {
+	cluster := &slowBlockCluster{fakeCluster: newFakeCluster()}
+	content := make([]byte, 64*8)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	cluster.putFile("big.bin", string(content), 64, true)
+
+	// The buffer holds a single block of the remote file, though many of the local block size
+	baseDir := t.TempDir()
+	syncer := NewSyncer(cluster, baseDir, SyncOptions{Chunker: NewFixedSizeChunker(4), Concurrency: 4, BufferSize: 64})
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if max := atomic.LoadInt32(&cluster.maxInFlight); max != 1 {
+		t.Errorf("%d blocks of 64 bytes downloaded at once with a 64 byte buffer", max)
+	}
+	assertTestFiles(t, baseDir, map[string]string{"big.bin": string(content)})
+}
@@ -3,24 +3,140 @@ package servestore
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"sort"
 	"strconv"
 )
 
+// ConsistentHashRing maps block hashes to the servers responsible for them. Each server
+// has VirtualNodes points on the ring for every unit of its weight, so that blocks are spread
+// more evenly and servers with more capacity get more of them. ServerMap maps the hash of
+// each point to the server's name, and Weights maps each server's name to its weight.
 type ConsistentHashRing struct {
-	ServerMap map[string]string
+	ServerMap    map[string]string
+	Weights      map[string]int
+	VirtualNodes int
 }
 
+// InsertServer adds a server with weight 1 to the ring
 func (c ConsistentHashRing) InsertServer(addr string) {
-	panic("to do")
+	c.InsertWeightedServer(addr, 1)
+}
+
+// InsertWeightedServer adds a server to the ring with the given weight, which is at least 1
+func (c ConsistentHashRing) InsertWeightedServer(addr string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	c.DeleteServer(addr)
+
+	c.Weights[addr] = weight
+	for i := 0; i < c.pointCount(weight); i++ {
+		c.ServerMap[c.Hash(virtualNodeName(addr, i))] = addr
+	}
 }
 
 func (c ConsistentHashRing) DeleteServer(addr string) {
-	panic("to do")
+	weight, ok := c.Weights[addr]
+	if !ok {
+		return
+	}
+
+	delete(c.Weights, addr)
+	for i := 0; i < c.pointCount(weight); i++ {
+		delete(c.ServerMap, c.Hash(virtualNodeName(addr, i)))
+	}
+}
+
+// Servers returns the names of the servers on the ring, sorted
+func (c ConsistentHashRing) Servers() []string {
+	servers := make([]string, 0, len(c.Weights))
+	for server := range c.Weights {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	return servers
 }
 
+// pointCount returns the number of points on the ring of a server with the given weight
+func (c ConsistentHashRing) pointCount(weight int) int {
+	if c.VirtualNodes < 1 {
+		return weight
+	}
+	return c.VirtualNodes * weight
+}
+
+// virtualNodeName returns the name hashed to place a server's i-th point on the ring.
+// The first point is placed by the server's own name, so that a ring with one virtual node
+// per server places servers the same way as a ring without virtual nodes.
+func virtualNodeName(addr string, i int) string {
+	if i == 0 {
+		return addr
+	}
+	return addr + VIRTUAL_NODE_DELIMITER + strconv.Itoa(i)
+}
+
+// GetResponsibleServer returns the server of the first point clockwise from blockId on
+// the ring, or "" if the ring has no servers
 func (c ConsistentHashRing) GetResponsibleServer(blockId string) string {
-	// Find the next largest key from ServerMap
-	panic("to do")
+	return c.responsibleServer(c.sortedServerHashes(), blockId)
+}
+
+// GetReplicaServers returns the first n distinct servers clockwise from blockId on the ring,
+// or all of them if the ring has fewer than n servers. The first is the responsible server.
+func (c ConsistentHashRing) GetReplicaServers(blockId string, n int) []string {
+	return c.replicaServers(c.sortedServerHashes(), blockId, n)
+}
+
+// replicaServers finds the first n distinct servers from blockId in the sorted keys of ServerMap
+func (c ConsistentHashRing) replicaServers(serverHashes []string, blockId string, n int) []string {
+	start := sort.Search(len(serverHashes), func(i int) bool {
+		return serverHashes[i] > blockId
+	})
+
+	replicas := make([]string, 0, n)
+	seen := make(map[string]bool)
+	for i := 0; i < len(serverHashes) && len(replicas) < n; i++ {
+		server := c.ServerMap[serverHashes[(start+i)%len(serverHashes)]]
+		if !seen[server] {
+			seen[server] = true
+			replicas = append(replicas, server)
+		}
+	}
+	return replicas
+}
+
+// GetResponsibleServers returns the responsible server of each block in blockHashes
+func (c ConsistentHashRing) GetResponsibleServers(blockHashes []string) []string {
+	serverHashes := c.sortedServerHashes()
+	servers := make([]string, len(blockHashes))
+	for i, blockHash := range blockHashes {
+		servers[i] = c.responsibleServer(serverHashes, blockHash)
+	}
+	return servers
+}
+
+// responsibleServer finds the next largest key from ServerMap in its sorted keys
+func (c ConsistentHashRing) responsibleServer(serverHashes []string, blockId string) string {
+	if len(serverHashes) == 0 {
+		return ""
+	}
+
+	i := sort.Search(len(serverHashes), func(i int) bool {
+		return serverHashes[i] > blockId
+	})
+	if i == len(serverHashes) {
+		i = 0
+	}
+	return c.ServerMap[serverHashes[i]]
+}
+
+func (c ConsistentHashRing) sortedServerHashes() []string {
+	serverHashes := make([]string, 0, len(c.ServerMap))
+	for serverHash := range c.ServerMap {
+		serverHashes = append(serverHashes, serverHash)
+	}
+	sort.Strings(serverHashes)
+	return serverHashes
 }
 
 func (c ConsistentHashRing) Hash(addr string) string {
@@ -31,25 +147,54 @@ func (c ConsistentHashRing) Hash(addr string) string {
 }
 
 func (c ConsistentHashRing) OutputMap(blockHashes []string) map[string]string {
+	serverHashes := c.sortedServerHashes()
 	res := make(map[string]string)
 	for i := 0; i < len(blockHashes); i++ {
-		res["block"+strconv.Itoa(i)] = c.GetResponsibleServer(blockHashes[i])
+		res["block"+strconv.Itoa(i)] = c.responsibleServer(serverHashes, blockHashes[i])
 	}
 	return res
 }
 
+// BlockServerName returns the name of the numbered server on a ring
+// built by NewConsistentHashRing
+func BlockServerName(id int) string {
+	return BLOCK_SERVER_PREFIX + strconv.Itoa(id)
+}
+
+// NewConsistentHashRing returns a ring of numbered servers, named by BlockServerName,
+// without the servers listed in downServer
 func NewConsistentHashRing(numServers int, downServer []int) *ConsistentHashRing {
-	c := &ConsistentHashRing{
-		ServerMap: make(map[string]string),
-	}
+	c := NewWeightedConsistentHashRing(1)
 
 	for i := 0; i < numServers; i++ {
-		c.InsertServer("blockServer" + strconv.Itoa(i))
+		c.InsertServer(BlockServerName(i))
 	}
 
 	for i := 0; i < len(downServer); i++ {
-		c.DeleteServer("blockServer" + strconv.Itoa(downServer[i]))
+		c.DeleteServer(BlockServerName(downServer[i]))
 	}
 
 	return c
 }
+
+// NewConsistentHashRingFromAddrs returns a ring of the servers at blockStoreAddrs,
+// placed on the ring by the hash of their address
+func NewConsistentHashRingFromAddrs(blockStoreAddrs []string) *ConsistentHashRing {
+	c := NewWeightedConsistentHashRing(1)
+
+	for _, addr := range blockStoreAddrs {
+		c.InsertServer(addr)
+	}
+
+	return c
+}
+
+// NewWeightedConsistentHashRing returns an empty ring that places virtualNodes points
+// for every unit of a server's weight
+func NewWeightedConsistentHashRing(virtualNodes int) *ConsistentHashRing {
+	return &ConsistentHashRing{
+		ServerMap:    make(map[string]string),
+		Weights:      make(map[string]int),
+		VirtualNodes: virtualNodes,
+	}
+}
@@ -0,0 +1,135 @@
+package servestore
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func testBlockHashes(n int) []string {
+	blockHashes := make([]string, n)
+	for i := range blockHashes {
+		blockHashes[i] = GetBlockHashString([]byte("block" + strconv.Itoa(i)))
+	}
+	return blockHashes
+}
+
+// blockShares returns the share of blockHashes mapped to each server
+func blockShares(ring *ConsistentHashRing, blockHashes []string) map[string]float64 {
+	shares := make(map[string]float64)
+	for _, server := range ring.GetResponsibleServers(blockHashes) {
+		shares[server] += 1 / float64(len(blockHashes))
+	}
+	return shares
+}
+
+func TestEmptyRing(t *testing.T) {
+	ring := NewWeightedConsistentHashRing(10)
+	if server := ring.GetResponsibleServer(GetBlockHashString([]byte("block"))); server != "" {
+		t.Errorf("empty ring maps block to %q", server)
+	}
+}
+
+func TestSingleVirtualNodePlacesServerByName(t *testing.T) {
+	ring := NewConsistentHashRing(4, []int{2})
+	if len(ring.ServerMap) != 3 {
+		t.Fatalf("%d points on the ring, want 3", len(ring.ServerMap))
+	}
+	for _, id := range []int{0, 1, 3} {
+		name := BlockServerName(id)
+		if ring.ServerMap[ring.Hash(name)] != name {
+			t.Errorf("%s is not placed at the hash of its name", name)
+		}
+	}
+}
+
+func TestVirtualNodesSpreadBlocks(t *testing.T) {
+	blockHashes := testBlockHashes(20000)
+
+	deviation := func(virtualNodes int) float64 {
+		ring := NewWeightedConsistentHashRing(virtualNodes)
+		for i := 0; i < 8; i++ {
+			ring.InsertServer(BlockServerName(i))
+		}
+		if len(ring.ServerMap) != 8*virtualNodes {
+			t.Fatalf("%d points on the ring, want %d", len(ring.ServerMap), 8*virtualNodes)
+		}
+
+		sumSquares := 0.0
+		for _, share := range blockShares(ring, blockHashes) {
+			sumSquares += (share - 1.0/8) * (share - 1.0/8)
+		}
+		return math.Sqrt(sumSquares / 8)
+	}
+
+	single, virtual := deviation(1), deviation(200)
+	if virtual >= single {
+		t.Errorf("deviation with 200 virtual nodes %.4f, not below %.4f with 1", virtual, single)
+	}
+	if virtual > 0.02 {
+		t.Errorf("deviation with 200 virtual nodes %.4f, want at most 0.02", virtual)
+	}
+}
+
+func TestWeightsSplitBlocks(t *testing.T) {
+	ring := NewWeightedConsistentHashRing(200)
+	ring.InsertWeightedServer("small", 1)
+	ring.InsertWeightedServer("large", 3)
+
+	shares := blockShares(ring, testBlockHashes(20000))
+	if math.Abs(shares["large"]-0.75) > 0.05 {
+		t.Errorf("server with 3/4 of the weight holds %.3f of the blocks", shares["large"])
+	}
+}
+
+func TestDeleteServerMovesOnlyItsBlocks(t *testing.T) {
+	blockHashes := testBlockHashes(5000)
+	ring := NewWeightedConsistentHashRing(50)
+	for i := 0; i < 5; i++ {
+		ring.InsertWeightedServer(BlockServerName(i), i+1)
+	}
+	before := ring.GetResponsibleServers(blockHashes)
+
+	ring.DeleteServer(BlockServerName(2))
+	after := ring.GetResponsibleServers(blockHashes)
+	for i := range before {
+		if before[i] != after[i] && before[i] != BlockServerName(2) {
+			t.Fatalf("block %s moved from %s to %s", blockHashes[i], before[i], after[i])
+		}
+		if after[i] == BlockServerName(2) {
+			t.Fatalf("block %s still maps to the deleted server", blockHashes[i])
+		}
+	}
+
+	ring.InsertWeightedServer(BlockServerName(2), 3)
+	restored := ring.GetResponsibleServers(blockHashes)
+	for i := range before {
+		if before[i] != restored[i] {
+			t.Fatalf("block %s maps to %s after reinserting, was %s", blockHashes[i], restored[i], before[i])
+		}
+	}
+}
+
+func TestReplicaServersAreDistinct(t *testing.T) {
+	ring := NewWeightedConsistentHashRing(50)
+	for i := 0; i < 5; i++ {
+		ring.InsertServer(BlockServerName(i))
+	}
+
+	for _, blockHash := range testBlockHashes(1000) {
+		replicas := ring.GetReplicaServers(blockHash, 3)
+		if len(replicas) != 3 {
+			t.Fatalf("block %s has %d replicas, want 3", blockHash, len(replicas))
+		}
+		if replicas[0] != ring.GetResponsibleServer(blockHash) {
+			t.Fatalf("block %s: first replica %s is not the responsible server", blockHash, replicas[0])
+		}
+		if replicas[0] == replicas[1] || replicas[1] == replicas[2] || replicas[0] == replicas[2] {
+			t.Fatalf("block %s has repeated replicas %v", blockHash, replicas)
+		}
+	}
+
+	if replicas := ring.GetReplicaServers(testBlockHashes(1)[0], 10); len(replicas) != 5 {
+		t.Errorf("%d replicas on a ring of 5 servers", len(replicas))
+	}
+}
@@ -0,0 +1,119 @@
+package servestore
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// POSIX permission bits stored in FileMetaData.Mode
+const MODE_SETUID uint32 = 04000
+const MODE_SETGID uint32 = 02000
+const MODE_STICKY uint32 = 01000
+
+// fileModeToPosix converts the permission bits of a Go FileMode to POSIX mode bits
+func fileModeToPosix(mode fs.FileMode) uint32 {
+	posixMode := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		posixMode |= MODE_SETUID
+	}
+	if mode&fs.ModeSetgid != 0 {
+		posixMode |= MODE_SETGID
+	}
+	if mode&fs.ModeSticky != 0 {
+		posixMode |= MODE_STICKY
+	}
+	return posixMode
+}
+
+// posixToFileMode converts POSIX mode bits to the permission bits of a Go FileMode
+func posixToFileMode(posixMode uint32) fs.FileMode {
+	mode := fs.FileMode(posixMode) & fs.ModePerm
+	if posixMode&MODE_SETUID != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if posixMode&MODE_SETGID != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if posixMode&MODE_STICKY != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
+
+// readFileAttributes sets the mode, modification time and, if `withXattrs` is set, the
+// extended attributes of the file at path in fileMetaData
+func readFileAttributes(path string, info fs.FileInfo, withXattrs bool, fileMetaData *FileMetaData) error {
+	fileMetaData.Mode = fileModeToPosix(info.Mode())
+	fileMetaData.Mtime = info.ModTime().UnixNano()
+
+	if withXattrs {
+		xattrs, err := getXattrs(path)
+		if err != nil {
+			return fmt.Errorf("read extended attributes of %s: %w", path, err)
+		}
+		fileMetaData.Xattrs = xattrs
+	}
+
+	return nil
+}
+
+// applyFileAttributes sets the mode, modification time and, if `withXattrs` is set, the
+// extended attributes recorded in fileMetaData on the file at path. Attributes that were
+// never recorded (e.g. by an older client) are left alone.
+func applyFileAttributes(path string, fileMetaData *FileMetaData, withXattrs bool) error {
+	if fileMetaData.GetMode() != 0 {
+		if err := os.Chmod(path, posixToFileMode(fileMetaData.GetMode())); err != nil {
+			return fmt.Errorf("chmod %s: %w", path, err)
+		}
+	}
+
+	if withXattrs {
+		if err := setXattrs(path, fileMetaData.GetXattrs()); err != nil {
+			return fmt.Errorf("write extended attributes of %s: %w", path, err)
+		}
+	}
+
+	// Set the modification time last, since changing the file updates it
+	if fileMetaData.GetMtime() != 0 {
+		mtime := time.Unix(0, fileMetaData.GetMtime())
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			return fmt.Errorf("chtimes %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// hasFileAttributes reports whether the file metadata records a mode and modification time
+func hasFileAttributes(fileMetaData *FileMetaData) bool {
+	return fileMetaData.GetMode() != 0 || fileMetaData.GetMtime() != 0
+}
+
+// attributesChanged reports whether the mode, modification time, extended attributes or
+// hard link target of a scanned local file differ from its local index entry
+func attributesChanged(local *FileMetaData, base *FileMetaData) bool {
+	// Index entries written before attributes were synced have nothing to compare with
+	if !hasFileAttributes(base) {
+		return false
+	}
+
+	return local.GetMode() != base.GetMode() ||
+		local.GetMtime() != base.GetMtime() ||
+		!equalXattrs(local.GetXattrs(), base.GetXattrs()) ||
+		local.GetHardlinkTarget() != base.GetHardlinkTarget()
+}
+
+func equalXattrs(a map[string][]byte, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if otherValue, exists := b[name]; !exists || !bytes.Equal(value, otherValue) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package servestore
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPosixModeConversion(t *testing.T) {
+	for _, mode := range []fs.FileMode{0644, 0755 | fs.ModeSetuid, 0750 | fs.ModeSetgid, 0777 | fs.ModeSticky} {
+		if converted := posixToFileMode(fileModeToPosix(mode)); converted != mode {
+			t.Errorf("%v converted back to %v", mode, converted)
+		}
+	}
+	if posix := fileModeToPosix(0755 | fs.ModeSetuid | fs.ModeDir); posix != 04755 {
+		t.Errorf("setuid directory converted to %o", posix)
+	}
+}
+
+// statAttributes reads the attributes of the file at path into new file metadata
+func statAttributes(t *testing.T, path string, withXattrs bool) *FileMetaData {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMetaData := &FileMetaData{}
+	if err := readFileAttributes(path, info, withXattrs, fileMetaData); err != nil {
+		t.Fatal(err)
+	}
+	return fileMetaData
+}
+
+func TestFileAttributesRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"src": "content", "dst": "content"})
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	mtime := time.Date(2026, 1, 2, 3, 4, 5, 600, time.UTC)
+	if err := os.Chmod(src, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	scanned := statAttributes(t, src, false)
+	if scanned.GetMode() != 0750 || scanned.GetMtime() != mtime.UnixNano() {
+		t.Fatalf("scanned mode %o, mtime %d", scanned.GetMode(), scanned.GetMtime())
+	}
+	if err := applyFileAttributes(dst, scanned, false); err != nil {
+		t.Fatal(err)
+	}
+	applied := statAttributes(t, dst, false)
+	if applied.GetMode() != 0750 || applied.GetMtime() != scanned.GetMtime() || attributesChanged(applied, scanned) {
+		t.Errorf("applied mode %o, mtime %d", applied.GetMode(), applied.GetMtime())
+	}
+
+	// Either attribute changing is detected, unless the index entry never recorded any
+	if err := os.Chmod(dst, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if !attributesChanged(statAttributes(t, dst, false), scanned) {
+		t.Error("mode change not detected")
+	}
+	if err := applyFileAttributes(dst, scanned, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dst, mtime, mtime.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if !attributesChanged(statAttributes(t, dst, false), scanned) {
+		t.Error("modification time change not detected")
+	}
+	if attributesChanged(statAttributes(t, dst, false), &FileMetaData{Filename: "dst"}) {
+		t.Error("attributes compared with an index entry without attributes")
+	}
+
+	// Attributes that were never recorded are left alone
+	if err := applyFileAttributes(dst, &FileMetaData{}, false); err != nil {
+		t.Fatal(err)
+	}
+	if mode := statAttributes(t, dst, false).GetMode(); mode != 0750 {
+		t.Errorf("unrecorded mode applied: %o", mode)
+	}
+}
+
+func TestSyncPreservesFileAttributes(t *testing.T) {
+	addr := startSyncCluster(t)
+	srcDir := t.TempDir()
+	writeTestFiles(t, srcDir, map[string]string{"script.sh": "#!/bin/sh\n"})
+	src := filepath.Join(srcDir, "script.sh")
+	mtime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chmod(src, 0751); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ClientSync(NewServeStoreRPCClient(addr, srcDir, 4)); err != nil {
+		t.Fatal(err)
+	}
+
+	dstDir := t.TempDir()
+	if _, err := ClientSync(NewServeStoreRPCClient(addr, dstDir, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if attributes := statAttributes(t, filepath.Join(dstDir, "script.sh"), false); attributes.GetMode() != 0751 || attributes.GetMtime() != mtime.UnixNano() {
+		t.Errorf("downloaded with mode %o, mtime %v", attributes.GetMode(), time.Unix(0, attributes.GetMtime()))
+	}
+
+	// A change to the mode alone is uploaded without the file's blocks
+	if err := os.Chmod(src, 0700); err != nil {
+		t.Fatal(err)
+	}
+	client := NewServeStoreRPCClient(addr, srcDir, 4)
+	report, err := ClientSync(client)
+	if err != nil || len(report.Files) != 1 || report.Files[0].Action != ACTION_UPLOADED || report.Files[0].BytesUploaded != 0 {
+		t.Fatalf("sync of a mode change: %v, %+v", err, report)
+	}
+	remoteIndex := make(map[string]*FileMetaData)
+	if err := client.GetFileInfoMap(&remoteIndex); err != nil {
+		t.Fatal(err)
+	}
+	if mode := remoteIndex["script.sh"].GetMode(); mode != 0700 {
+		t.Errorf("uploaded mode %o", mode)
+	}
+}
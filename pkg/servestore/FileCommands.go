@@ -0,0 +1,361 @@
+package servestore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrRemoteFileNotFound = errors.New("ErrRemoteFileNotFound")
+
+// Blocks whose replicas are asked at once whether they hold them, when uploading a stream
+const UPLOAD_BATCH_BLOCKS int = 16
+
+// ListFiles returns the remote files whose name starts with prefix, sorted by name.
+// Deleted files are left out.
+func ListFiles(client RPCClient, prefix string) ([]*FileMetaData, error) {
+	return listFiles(&client, prefix)
+}
+
+func listFiles(client ClientInterface, prefix string) ([]*FileMetaData, error) {
+	fileInfoMap := make(map[string]*FileMetaData)
+	if err := client.GetFileInfoMap(&fileInfoMap); err != nil {
+		return nil, fmt.Errorf("get remote index: %w", err)
+	}
+
+	var files []*FileMetaData
+	for filename, fileMetaData := range fileInfoMap {
+		if strings.HasPrefix(filename, prefix) && !isTombstone(fileMetaData) {
+			files = append(files, fileMetaData)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].GetFilename() < files[j].GetFilename() })
+	return files, nil
+}
+
+// PushFile uploads the local file at localPath as the next version of the remote file
+// `filename`. Only the blocks that their replicas do not hold yet are uploaded. If another
+// client updates the file meanwhile, nothing is changed and a conflict is reported.
+func PushFile(client RPCClient, localPath string, filename string) (*FileReport, error) {
+	return pushFile(&client, localPath, filename, client.BlockSize, client.Xattrs)
+}
+
+func pushFile(client ClientInterface, localPath string, filename string, blockSize int, withXattrs bool) (*FileReport, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", localPath)
+	}
+
+	base, err := remoteFile(client, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cutting the file as its previous version was cut lets unchanged blocks be found
+	if blockSize <= 0 {
+		blockSize = int(base.GetBlockSize())
+	}
+	if blockSize <= 0 {
+		blockSize = DEFAULT_BLOCK_SIZE
+	}
+
+	placement, err := FetchBlockPlacement(client)
+	if err != nil {
+		return nil, err
+	}
+	fileMetaData, bytesUploaded, err := putReaderBlocks(client, placement, file, blockSize)
+	if err != nil {
+		return nil, fmt.Errorf("push %s: %w", localPath, err)
+	}
+	if err := readFileAttributes(localPath, info, withXattrs, fileMetaData); err != nil {
+		return nil, err
+	}
+
+	fileMetaData.Filename = filename
+	fileMetaData.Version = base.GetVersion() + 1
+	fileReport, err := commitFile(client, fileMetaData, ACTION_UPLOADED)
+	if fileReport != nil {
+		fileReport.BytesUploaded = bytesUploaded
+	}
+	return fileReport, err
+}
+
+// PullFile downloads the remote file `filename` to localPath, or into localPath if it is a
+// directory. The file is written to a temporary file that replaces localPath once every
+// block is checked against its hash.
+func PullFile(client RPCClient, filename string, localPath string) (*FileReport, error) {
+	return pullFile(&client, filename, localPath, client.Xattrs)
+}
+
+func pullFile(client ClientInterface, filename string, localPath string, withXattrs bool) (*FileReport, error) {
+	fileMetaData, err := liveRemoteFile(client, filename)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		localPath = filepath.Join(localPath, path.Base(filename))
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(localPath), TEMP_FILE_PREFIX+filepath.Base(localPath)+"-*")
+	if err != nil {
+		return nil, err
+	}
+	tempPath := file.Name()
+	defer os.Remove(tempPath)
+	defer file.Close()
+	fileReport := &FileReport{Filename: filename, Action: ACTION_DOWNLOADED, Direction: DIRECTION_DOWN, Version: fileMetaData.GetVersion()}
+
+	if isSymlink(fileMetaData) {
+		// The link takes the name of the temporary file, which no other pull can be using
+		file.Close()
+		if err := os.Remove(tempPath); err != nil {
+			return nil, err
+		}
+		if err := os.Symlink(fileMetaData.GetSymlinkTarget(), tempPath); err != nil {
+			return nil, err
+		}
+	} else {
+		fileReport.BytesDownloaded, err = getFileBlocks(client, fileMetaData, file)
+		if err != nil {
+			return nil, fmt.Errorf("pull %s: %w", filename, err)
+		}
+		// Files from clients that do not sync the mode get the mode os.Create would give them
+		if fileMetaData.GetMode() == 0 {
+			if err := file.Chmod(0644); err != nil {
+				return nil, fmt.Errorf("chmod %s: %w", localPath, err)
+			}
+		}
+		if err := file.Sync(); err != nil {
+			return nil, fmt.Errorf("write %s: %w", localPath, err)
+		}
+		if err := file.Close(); err != nil {
+			return nil, fmt.Errorf("write %s: %w", localPath, err)
+		}
+		if err := applyFileAttributes(tempPath, fileMetaData, withXattrs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Rename(tempPath, localPath); err != nil {
+		return nil, err
+	}
+	return fileReport, nil
+}
+
+// CatFile writes the blocks of the remote file `filename` to w in order
+func CatFile(client RPCClient, filename string, w io.Writer) (*FileReport, error) {
+	return catFile(&client, filename, w)
+}
+
+func catFile(client ClientInterface, filename string, w io.Writer) (*FileReport, error) {
+	fileMetaData, err := liveRemoteFile(client, filename)
+	if err != nil {
+		return nil, err
+	}
+	bytesDownloaded, err := getFileBlocks(client, fileMetaData, w)
+	if err != nil {
+		return nil, fmt.Errorf("pull %s: %w", filename, err)
+	}
+	return &FileReport{Filename: filename, Action: ACTION_DOWNLOADED, Direction: DIRECTION_DOWN, Version: fileMetaData.GetVersion(), BytesDownloaded: bytesDownloaded}, nil
+}
+
+// RemoveFile deletes the remote file `filename` by updating it to a tombstone. If another
+// client updates the file meanwhile, it is kept and a conflict is reported.
+func RemoveFile(client RPCClient, filename string) (*FileReport, error) {
+	return removeFile(&client, filename)
+}
+
+func removeFile(client ClientInterface, filename string) (*FileReport, error) {
+	fileMetaData, err := liveRemoteFile(client, filename)
+	if err != nil {
+		return nil, err
+	}
+	tombstone := &FileMetaData{Filename: filename, Version: fileMetaData.GetVersion() + 1, BlockHashList: []string{TOMBSTONE_HASH}}
+	return commitFile(client, tombstone, ACTION_DELETED)
+}
+
+// MoveFile renames the remote file `from` to `to`, replacing `to` if it exists, without
+// uploading any block. Other clients rename the file locally instead of downloading it again.
+// If another client updates either file meanwhile, a conflict is reported.
+func MoveFile(client RPCClient, from string, to string) (*FileReport, error) {
+	return moveFile(&client, from, to)
+}
+
+func moveFile(client ClientInterface, from string, to string) (*FileReport, error) {
+	if from == to {
+		return nil, fmt.Errorf("cannot move %s to itself", from)
+	}
+	fileMetaData, err := liveRemoteFile(client, from)
+	if err != nil {
+		return nil, err
+	}
+	base, err := remoteFile(client, to)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := proto.Clone(fileMetaData).(*FileMetaData)
+	moved.Filename = to
+	moved.Version = base.GetVersion() + 1
+	// Other clients recreate `to` as a copy rather than as a link to the files `from` was linked to
+	moved.HardlinkTarget = ""
+
+	var latestVersion int32
+	err = client.RenameFile(&FileRename{OldFilename: from, OldVersion: fileMetaData.GetVersion(), FileMetaData: moved}, &latestVersion)
+	if status.Code(err) == codes.Unimplemented {
+		return moveFileByUpdates(client, fileMetaData, moved)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rename %s: %w", from, err)
+	}
+	if latestVersion != moved.GetVersion() {
+		log.Println(from, "or", to, "was updated by another client, rename rejected")
+		return &FileReport{Filename: to, Action: ACTION_CONFLICT, Version: moved.GetVersion(), RenamedFrom: from}, nil
+	}
+	return &FileReport{Filename: to, Action: ACTION_RENAMED, Direction: DIRECTION_UP, Version: latestVersion, RenamedFrom: from}, nil
+}
+
+// moveFileByUpdates renames a file on a MetaStore that cannot rename files: the new name is
+// updated to the blocks and attributes of the file, and then the old name is deleted
+func moveFileByUpdates(client ClientInterface, fileMetaData *FileMetaData, moved *FileMetaData) (*FileReport, error) {
+	fileReport, err := commitFile(client, moved, ACTION_RENAMED)
+	if err != nil || fileReport.Action == ACTION_CONFLICT {
+		return fileReport, err
+	}
+	fileReport.RenamedFrom = fileMetaData.GetFilename()
+
+	tombstone := &FileMetaData{Filename: fileMetaData.GetFilename(), Version: fileMetaData.GetVersion() + 1, BlockHashList: []string{TOMBSTONE_HASH}}
+	deleteReport, err := commitFile(client, tombstone, ACTION_DELETED)
+	if err != nil || deleteReport.Action == ACTION_CONFLICT {
+		return deleteReport, err
+	}
+	return fileReport, nil
+}
+
+// remoteFile returns the metadata of the remote file `filename`, or nil if it does not exist
+func remoteFile(client ClientInterface, filename string) (*FileMetaData, error) {
+	fileInfoMap := make(map[string]*FileMetaData)
+	if err := client.GetFileInfoMap(&fileInfoMap); err != nil {
+		return nil, fmt.Errorf("get remote index: %w", err)
+	}
+	return fileInfoMap[filename], nil
+}
+
+// liveRemoteFile returns the metadata of the remote file `filename`, or ErrRemoteFileNotFound
+// if it does not exist or is deleted
+func liveRemoteFile(client ClientInterface, filename string) (*FileMetaData, error) {
+	fileMetaData, err := remoteFile(client, filename)
+	if err != nil {
+		return nil, err
+	}
+	if fileMetaData == nil || isTombstone(fileMetaData) {
+		return nil, fmt.Errorf("%s: %w", filename, ErrRemoteFileNotFound)
+	}
+	return fileMetaData, nil
+}
+
+// commitFile updates the remote metadata of a file, and reports `action`, or a conflict if
+// another client updated the file first
+func commitFile(client ClientInterface, fileMetaData *FileMetaData, action SyncAction) (*FileReport, error) {
+	var latestVersion int32
+	if err := client.UpdateFile(fileMetaData, &latestVersion); err != nil {
+		return nil, fmt.Errorf("update %s: %w", fileMetaData.GetFilename(), err)
+	}
+	if latestVersion != fileMetaData.GetVersion() {
+		log.Println(fileMetaData.GetFilename(), "was updated by another client, version", fileMetaData.GetVersion(), "rejected")
+		return &FileReport{Filename: fileMetaData.GetFilename(), Action: ACTION_CONFLICT, Version: fileMetaData.GetVersion()}, nil
+	}
+	return &FileReport{Filename: fileMetaData.GetFilename(), Action: action, Direction: DIRECTION_UP, Version: latestVersion}, nil
+}
+
+// putReaderBlocks cuts what is read from r into blocks of blockSize bytes and uploads the
+// blocks that are not present on all of their replicas yet, UPLOAD_BATCH_BLOCKS at a time.
+// It returns the metadata of the blocks and the bytes written to all replicas.
+func putReaderBlocks(client ClientInterface, placement *BlockPlacement, r io.Reader, blockSize int) (*FileMetaData, int64, error) {
+	fileMetaData := &FileMetaData{BlockSize: int32(blockSize)}
+	var bytesUploaded int64
+	uploaded := make(map[string]bool)
+	var batch []*Block
+	putBatch := func() error {
+		blockHashes := fileMetaData.BlockHashList[len(fileMetaData.BlockHashList)-len(batch):]
+		holders := placement.HasBlocks(client, blockHashes)
+		for i, block := range batch {
+			blockHash := blockHashes[i]
+			if uploaded[blockHash] || len(holders[blockHash]) >= len(placement.Replicas(blockHash)) {
+				continue
+			}
+			written, err := placement.PutBlock(client, block, blockHash, holders[blockHash])
+			if err != nil {
+				return fmt.Errorf("put block %s: %w", blockHash, err)
+			}
+			uploaded[blockHash] = true
+			bytesUploaded += int64(written) * int64(block.GetBlockSize())
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		blockData, err := readBlock(r, make([]byte, blockSize))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		fileMetaData.BlockHashList = append(fileMetaData.BlockHashList, GetBlockHashString(blockData))
+		fileMetaData.Size += int64(len(blockData))
+		batch = append(batch, &Block{BlockData: blockData, BlockSize: int32(len(blockData))})
+		if len(batch) == UPLOAD_BATCH_BLOCKS {
+			if err := putBatch(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if err := putBatch(); err != nil {
+		return nil, 0, err
+	}
+	return fileMetaData, bytesUploaded, nil
+}
+
+// getFileBlocks writes the blocks of a file to w in order, each checked against its hash,
+// and returns the bytes written
+func getFileBlocks(client ClientInterface, fileMetaData *FileMetaData, w io.Writer) (int64, error) {
+	placement, err := FetchBlockPlacement(client)
+	if err != nil {
+		return 0, err
+	}
+
+	var bytesDownloaded int64
+	for _, blockHash := range fileMetaData.GetBlockHashList() {
+		block := &Block{}
+		if err := placement.GetBlock(client, blockHash, block); err != nil {
+			return bytesDownloaded, fmt.Errorf("get block %s: %w", blockHash, err)
+		}
+		n, err := w.Write(block.GetBlockData())
+		bytesDownloaded += int64(n)
+		if err != nil {
+			return bytesDownloaded, err
+		}
+	}
+	return bytesDownloaded, nil
+}
@@ -0,0 +1,163 @@
+package servestore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPushAndPullFile(t *testing.T) {
+	cluster := newFakeCluster()
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "local.txt")
+	if err := os.WriteFile(localPath, []byte("aaaabbbbcccc"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	fileReport, err := pushFile(cluster, localPath, "docs/remote.txt", 4, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileReport.Action != ACTION_UPLOADED || fileReport.Version != 1 || fileReport.BytesUploaded != 12 {
+		t.Errorf("push new file: %+v", fileReport)
+	}
+	if remote := cluster.files["docs/remote.txt"]; remote.GetSize() != 12 || remote.GetBlockSize() != 4 || len(remote.GetBlockHashList()) != 3 {
+		t.Errorf("pushed metadata: %v", remote)
+	}
+
+	// Unchanged blocks are not uploaded again, and the block size of the remote file is reused
+	if err := os.WriteFile(localPath, []byte("aaaaXXXXcccc"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	fileReport, err = pushFile(cluster, localPath, "docs/remote.txt", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileReport.Action != ACTION_UPLOADED || fileReport.Version != 2 || fileReport.BytesUploaded != 4 {
+		t.Errorf("push changed file: %+v", fileReport)
+	}
+
+	pulledPath := filepath.Join(dir, "pulled.txt")
+	fileReport, err = pullFile(cluster, "docs/remote.txt", pulledPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileReport.Action != ACTION_DOWNLOADED || fileReport.Version != 2 || fileReport.BytesDownloaded != 12 {
+		t.Errorf("pull: %+v", fileReport)
+	}
+	if content, err := os.ReadFile(pulledPath); err != nil || string(content) != "aaaaXXXXcccc" {
+		t.Errorf("pulled content %q, %v", content, err)
+	}
+	if info, err := os.Stat(pulledPath); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("pulled mode: %v, %v", info.Mode(), err)
+	}
+
+	// Pulling into a directory keeps the remote base name
+	if _, err := pullFile(cluster, "docs/remote.txt", dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "remote.txt")); err != nil {
+		t.Errorf("pull into directory: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if _, err := catFile(cluster, "docs/remote.txt", &stdout); err != nil || stdout.String() != "aaaaXXXXcccc" {
+		t.Errorf("cat: %q, %v", stdout.String(), err)
+	}
+
+	// A failed pull leaves no temporary file behind
+	cluster.down["a:1"] = true
+	if _, err := pullFile(cluster, "docs/remote.txt", pulledPath, false); err == nil {
+		t.Errorf("pulled from an unreachable BlockStore")
+	}
+	cluster.down["a:1"] = false
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestPushFileConflict(t *testing.T) {
+	cluster := newFakeCluster()
+	localPath := filepath.Join(t.TempDir(), "local.txt")
+	if err := os.WriteFile(localPath, []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cluster.putFile("a.txt", "remote", 4, true)
+
+	// Another client updates the file between the push reading and updating its version
+	racing := &racingCluster{fakeCluster: cluster}
+	fileReport, err := pushFile(racing, localPath, "a.txt", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileReport.Action != ACTION_CONFLICT || cluster.files["a.txt"].GetVersion() != 2 {
+		t.Errorf("push racing another client: %+v, remote %v", fileReport, cluster.files["a.txt"])
+	}
+}
+
+// racingCluster bumps the version of a file right before it is updated
+type racingCluster struct {
+	*fakeCluster
+}
+
+func (r *racingCluster) UpdateFile(fileMetaData *FileMetaData, latestVersion *int32) error {
+	r.mu.Lock()
+	r.files[fileMetaData.GetFilename()].Version++
+	r.mu.Unlock()
+	return r.fakeCluster.UpdateFile(fileMetaData, latestVersion)
+}
+
+func TestListRemoveAndMoveFiles(t *testing.T) {
+	cluster := newFakeCluster()
+	cluster.putFile("docs/a.txt", "aaaa", 4, true)
+	cluster.putFile("docs/b.txt", "bbbb", 4, true)
+	cluster.putFile("c.txt", "cccc", 4, true)
+	cluster.files["docs/gone.txt"] = &FileMetaData{Filename: "docs/gone.txt", Version: 2, BlockHashList: []string{TOMBSTONE_HASH}}
+
+	files, err := listFiles(cluster, "docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].GetFilename() != "docs/a.txt" || files[1].GetFilename() != "docs/b.txt" {
+		t.Errorf("list docs/: %v", files)
+	}
+
+	fileReport, err := removeFile(cluster, "docs/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileReport.Action != ACTION_DELETED || !isTombstone(cluster.files["docs/b.txt"]) || cluster.files["docs/b.txt"].GetVersion() != 2 {
+		t.Errorf("remove: %+v, remote %v", fileReport, cluster.files["docs/b.txt"])
+	}
+
+	// Moving onto a deleted file continues its versions
+	blocksBefore := len(cluster.blocks["a:1"])
+	fileReport, err = moveFile(cluster, "docs/a.txt", "docs/gone.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := cluster.files["docs/gone.txt"]
+	if fileReport.Action != ACTION_RENAMED || fileReport.RenamedFrom != "docs/a.txt" || moved.GetVersion() != 3 || moved.GetRenamedFrom() != "docs/a.txt" || !equalHashLists(moved.GetBlockHashList(), []string{GetBlockHashString([]byte("aaaa"))}) {
+		t.Errorf("move: %+v, remote %v", fileReport, moved)
+	}
+	if !isTombstone(cluster.files["docs/a.txt"]) || len(cluster.blocks["a:1"]) != blocksBefore {
+		t.Errorf("move left %v and stored %d blocks", cluster.files["docs/a.txt"], len(cluster.blocks["a:1"])-blocksBefore)
+	}
+
+	for _, command := range []func() (*FileReport, error){
+		func() (*FileReport, error) { return removeFile(cluster, "docs/b.txt") },
+		func() (*FileReport, error) { return moveFile(cluster, "missing.txt", "c.txt") },
+		func() (*FileReport, error) { return pullFile(cluster, "docs/a.txt", t.TempDir(), false) },
+	} {
+		if _, err := command(); !errors.Is(err, ErrRemoteFileNotFound) {
+			t.Errorf("command on a missing file: %v, want ErrRemoteFileNotFound", err)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package servestore
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// getFileCtime returns the time of the file's last status change in nanoseconds
+func getFileCtime(info fs.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return stat.Ctim.Nano()
+}
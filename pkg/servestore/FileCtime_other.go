@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package servestore
+
+import (
+	"io/fs"
+)
+
+// The status change time is only used on Linux, elsewhere the stat cache relies on
+// the size, modification time and inode of a file
+
+func getFileCtime(info fs.FileInfo) int64 {
+	return 0
+}
@@ -0,0 +1,14 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package servestore
+
+import (
+	"io/fs"
+)
+
+// Hard links are only detected on Unix systems
+
+func getFileID(info fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
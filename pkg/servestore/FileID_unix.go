@@ -0,0 +1,18 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package servestore
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// getFileID returns the device and inode of a file and whether it has more than one hard link
+func getFileID(info fs.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, stat.Nlink > 1
+}
@@ -0,0 +1,146 @@
+package servestore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrSymlinkedParent = errors.New("ErrSymlinkedParent")
+
+// LinkPolicy decides how symbolic links in a client's base directory are synced
+type LinkPolicy string
+
+const (
+	// Symbolic links are synced as links: the link target is recorded in FileMetaData.SymlinkTarget
+	// and the link is recreated on download, wherever it points to
+	LINK_PRESERVE LinkPolicy = "preserve"
+	// Symbolic links to regular files are synced as the file they point to. Links to directories are skipped.
+	LINK_FOLLOW LinkPolicy = "follow"
+	// Symbolic links are neither uploaded nor downloaded
+	LINK_SKIP LinkPolicy = "skip"
+)
+
+func ParseLinkPolicy(policy string) (LinkPolicy, error) {
+	switch LinkPolicy(policy) {
+	case LINK_PRESERVE, LINK_FOLLOW, LINK_SKIP:
+		return LinkPolicy(policy), nil
+	case "":
+		return LINK_PRESERVE, nil
+	}
+	return "", fmt.Errorf("unknown link policy %q", policy)
+}
+
+// isSymlink reports whether the file metadata describes a symbolic link
+func isSymlink(fileMetaData *FileMetaData) bool {
+	return fileMetaData.GetSymlinkTarget() != ""
+}
+
+// fileID identifies a file on disk, so that hard links to it can be recognised
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// readSymlinkBlocks returns the single block of a symbolic link, which holds its target.
+// Clients that do not know about links recreate the link as a small file holding its target.
+func readSymlinkBlocks(target string) ([]*Block, []string) {
+	blockData := []byte(target)
+	block := &Block{BlockData: blockData, BlockSize: int32(len(blockData))}
+	return []*Block{block}, []string{GetBlockHashString(blockData)}
+}
+
+// updateLocalSymlink replaces the local file with a symbolic link to `target`
+func updateLocalSymlink(directory string, filename string, target string) error {
+	path := ConcatPath(directory, filename)
+	log.Println("Linking", path, "to", target)
+
+	if err := checkLocalParents(directory, filename); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create directory for %s: %w", filename, err)
+	}
+	if err := removeLocalFile(path); err != nil {
+		return err
+	}
+	if err := os.Symlink(target, path); err != nil {
+		return fmt.Errorf("symlink %s: %w", filename, err)
+	}
+	return nil
+}
+
+// updateLocalHardlink replaces the local file with a hard link to the local file `target`,
+// returning false if the target, whose synced metadata is `targetFileMetaData` (nil if it is
+// not synced yet), does not hold the same content, in which case the file has to be
+// downloaded instead
+func updateLocalHardlink(directory string, filename string, target string, targetFileMetaData *FileMetaData, blockHashList []string) (bool, error) {
+	if targetFileMetaData == nil || isSymlink(targetFileMetaData) || !equalHashLists(targetFileMetaData.GetBlockHashList(), blockHashList) {
+		return false, nil
+	}
+
+	path := ConcatPath(directory, filename)
+	log.Println("Linking", path, "to", target)
+
+	for _, linked := range []string{filename, target} {
+		if err := checkLocalParents(directory, linked); err != nil {
+			return false, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("create directory for %s: %w", filename, err)
+	}
+	if err := removeLocalFile(path); err != nil {
+		return false, err
+	}
+	if err := os.Link(ConcatPath(directory, target), path); err != nil {
+		return false, fmt.Errorf("link %s: %w", filename, err)
+	}
+	return true, nil
+}
+
+// removeLocalFile removes the file at path if present. A symbolic link is removed
+// itself, so that writing to the path never writes through the link.
+func removeLocalFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("remove %s: %w", path, fs.ErrExist)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkLocalParents returns an error wrapping ErrSymlinkedParent if a parent directory of
+// filename in directory is a symbolic link, so that writing, renaming or removing the file
+// never reaches outside the base directory through a link synced from another client.
+// Missing parents are left to be created as directories.
+func checkLocalParents(directory string, filename string) error {
+	parent := directory
+	components := strings.Split(filename, "/")
+	for _, component := range components[:len(components)-1] {
+		parent = ConcatPath(parent, component)
+		info, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", parent, err)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%s: %w", parent, ErrSymlinkedParent)
+		}
+	}
+	return nil
+}
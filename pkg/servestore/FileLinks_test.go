@@ -0,0 +1,164 @@
+package servestore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncIgnoresRemoteFilesOutsideBaseDir(t *testing.T) {
+	cluster := newFakeCluster()
+	for _, filename := range []string{"../escaped.txt", "/absolute.txt", "docs/../dotdot.txt", "./dot.txt", "docs//double.txt"} {
+		cluster.putFile(filename, "escaped", 4, true)
+	}
+	cluster.putFile("docs/linked.txt", "escaped", 4, true).HardlinkTarget = "../escaped.txt"
+	cluster.putFile("docs/a.txt", "aaaa", 4, true)
+
+	baseDir := filepath.Join(t.TempDir(), "base")
+	if err := os.Mkdir(baseDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	report, err := NewSyncer(cluster, baseDir, SyncOptions{Chunker: NewFixedSizeChunker(4)}).Sync()
+	if err != nil || len(report.Files) != 1 || report.Count(ACTION_DOWNLOADED) != 1 {
+		t.Fatalf("sync: %v, %+v", err, report)
+	}
+	assertTestFiles(t, baseDir, map[string]string{"docs/a.txt": "aaaa"})
+	if _, err := os.Stat(filepath.Join(filepath.Dir(baseDir), "escaped.txt")); !os.IsNotExist(err) {
+		t.Errorf("escaped.txt written outside the base directory: %v", err)
+	}
+}
+
+func TestSyncNeverWritesThroughSymlinkedParents(t *testing.T) {
+	cluster := newFakeCluster()
+	outside := t.TempDir()
+	writeTestFiles(t, outside, map[string]string{"victim.txt": "victim"})
+	link := cluster.putFile("link", outside, 4096, true)
+	link.SymlinkTarget, link.Mtime = outside, 0
+
+	baseDir := t.TempDir()
+	syncer := NewSyncer(cluster, baseDir, SyncOptions{Chunker: NewFixedSizeChunker(4)})
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(filepath.Join(baseDir, "link")); err != nil || target != outside {
+		t.Fatalf("link points to %q, %v", target, err)
+	}
+
+	// Files under the link, written or renamed, fail to sync instead of landing outside
+	cluster.putFile("link/new.txt", "new", 4, true)
+	cluster.putFile("dir/renamed.txt", "aaaa", 4, true)
+	report, err := syncer.Sync()
+	if !errors.Is(err, ErrSyncIncomplete) || report.Count(ACTION_DOWNLOADED) != 1 {
+		t.Fatalf("sync under the link: %v, %+v", err, report)
+	}
+	for _, fileReport := range report.Files {
+		if fileReport.Filename == "link/new.txt" && fileReport.Error == "" {
+			t.Errorf("link/new.txt synced: %+v", fileReport)
+		}
+	}
+	var latestVersion int32
+	renamed := &FileMetaData{Filename: "link/renamed.txt", Version: 1, BlockHashList: cluster.files["dir/renamed.txt"].GetBlockHashList(), BlockSize: 4, Size: 4}
+	if err := cluster.RenameFile(&FileRename{OldFilename: "dir/renamed.txt", OldVersion: 1, FileMetaData: renamed}, &latestVersion); err != nil || latestVersion != 1 {
+		t.Fatalf("rename: %v, version %d", err, latestVersion)
+	}
+	if _, err := syncer.Sync(); !errors.Is(err, ErrSyncIncomplete) {
+		t.Fatalf("sync of a rename under the link: %v", err)
+	}
+
+	// Nor is anything removed through the link
+	tombstone := &FileMetaData{Filename: "link/victim.txt", Version: 2, BlockHashList: []string{TOMBSTONE_HASH}}
+	if _, err := syncer.updateLocalFile(baseDir, "link/victim.txt", tombstone); !errors.Is(err, ErrSymlinkedParent) {
+		t.Errorf("remove through the link: %v, want ErrSymlinkedParent", err)
+	}
+	assertTestFiles(t, outside, map[string]string{"victim.txt": "victim"})
+}
+
+// newLinkTestDir creates a base directory holding a.txt, a symbolic link to it, a hard link
+// to it, and a symbolic link to a directory outside the base directory
+func newLinkTestDir(t *testing.T) string {
+	baseDir := t.TempDir()
+	outside := t.TempDir()
+	writeTestFiles(t, baseDir, map[string]string{"a.txt": "aaaaaaaa"})
+	for _, err := range []error{
+		os.Symlink("a.txt", filepath.Join(baseDir, "symlink")),
+		os.Link(filepath.Join(baseDir, "a.txt"), filepath.Join(baseDir, "z.txt")),
+		os.Symlink(outside, filepath.Join(baseDir, "dirlink")),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return baseDir
+}
+
+func TestSyncPreservesLinks(t *testing.T) {
+	cluster := newFakeCluster()
+	options := SyncOptions{Chunker: NewFixedSizeChunker(4), LinkPolicy: LINK_PRESERVE}
+	if _, err := NewSyncer(cluster, newLinkTestDir(t), options).Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if target := cluster.files["symlink"].GetSymlinkTarget(); target != "a.txt" {
+		t.Errorf("symlink uploaded pointing to %q", target)
+	}
+	if target := cluster.files["z.txt"].GetHardlinkTarget(); target != "a.txt" {
+		t.Errorf("hard link uploaded linking to %q", target)
+	}
+
+	// Hard links are synced after their targets, so they are linked instead of downloaded
+	baseDir := t.TempDir()
+	report, err := NewSyncer(cluster, baseDir, options).Sync()
+	if err != nil || report.Count(ACTION_DOWNLOADED) != 4 {
+		t.Fatalf("download: %v, %+v", err, report)
+	}
+	for _, fileReport := range report.Files {
+		if fileReport.Filename == "z.txt" && fileReport.BytesDownloaded != 0 {
+			t.Errorf("hard link downloaded %d bytes", fileReport.BytesDownloaded)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(baseDir, "symlink")); err != nil || target != "a.txt" {
+		t.Errorf("symlink points to %q, %v", target, err)
+	}
+	a, errA := os.Stat(filepath.Join(baseDir, "a.txt"))
+	z, errZ := os.Stat(filepath.Join(baseDir, "z.txt"))
+	if errA != nil || errZ != nil || !os.SameFile(a, z) {
+		t.Errorf("z.txt is not a hard link to a.txt: %v, %v", errA, errZ)
+	}
+}
+
+func TestSyncFollowsLinks(t *testing.T) {
+	cluster := newFakeCluster()
+	report, err := NewSyncer(cluster, newLinkTestDir(t), SyncOptions{Chunker: NewFixedSizeChunker(4), LinkPolicy: LINK_FOLLOW}).Sync()
+	if err != nil || report.Count(ACTION_UPLOADED) != 3 {
+		t.Fatalf("sync: %v, %+v", err, report)
+	}
+	symlink := cluster.files["symlink"]
+	if isSymlink(symlink) || symlink.GetSize() != 8 || !equalHashLists(symlink.GetBlockHashList(), cluster.files["a.txt"].GetBlockHashList()) {
+		t.Errorf("followed link uploaded as %v", symlink)
+	}
+	if _, exists := cluster.files["dirlink"]; exists {
+		t.Error("link to a directory uploaded")
+	}
+}
+
+func TestSyncSkipsLinks(t *testing.T) {
+	cluster := newFakeCluster()
+	options := SyncOptions{Chunker: NewFixedSizeChunker(4), LinkPolicy: LINK_SKIP}
+	if _, err := NewSyncer(cluster, newLinkTestDir(t), options).Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.files) != 2 || cluster.files["a.txt"] == nil || cluster.files["z.txt"] == nil {
+		t.Errorf("uploaded %v", cluster.files)
+	}
+
+	// Remote links are not downloaded either
+	cluster.putFile("remote-link", "a.txt", 4, true).SymlinkTarget = "a.txt"
+	baseDir := t.TempDir()
+	if _, err := NewSyncer(cluster, baseDir, options).Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(baseDir, "remote-link")); !os.IsNotExist(err) {
+		t.Errorf("remote link downloaded: %v", err)
+	}
+	assertTestFiles(t, baseDir, map[string]string{"a.txt": "aaaaaaaa", "z.txt": "aaaaaaaa"})
+}
@@ -0,0 +1,53 @@
+package servestore
+
+import (
+	"io/fs"
+	"time"
+)
+
+// LocalIndex is the content of a client's local metadata file
+type LocalIndex struct {
+	Files map[string]*FileMetaData // Metadata of each file as of the last sync
+	Stats map[string]*FileStat     // Stat cache of each file as of the last sync
+
+	WriteTime     time.Time // Modification time of the local metadata file when it was loaded
+	FormatVersion int       // Format version of the local metadata file when it was loaded
+}
+
+// FileStat is the state of a local file on disk when it was last synced. A file whose
+// FileStat is unchanged since is assumed unchanged, and is not read and hashed again.
+type FileStat struct {
+	Size  int64
+	Mtime int64 // Nanoseconds since the epoch
+	Ctime int64 // Nanoseconds since the epoch, 0 where unavailable
+	Ino   uint64
+}
+
+// Timestamps are only trusted if they are older than the local index by at least this much,
+// to allow for file systems that store them with coarse precision
+const RACY_TIMESTAMP_WINDOW time.Duration = time.Second
+
+func newFileStat(info fs.FileInfo) *FileStat {
+	id, _ := getFileID(info)
+	return &FileStat{
+		Size:  info.Size(),
+		Mtime: info.ModTime().UnixNano(),
+		Ctime: getFileCtime(info),
+		Ino:   id.ino,
+	}
+}
+
+// unchangedSince reports whether a file with stat cache `cached`, recorded in a local
+// index written at `indexWriteTime`, can be assumed unchanged given its current stat.
+//
+// A file modified within RACY_TIMESTAMP_WINDOW of the index being written is "racy": it
+// may have been modified again after it was hashed without its timestamps changing, so
+// it is always hashed.
+func (current *FileStat) unchangedSince(cached *FileStat, indexWriteTime time.Time) bool {
+	if cached == nil || *current != *cached {
+		return false
+	}
+
+	racyTime := indexWriteTime.Add(-RACY_TIMESTAMP_WINDOW).UnixNano()
+	return current.Mtime < racyTime && current.Ctime < racyTime
+}
@@ -0,0 +1,85 @@
+package servestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStatUnchangedSince(t *testing.T) {
+	indexWriteTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	old := indexWriteTime.Add(-time.Hour).UnixNano()
+	racy := indexWriteTime.Add(-RACY_TIMESTAMP_WINDOW / 2).UnixNano()
+	cached := &FileStat{Size: 4, Mtime: old, Ctime: old, Ino: 7}
+
+	tests := []struct {
+		name      string
+		current   FileStat
+		cached    *FileStat
+		unchanged bool
+	}{
+		{"unchanged", *cached, cached, true},
+		{"never cached", *cached, nil, false},
+		{"resized", FileStat{Size: 5, Mtime: old, Ctime: old, Ino: 7}, cached, false},
+		{"replaced", FileStat{Size: 4, Mtime: old, Ctime: old, Ino: 8}, cached, false},
+		{"modified within the racy window", FileStat{Size: 4, Mtime: racy, Ctime: racy, Ino: 7}, &FileStat{Size: 4, Mtime: racy, Ctime: racy, Ino: 7}, false},
+		{"status changed within the racy window", FileStat{Size: 4, Mtime: old, Ctime: racy, Ino: 7}, &FileStat{Size: 4, Mtime: old, Ctime: racy, Ino: 7}, false},
+	}
+	for _, test := range tests {
+		if unchanged := test.current.unchangedSince(test.cached, indexWriteTime); unchanged != test.unchanged {
+			t.Errorf("%s: unchanged %v, want %v", test.name, unchanged, test.unchanged)
+		}
+	}
+}
+
+func TestStatCacheSkipsHashingUnchangedFiles(t *testing.T) {
+	addr := startSyncCluster(t)
+	baseDir := t.TempDir()
+	writeTestFiles(t, baseDir, map[string]string{"a.txt": "aaaaaaaa"})
+	if _, err := ClientSync(NewServeStoreRPCClient(addr, baseDir, 4)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The local index records stale blocks for a.txt, so that a.txt is planned to be
+	// uploaded whenever it is hashed, and skipped when its stat cache is trusted
+	localIndex, err := LoadLocalIndex(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localIndex.Files["a.txt"].BlockHashList = []string{GetBlockHashString([]byte("stale"))}
+	if err := WriteLocalIndex(localIndex, baseDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// setIndexWriteTime makes the local index look written at indexWriteTime
+	indexPath := filepath.Join(baseDir, DEFAULT_META_FILENAME)
+	setIndexWriteTime := func(indexWriteTime time.Time) {
+		if err := os.Chtimes(indexPath, indexWriteTime, indexWriteTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	planAction := func(rehash bool) SyncAction {
+		client := NewServeStoreRPCClient(addr, baseDir, 4)
+		client.Rehash = rehash
+		plan, err := ClientPlan(client)
+		if err != nil || len(plan.Files) != 1 {
+			t.Fatalf("plan: %v, %+v", err, plan)
+		}
+		return plan.Files[0].Action
+	}
+
+	// The file was written moments before the index, so it may have changed since it was hashed
+	setIndexWriteTime(time.Now())
+	if action := planAction(false); action != ACTION_UPLOADED {
+		t.Errorf("file modified within the racy window was not hashed, and planned %s", action)
+	}
+
+	setIndexWriteTime(time.Now().Add(time.Hour))
+	if action := planAction(false); action != ACTION_SKIPPED {
+		t.Errorf("unchanged file hashed, and planned %s", action)
+	}
+	if action := planAction(true); action != ACTION_UPLOADED {
+		t.Errorf("unchanged file not hashed with Rehash, and planned %s", action)
+	}
+}
@@ -0,0 +1,446 @@
+package servestore
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSeek = errors.New("ErrInvalidSeek")
+
+// Methods served by the gateway. Every other method is refused, the gateway is read-only.
+const GATEWAY_ALLOWED_METHODS string = "OPTIONS, GET, HEAD, PROPFIND"
+
+// WebDAV compliance class announced by the gateway, which supports no locks
+const DAV_COMPLIANCE_CLASS string = "1"
+
+// Gateway serves the files of a MetaStore read-only over HTTP and WebDAV. Directories are
+// the prefixes of the slash separated filenames, and files are streamed from their blocks.
+// Symbolic links are served as a small file holding their target, like clients that do not
+// know about links recreate them.
+type Gateway struct {
+	client ClientInterface
+}
+
+func NewGateway(client ClientInterface) *Gateway {
+	return &Gateway{client: client}
+}
+
+// gatewayEntry is a file or directory served by the gateway. Directories have no metadata.
+type gatewayEntry struct {
+	name         string
+	fileMetaData *FileMetaData
+}
+
+func (e *gatewayEntry) isDir() bool {
+	return e.fileMetaData == nil
+}
+
+// href returns the escaped absolute path of the entry, ending in a slash for directories
+func (e *gatewayEntry) href() string {
+	p := "/" + e.name
+	if e.isDir() && e.name != "" {
+		p += "/"
+	}
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Allow", GATEWAY_ALLOWED_METHODS)
+		w.Header().Set("DAV", DAV_COMPLIANCE_CLASS)
+		return
+	case http.MethodGet, http.MethodHead, "PROPFIND":
+	default:
+		w.Header().Set("Allow", GATEWAY_ALLOWED_METHODS)
+		http.Error(w, "The gateway is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fileInfoMap := make(map[string]*FileMetaData)
+	if err := g.client.GetFileInfoMap(&fileInfoMap); err != nil {
+		log.Printf("Gateway %s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, "MetaStore unavailable", http.StatusBadGateway)
+		return
+	}
+
+	entry, ok := lookupEntry(fileInfoMap, strings.Trim(path.Clean("/"+r.URL.Path), "/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == "PROPFIND":
+		g.propfind(w, r, entry, fileInfoMap)
+	case entry.isDir():
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, (&url.URL{Path: path.Base(r.URL.Path) + "/"}).String(), http.StatusMovedPermanently)
+			return
+		}
+		g.listDirectory(w, entry, fileInfoMap)
+	default:
+		g.serveFile(w, r, entry.fileMetaData)
+	}
+}
+
+// lookupEntry returns the file named name, or the directory holding files whose names
+// start with name and a slash. The root directory is named "".
+func lookupEntry(fileInfoMap map[string]*FileMetaData, name string) (*gatewayEntry, bool) {
+	if fileMetaData, ok := fileInfoMap[name]; ok && !isTombstone(fileMetaData) {
+		return &gatewayEntry{name: name, fileMetaData: fileMetaData}, true
+	}
+	if name == "" {
+		return &gatewayEntry{}, true
+	}
+	for filename, fileMetaData := range fileInfoMap {
+		if strings.HasPrefix(filename, name+"/") && !isTombstone(fileMetaData) {
+			return &gatewayEntry{name: name}, true
+		}
+	}
+	return nil, false
+}
+
+// dirEntries returns the files and directories directly in the directory, sorted by name
+func dirEntries(fileInfoMap map[string]*FileMetaData, dir string) []*gatewayEntry {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	var entries []*gatewayEntry
+	subdirs := make(map[string]bool)
+	for filename, fileMetaData := range fileInfoMap {
+		if !strings.HasPrefix(filename, prefix) || isTombstone(fileMetaData) {
+			continue
+		}
+		if i := strings.Index(filename[len(prefix):], "/"); i >= 0 {
+			subdir := filename[:len(prefix)+i]
+			if !subdirs[subdir] {
+				subdirs[subdir] = true
+				entries = append(entries, &gatewayEntry{name: subdir})
+			}
+			continue
+		}
+		entries = append(entries, &gatewayEntry{name: filename, fileMetaData: fileMetaData})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries
+}
+
+// fileETag derives the entity tag of a file from its block hash list, so that it changes
+// whenever the content of the file does
+func fileETag(fileMetaData *FileMetaData) string {
+	return `"` + GetBlockHashString([]byte(strings.Join(fileMetaData.GetBlockHashList(), HASH_DELIMITER))) + `"`
+}
+
+// modTime returns the modification time of a file, or the zero time if it is unknown
+func modTime(fileMetaData *FileMetaData) time.Time {
+	if fileMetaData.GetMtime() == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, fileMetaData.GetMtime()).UTC()
+}
+
+func (g *Gateway) serveFile(w http.ResponseWriter, r *http.Request, fileMetaData *FileMetaData) {
+	if err := serveFileBlocks(w, r, g.client, fileMetaData); err != nil {
+		log.Printf("Gateway %s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, "BlockStores unavailable", http.StatusBadGateway)
+	}
+}
+
+// serveFileBlocks streams a file from its blocks. Only the blocks holding the requested
+// ranges are fetched, and conditional requests are answered from the ETag and modification
+// time. The first block to be sent is fetched before the response starts, so that an error
+// is returned while one can still be sent; a block failing later cuts the response short.
+func serveFileBlocks(w http.ResponseWriter, r *http.Request, client ClientInterface, fileMetaData *FileMetaData) error {
+	placement, err := FetchBlockPlacement(client)
+	if err != nil {
+		return err
+	}
+
+	reader := newBlockReader(client, placement, fileMetaData)
+	if r.Method != http.MethodHead {
+		if err := reader.prefetch(firstRangeOffset(r.Header.Get("Range"), fileMetaData.GetSize())); err != nil {
+			return err
+		}
+	}
+	w.Header().Set("ETag", fileETag(fileMetaData))
+	http.ServeContent(w, r, fileMetaData.GetFilename(), modTime(fileMetaData), reader)
+	if reader.err != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, reader.err)
+	}
+	return nil
+}
+
+// firstRangeOffset returns the offset of the first range of a Range header, or 0 if there
+// is none or it cannot be satisfied, in which case the whole file is sent or none of it
+func firstRangeOffset(rangeHeader string, size int64) int64 {
+	spec := strings.TrimPrefix(rangeHeader, "bytes=")
+	if spec == rangeHeader {
+		return 0
+	}
+	spec, _, _ = strings.Cut(spec, ",")
+	start, end, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0
+	}
+	if start == "" {
+		suffix, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || suffix >= size {
+			return 0
+		}
+		return size - suffix
+	}
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || offset >= size {
+		return 0
+	}
+	return offset
+}
+
+// directoryListing is the HTML page listing a directory
+var directoryListing = template.Must(template.New("directory").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+{{if .Parent}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.Link}}">{{.Name}}</a></td><td>{{.Size}}</td><td>{{.Modified}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type directoryListingEntry struct {
+	Name     string
+	Link     string
+	Size     string
+	Modified string
+}
+
+func (g *Gateway) listDirectory(w http.ResponseWriter, dir *gatewayEntry, fileInfoMap map[string]*FileMetaData) {
+	var entries []directoryListingEntry
+	for _, entry := range dirEntries(fileInfoMap, dir.name) {
+		name := path.Base(entry.name)
+		listingEntry := directoryListingEntry{Name: name, Link: (&url.URL{Path: name}).String()}
+		if entry.isDir() {
+			listingEntry.Name += "/"
+			listingEntry.Link += "/"
+		} else {
+			listingEntry.Size = fmt.Sprint(entry.fileMetaData.GetSize())
+			if modified := modTime(entry.fileMetaData); !modified.IsZero() {
+				listingEntry.Modified = modified.Format(time.RFC3339)
+			}
+		}
+		entries = append(entries, listingEntry)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := directoryListing.Execute(w, struct {
+		Path    string
+		Parent  bool
+		Entries []directoryListingEntry
+	}{Path: dir.href(), Parent: dir.name != "", Entries: entries})
+	if err != nil {
+		log.Printf("Gateway list %s: %v", dir.href(), err)
+	}
+}
+
+// WebDAV multistatus response to PROPFIND, listing the properties of each resource
+type davMultistatus struct {
+	XMLName   xml.Name       `xml:"D:multistatus"`
+	XMLNS     string         `xml:"xmlns:D,attr"`
+	Responses []*davResponse `xml:"D:response"`
+}
+
+type davResponse struct {
+	Href     string      `xml:"D:href"`
+	Propstat davPropstat `xml:"D:propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"D:prop"`
+	Status string  `xml:"D:status"`
+}
+
+type davProp struct {
+	DisplayName   string          `xml:"D:displayname"`
+	ResourceType  davResourceType `xml:"D:resourcetype"`
+	ContentLength *int64          `xml:"D:getcontentlength,omitempty"`
+	ContentType   string          `xml:"D:getcontenttype,omitempty"`
+	LastModified  string          `xml:"D:getlastmodified,omitempty"`
+	ETag          string          `xml:"D:getetag,omitempty"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"D:collection"`
+}
+
+// propfind answers a WebDAV PROPFIND with the properties of the entry, and of the entries
+// of a directory at depth 1. Every live property is returned, whichever were requested.
+func (g *Gateway) propfind(w http.ResponseWriter, r *http.Request, entry *gatewayEntry, fileInfoMap map[string]*FileMetaData) {
+	io.Copy(io.Discard, r.Body)
+
+	entries := []*gatewayEntry{entry}
+	switch r.Header.Get("Depth") {
+	case "0":
+	case "1":
+		if entry.isDir() {
+			entries = append(entries, dirEntries(fileInfoMap, entry.name)...)
+		}
+	default: // Listing the whole namespace at once is refused, as RFC 4918 allows
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, xml.Header+`<D:error xmlns:D="DAV:"><D:propfind-finite-depth/></D:error>`)
+		return
+	}
+
+	multistatus := &davMultistatus{XMLNS: "DAV:"}
+	for _, entry := range entries {
+		multistatus.Responses = append(multistatus.Responses, davEntryResponse(entry))
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	fmt.Fprint(w, xml.Header)
+	if err := xml.NewEncoder(w).Encode(multistatus); err != nil {
+		log.Printf("Gateway PROPFIND %s: %v", r.URL.Path, err)
+	}
+}
+
+func davEntryResponse(entry *gatewayEntry) *davResponse {
+	prop := davProp{DisplayName: path.Base("/" + entry.name)}
+	if entry.isDir() {
+		prop.ResourceType.Collection = &struct{}{}
+	} else {
+		size := entry.fileMetaData.GetSize()
+		prop.ContentLength = &size
+		prop.ContentType = mime.TypeByExtension(path.Ext(entry.name))
+		if prop.ContentType == "" {
+			prop.ContentType = "application/octet-stream"
+		}
+		if modified := modTime(entry.fileMetaData); !modified.IsZero() {
+			prop.LastModified = modified.Format(http.TimeFormat)
+		}
+		prop.ETag = fileETag(entry.fileMetaData)
+	}
+	return &davResponse{Href: entry.href(), Propstat: davPropstat{Prop: prop, Status: "HTTP/1.1 200 OK"}}
+}
+
+// blockReader reads a file from its blocks, fetching only the block holding the offset read.
+// Files uploaded before their block size was recorded are assumed to be cut into blocks
+// of the size of their first block.
+type blockReader struct {
+	client       ClientInterface
+	placement    *BlockPlacement
+	fileMetaData *FileMetaData
+	blockSize    int64
+	offset       int64
+	blockIndex   int
+	block        []byte
+	err          error
+}
+
+func newBlockReader(client ClientInterface, placement *BlockPlacement, fileMetaData *FileMetaData) *blockReader {
+	return &blockReader{
+		client:       client,
+		placement:    placement,
+		fileMetaData: fileMetaData,
+		blockSize:    int64(fileMetaData.GetBlockSize()),
+		blockIndex:   -1,
+	}
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	if b.offset >= b.fileMetaData.GetSize() {
+		return 0, io.EOF
+	}
+	if err := b.prefetch(b.offset); err != nil {
+		return 0, err
+	}
+	n := copy(p, b.block[b.offset-int64(b.blockIndex)*b.blockSize:])
+	b.offset += int64(n)
+	return n, nil
+}
+
+// fetchBlock fetches the block at index, unless it is the last one fetched, and checks
+// that it is as long as the block size and file size say
+func (b *blockReader) fetchBlock(index int) error {
+	if index == b.blockIndex {
+		return nil
+	}
+
+	blockHashList := b.fileMetaData.GetBlockHashList()
+	if index >= len(blockHashList) {
+		b.err = fmt.Errorf("%s has %d blocks, want more than %d", b.fileMetaData.GetFilename(), len(blockHashList), index)
+		return b.err
+	}
+	block := &Block{}
+	if err := b.placement.GetBlock(b.client, blockHashList[index], block); err != nil {
+		b.err = fmt.Errorf("get block %d of %s: %w", index, b.fileMetaData.GetFilename(), err)
+		return b.err
+	}
+
+	if b.blockSize != 0 {
+		want := b.blockSize
+		if index == len(blockHashList)-1 {
+			want = b.fileMetaData.GetSize() - int64(index)*b.blockSize
+		}
+		if int64(len(block.GetBlockData())) != want {
+			b.err = fmt.Errorf("block %d of %s has %d bytes, want %d", index, b.fileMetaData.GetFilename(), len(block.GetBlockData()), want)
+			return b.err
+		}
+	}
+	if len(block.GetBlockData()) == 0 {
+		b.err = fmt.Errorf("block %d of %s is empty", index, b.fileMetaData.GetFilename())
+		return b.err
+	}
+
+	b.blockIndex, b.block = index, block.GetBlockData()
+	return nil
+}
+
+// prefetch fetches the block holding offset, which the next read from offset then uses
+func (b *blockReader) prefetch(offset int64) error {
+	if offset >= b.fileMetaData.GetSize() {
+		return nil
+	}
+	if b.blockSize == 0 {
+		if err := b.fetchBlock(0); err != nil {
+			return err
+		}
+		b.blockSize = int64(len(b.block))
+	}
+	return b.fetchBlock(int(offset / b.blockSize))
+}
+
+func (b *blockReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.fileMetaData.GetSize()
+	default:
+		return 0, ErrInvalidSeek
+	}
+	if offset < 0 {
+		return 0, ErrInvalidSeek
+	}
+	b.offset = offset
+	return offset, nil
+}
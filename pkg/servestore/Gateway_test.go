@@ -0,0 +1,196 @@
+package servestore
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestGateway(t *testing.T) (*fakeCluster, *httptest.Server) {
+	cluster := newFakeCluster()
+	cluster.putFile("docs/a.txt", "the quick brown fox jumps over the lazy dog", 4, true)
+	cluster.putFile("docs/old.txt", "written before block sizes were recorded", 8, false)
+	cluster.putFile("docs/sub dir/c.txt", "c", 4, true)
+	cluster.putFile("b.txt", "bbbb", 4, true)
+	cluster.files["gone.txt"] = &FileMetaData{Filename: "gone.txt", Version: 2, BlockHashList: []string{TOMBSTONE_HASH}}
+
+	server := httptest.NewServer(NewGateway(cluster))
+	t.Cleanup(server.Close)
+	return cluster, server
+}
+
+func doRequest(t *testing.T, method string, url string, header map[string]string) (*http.Response, string) {
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response, string(body)
+}
+
+func TestGatewayServesFiles(t *testing.T) {
+	cluster, server := newTestGateway(t)
+
+	response, body := doRequest(t, http.MethodGet, server.URL+"/docs/a.txt", nil)
+	if response.StatusCode != http.StatusOK || body != "the quick brown fox jumps over the lazy dog" {
+		t.Fatalf("GET a.txt: %s %q", response.Status, body)
+	}
+	etag := response.Header.Get("ETag")
+	if etag != fileETag(cluster.files["docs/a.txt"]) || response.Header.Get("Last-Modified") != "Fri, 02 Jan 2026 03:04:05 GMT" {
+		t.Errorf("GET a.txt headers %v", response.Header)
+	}
+
+	response, _ = doRequest(t, http.MethodGet, server.URL+"/docs/a.txt", map[string]string{"If-None-Match": etag})
+	if response.StatusCode != http.StatusNotModified {
+		t.Errorf("GET a.txt with its ETag: %s, want 304", response.Status)
+	}
+
+	response, body = doRequest(t, http.MethodHead, server.URL+"/b.txt", nil)
+	if response.StatusCode != http.StatusOK || response.ContentLength != 4 || body != "" {
+		t.Errorf("HEAD b.txt: %s, length %d, %q", response.Status, response.ContentLength, body)
+	}
+
+	for _, path := range []string{"/gone.txt", "/missing.txt", "/doc"} {
+		if response, _ := doRequest(t, http.MethodGet, server.URL+path, nil); response.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: %s, want 404", path, response.Status)
+		}
+	}
+}
+
+func TestGatewayRangeRequests(t *testing.T) {
+	_, server := newTestGateway(t)
+
+	tests := []struct {
+		path  string
+		rng   string
+		want  string
+		total string
+	}{
+		{"/docs/a.txt", "bytes=6-18", "ick brown fox", "/43"},
+		{"/docs/a.txt", "bytes=-3", "dog", "/43"},
+		{"/docs/a.txt", "bytes=40-", "dog", "/43"},
+		{"/docs/old.txt", "bytes=10-29", "fore block sizes wer", "/40"},
+	}
+	for _, test := range tests {
+		response, body := doRequest(t, http.MethodGet, server.URL+test.path, map[string]string{"Range": test.rng})
+		if response.StatusCode != http.StatusPartialContent || body != test.want || !strings.HasSuffix(response.Header.Get("Content-Range"), test.total) {
+			t.Errorf("GET %s %s: %s %q, Content-Range %s, want %q", test.path, test.rng, response.Status, body, response.Header.Get("Content-Range"), test.want)
+		}
+	}
+
+	if response, _ := doRequest(t, http.MethodGet, server.URL+"/b.txt", map[string]string{"Range": "bytes=10-"}); response.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("GET b.txt past its end: %s, want 416", response.Status)
+	}
+}
+
+func TestGatewayBlockFailures(t *testing.T) {
+	cluster, server := newTestGateway(t)
+
+	// The last block of a.txt is lost: ranges holding it fail before the response starts
+	cluster.mu.Lock()
+	delete(cluster.blocks["a:1"], GetBlockHashString([]byte("dog")))
+	cluster.mu.Unlock()
+	if response, _ := doRequest(t, http.MethodGet, server.URL+"/docs/a.txt", map[string]string{"Range": "bytes=40-"}); response.StatusCode != http.StatusBadGateway {
+		t.Errorf("GET a range of a lost block: %s, want 502", response.Status)
+	}
+	if response, body := doRequest(t, http.MethodGet, server.URL+"/docs/a.txt", map[string]string{"Range": "bytes=0-3"}); response.StatusCode != http.StatusPartialContent || body != "the " {
+		t.Errorf("GET a range of other blocks: %s %q, want 206 %q", response.Status, body, "the ")
+	}
+
+	cluster.mu.Lock()
+	cluster.down["a:1"] = true
+	cluster.mu.Unlock()
+	if response, _ := doRequest(t, http.MethodGet, server.URL+"/b.txt", nil); response.StatusCode != http.StatusBadGateway {
+		t.Errorf("GET from an unreachable BlockStore: %s, want 502", response.Status)
+	}
+	if response, _ := doRequest(t, http.MethodHead, server.URL+"/b.txt", nil); response.StatusCode != http.StatusOK {
+		t.Errorf("HEAD from an unreachable BlockStore: %s, want 200", response.Status)
+	}
+}
+
+func TestGatewayListsDirectories(t *testing.T) {
+	_, server := newTestGateway(t)
+
+	response, body := doRequest(t, http.MethodGet, server.URL+"/", nil)
+	if response.StatusCode != http.StatusOK || !strings.Contains(body, `<a href="docs/">docs/</a>`) ||
+		!strings.Contains(body, `<a href="b.txt">b.txt</a>`) || strings.Contains(body, "gone.txt") {
+		t.Errorf("GET /: %s\n%s", response.Status, body)
+	}
+
+	response, body = doRequest(t, http.MethodGet, server.URL+"/docs", nil)
+	if response.Request.URL.Path != "/docs/" || !strings.Contains(body, `<a href="sub%20dir/">sub dir/</a>`) ||
+		!strings.Contains(body, `<a href="a.txt">a.txt</a></td><td>43</td><td>2026-01-02T03:04:05Z</td>`) {
+		t.Errorf("GET /docs: %s %s\n%s", response.Request.URL, response.Status, body)
+	}
+}
+
+// Properties of a PROPFIND response, as parsed by WebDAV clients
+type testMultistatus struct {
+	Responses []struct {
+		Href          string    `xml:"DAV: href"`
+		Collection    *struct{} `xml:"DAV: propstat>prop>resourcetype>collection"`
+		ContentLength int64     `xml:"DAV: propstat>prop>getcontentlength"`
+		ETag          string    `xml:"DAV: propstat>prop>getetag"`
+		Status        string    `xml:"DAV: propstat>status"`
+	} `xml:"DAV: response"`
+}
+
+func TestGatewayPropfind(t *testing.T) {
+	cluster, server := newTestGateway(t)
+
+	response, body := doRequest(t, "PROPFIND", server.URL+"/docs/", map[string]string{"Depth": "1"})
+	if response.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND /docs/: %s", response.Status)
+	}
+	var multistatus testMultistatus
+	if err := xml.Unmarshal([]byte(body), &multistatus); err != nil {
+		t.Fatal(err)
+	}
+	responses := multistatus.Responses
+	if len(responses) != 4 || responses[0].Href != "/docs/" || responses[0].Collection == nil ||
+		responses[1].Href != "/docs/a.txt" || responses[1].Collection != nil || responses[1].ContentLength != 43 ||
+		responses[1].ETag != fileETag(cluster.files["docs/a.txt"]) || responses[3].Href != "/docs/sub%20dir/" {
+		t.Errorf("PROPFIND /docs/ listed %+v", responses)
+	}
+
+	response, body = doRequest(t, "PROPFIND", server.URL+"/b.txt", map[string]string{"Depth": "0"})
+	multistatus = testMultistatus{}
+	if err := xml.Unmarshal([]byte(body), &multistatus); err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusMultiStatus || len(multistatus.Responses) != 1 || multistatus.Responses[0].Status != "HTTP/1.1 200 OK" {
+		t.Errorf("PROPFIND /b.txt: %s %+v", response.Status, multistatus.Responses)
+	}
+
+	if response, _ := doRequest(t, "PROPFIND", server.URL+"/", map[string]string{"Depth": "infinity"}); response.StatusCode != http.StatusForbidden {
+		t.Errorf("PROPFIND / at infinite depth: %s, want 403", response.Status)
+	}
+}
+
+func TestGatewayIsReadOnly(t *testing.T) {
+	_, server := newTestGateway(t)
+
+	response, _ := doRequest(t, http.MethodOptions, server.URL+"/", nil)
+	if response.Header.Get("DAV") != DAV_COMPLIANCE_CLASS || response.Header.Get("Allow") != GATEWAY_ALLOWED_METHODS {
+		t.Errorf("OPTIONS headers %v", response.Header)
+	}
+	for _, method := range []string{http.MethodPut, http.MethodDelete, "MKCOL", "MOVE", "LOCK"} {
+		if response, _ := doRequest(t, method, server.URL+"/b.txt", nil); response.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("%s b.txt: %s, want 405", method, response.Status)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package servestore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// ignorePattern is a single gitignore-style pattern
+type ignorePattern struct {
+	base     string   // Directory the pattern is relative to, as a slash-separated path relative to the base directory ("" for the base directory)
+	segments []string // Pattern split on "/", may contain "**" segments
+	negate   bool     // Pattern starts with "!" and re-includes matching paths
+	dirOnly  bool     // Pattern ends with "/" and only matches directories
+	anchored bool     // Pattern contains a "/" and only matches relative to `base`
+}
+
+// IgnoreRules decides which paths under a client's base directory are never synced.
+// Rules come from gitignore-style IGNORE_FILENAME files, which may be placed in any
+// directory, and from exclude and include patterns given on the command line.
+// Exclude patterns behave like a IGNORE_FILENAME file in the base directory that
+// every other file takes precedence over, and include patterns re-include paths
+// after every other rule (as if they were "!pattern" lines).
+//
+// As with gitignore, a later matching pattern takes precedence over an earlier one,
+// patterns in deeper directories take precedence over patterns in their parents,
+// and a path inside an ignored directory is always ignored.
+type IgnoreRules struct {
+	patterns []*ignorePattern
+	includes []*ignorePattern
+}
+
+func NewIgnoreRules(excludes []string, includes []string) *IgnoreRules {
+	rules := &IgnoreRules{
+		patterns: make([]*ignorePattern, 0),
+		includes: make([]*ignorePattern, 0),
+	}
+
+	for _, exclude := range excludes {
+		if pattern := parseIgnorePattern("", exclude); pattern != nil {
+			rules.patterns = append(rules.patterns, pattern)
+		}
+	}
+	for _, include := range includes {
+		if pattern := parseIgnorePattern("", include); pattern != nil {
+			pattern.negate = !pattern.negate
+			rules.includes = append(rules.includes, pattern)
+		}
+	}
+
+	return rules
+}
+
+// LoadIgnoreFile adds the patterns of the IGNORE_FILENAME file in `dir`, a slash-separated
+// path relative to the base directory. It is a no-op if the file does not exist.
+func (r *IgnoreRules) LoadIgnoreFile(baseDir string, dir string) error {
+	ignorePath := ConcatPath(baseDir, IGNORE_FILENAME)
+	if dir != "" {
+		ignorePath = ConcatPath(baseDir, ConcatPath(dir, IGNORE_FILENAME))
+	}
+
+	ignoreFD, err := os.Open(ignorePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer ignoreFD.Close()
+
+	scanner := bufio.NewScanner(ignoreFD)
+	for scanner.Scan() {
+		if pattern := parseIgnorePattern(dir, scanner.Text()); pattern != nil {
+			r.patterns = append(r.patterns, pattern)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Ignored reports whether the slash-separated path relative to the base directory is ignored.
+// Each parent directory of the path is checked first, so that remote files inside a locally
+// ignored directory are ignored too.
+func (r *IgnoreRules) Ignored(filename string, isDir bool) bool {
+	segments := strings.Split(filename, "/")
+	for i := 1; i < len(segments); i++ {
+		if r.matches(strings.Join(segments[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.matches(filename, isDir)
+}
+
+func (r *IgnoreRules) matches(filename string, isDir bool) bool {
+	ignored := false
+	for _, pattern := range r.patterns {
+		if pattern.match(filename, isDir) {
+			ignored = !pattern.negate
+		}
+	}
+	for _, pattern := range r.includes {
+		if pattern.match(filename, isDir) {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+// parseIgnorePattern parses one line of an ignore file, returning nil for blank lines and comments
+func parseIgnorePattern(base string, line string) *ignorePattern {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	pattern := &ignorePattern{base: base}
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\#") || strings.HasPrefix(line, "\\!") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		pattern.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	pattern.segments = strings.Split(line, "/")
+	if !pattern.anchored {
+		// A pattern without a slash matches at any depth below its directory
+		pattern.segments = append([]string{"**"}, pattern.segments...)
+	}
+
+	return pattern
+}
+
+func (p *ignorePattern) match(filename string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		if !strings.HasPrefix(filename, p.base+"/") {
+			return false
+		}
+		filename = strings.TrimPrefix(filename, p.base+"/")
+	}
+
+	return matchSegments(p.segments, strings.Split(filename, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, where a "**" pattern
+// segment matches zero or more path segments. A trailing "**" matches one or more, so that
+// "dir/**" matches everything inside dir but not dir itself.
+func matchSegments(patternSegments []string, pathSegments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(pathSegments) == 0
+	}
+
+	if len(patternSegments) == 1 && patternSegments[0] == "**" {
+		return len(pathSegments) > 0
+	}
+	if patternSegments[0] == "**" {
+		for i := 0; i <= len(pathSegments); i++ {
+			if matchSegments(patternSegments[1:], pathSegments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(pathSegments) == 0 {
+		return false
+	}
+	if matched, err := path.Match(patternSegments[0], pathSegments[0]); err != nil || !matched {
+		return false
+	}
+	return matchSegments(patternSegments[1:], pathSegments[1:])
+}
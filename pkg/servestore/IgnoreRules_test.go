@@ -0,0 +1,104 @@
+package servestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	type check struct {
+		filename string
+		isDir    bool
+		ignored  bool
+	}
+	tests := []struct {
+		name     string
+		excludes []string
+		includes []string
+		checks   []check
+	}{
+		{"unanchored", []string{"*.log"}, nil, []check{
+			{"a.log", false, true}, {"dir/sub/b.log", false, true}, {"a.txt", false, false}, {"a.log.txt", false, false},
+		}},
+		{"anchored by a leading slash", []string{"/build"}, nil, []check{
+			{"build", true, true}, {"build", false, true}, {"src/build", true, false},
+		}},
+		{"anchored by a middle slash", []string{"docs/*.md"}, nil, []check{
+			{"docs/a.md", false, true}, {"docs/sub/a.md", false, false}, {"src/docs/a.md", false, false},
+		}},
+		{"leading **", []string{"**/tmp"}, nil, []check{
+			{"tmp", true, true}, {"a/b/tmp", false, true}, {"a/tmpx", false, false},
+		}},
+		{"trailing **", []string{"out/**"}, nil, []check{
+			{"out/a", false, true}, {"out/a/b", false, true}, {"out", true, false}, {"src/out/a", false, false},
+		}},
+		{"middle **", []string{"a/**/z"}, nil, []check{
+			{"a/z", false, true}, {"a/b/z", false, true}, {"a/b/c/z", false, true}, {"b/a/z", false, false},
+		}},
+		{"negation overrides an earlier exclude", []string{"*.log", "!keep.log"}, nil, []check{
+			{"a.log", false, true}, {"keep.log", false, false}, {"dir/keep.log", false, false},
+		}},
+		{"a later exclude overrides a negation", []string{"!keep.log", "*.log"}, nil, []check{
+			{"keep.log", false, true},
+		}},
+		{"negation inside a directory matched by trailing **", []string{"out/**", "!out/keep"}, nil, []check{
+			{"out/a", false, true}, {"out/keep", false, false},
+		}},
+		{"dir-only", []string{"cache/"}, nil, []check{
+			{"cache", true, true}, {"cache", false, false}, {"a/cache", true, true},
+		}},
+		{"files under an excluded directory", []string{"build/", "!build/keep"}, nil, []check{
+			{"build/x.o", false, true}, {"build/sub/y.o", false, true}, {"build/keep", false, true}, {"src/x.o", false, false},
+		}},
+		{"includes come after every exclude", []string{"*.txt", "!notes.txt"}, []string{"important.txt"}, []check{
+			{"a.txt", false, true}, {"important.txt", false, false}, {"notes.txt", false, false},
+		}},
+		{"comments and escapes", []string{"# not a pattern", "\\#literal", "\\!bang", ""}, nil, []check{
+			{"# not a pattern", false, false}, {"#literal", false, true}, {"!bang", false, true},
+		}},
+	}
+
+	for _, test := range tests {
+		rules := NewIgnoreRules(test.excludes, test.includes)
+		for _, check := range test.checks {
+			if ignored := rules.Ignored(check.filename, check.isDir); ignored != check.ignored {
+				t.Errorf("%s: %v ignores %s (directory %v): %v, want %v", test.name, test.excludes, check.filename, check.isDir, ignored, check.ignored)
+			}
+		}
+	}
+}
+
+func TestIgnoreFilesApplyBelowTheirDirectory(t *testing.T) {
+	baseDir := t.TempDir()
+	writeTestFiles(t, baseDir, map[string]string{
+		IGNORE_FILENAME:          "*.tmp\n",
+		"sub/" + IGNORE_FILENAME: "/local\n!keep.tmp\n",
+	})
+
+	rules := NewIgnoreRules(nil, nil)
+	for _, dir := range []string{"", "sub", "missing"} {
+		if err := rules.LoadIgnoreFile(baseDir, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for filename, ignored := range map[string]bool{
+		"a.tmp":         true,
+		"sub/a.tmp":     true,
+		"sub/keep.tmp":  false,
+		"keep.tmp":      true,
+		"sub/local":     true,
+		"local":         false,
+		"sub/dir/local": false,
+	} {
+		if rules.Ignored(filename, false) != ignored {
+			t.Errorf("%s ignored: %v, want %v", filename, !ignored, ignored)
+		}
+	}
+
+	if err := os.Chmod(filepath.Join(baseDir, "sub", IGNORE_FILENAME), 0); err == nil && os.Getuid() != 0 {
+		if err := NewIgnoreRules(nil, nil).LoadIgnoreFile(baseDir, "sub"); err == nil {
+			t.Error("unreadable ignore file loaded")
+		}
+	}
+}
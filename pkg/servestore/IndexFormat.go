@@ -0,0 +1,381 @@
+package servestore
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrCorruptIndex is returned when the local metadata file cannot be parsed, or does not
+// match its checksum. Removing the file makes the next sync rebuild it by rehashing every
+// local file.
+var ErrCorruptIndex = errors.New("ErrCorruptIndex")
+
+// The local metadata file starts with a header line holding the format version:
+//
+//	servestore-index 2
+//
+// followed by one record per file, and ends with the SHA-256 checksum of everything before it:
+//
+//	checksum <hex>
+//
+// A record holds the fields of the legacy format (filename, version, hashes, size, mode,
+// modification time, extended attributes, symbolic and hard link targets), except that the
+// filename is escaped and the hashes have no trailing delimiter, optionally followed by the
+// file's stat cache. Files without a header are in the legacy format and are migrated to the
+// current format when the index is next written.
+const INDEX_HEADER string = "servestore-index"
+const INDEX_CHECKSUM string = "checksum"
+const INDEX_FORMAT_VERSION int = 2
+const LEGACY_INDEX_FORMAT_VERSION int = 1
+
+// Number of fields of a record, without and with the stat cache
+const INDEX_RECORD_FIELDS int = HARDLINK_TARGET_INDEX + 1
+const INDEX_RECORD_STAT_FIELDS int = STAT_INO_INDEX + 1
+
+// ParseLocalIndex parses a local metadata file in the current or the legacy format. Malformed
+// input is rejected with an error wrapping ErrCorruptIndex.
+func ParseLocalIndex(r io.Reader) (*LocalIndex, error) {
+	localIndex := &LocalIndex{
+		Files: make(map[string]*FileMetaData),
+		Stats: make(map[string]*FileStat),
+	}
+
+	reader := bufio.NewReader(r)
+	checksum := sha256.New()
+
+	line, err := readIndexLine(reader)
+	if err == io.EOF {
+		localIndex.FormatVersion = INDEX_FORMAT_VERSION
+		return localIndex, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Files without a header are in the legacy format
+	header, formatVersion, isHeader := cut(line, HASH_DELIMITER)
+	if header != INDEX_HEADER || !isHeader {
+		localIndex.FormatVersion = LEGACY_INDEX_FORMAT_VERSION
+		for ; err == nil; line, err = readIndexLine(reader) {
+			if line == "" {
+				continue
+			}
+			if err := localIndex.addRecord(line, true); err != nil {
+				return nil, err
+			}
+		}
+		if err != io.EOF {
+			return nil, err
+		}
+		return localIndex, nil
+	}
+
+	localIndex.FormatVersion, err = strconv.Atoi(formatVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid format version %q", ErrCorruptIndex, formatVersion)
+	}
+	if localIndex.FormatVersion != INDEX_FORMAT_VERSION {
+		return nil, fmt.Errorf("unsupported local index format version %d", localIndex.FormatVersion)
+	}
+	checksum.Write([]byte(line + "\n"))
+
+	for {
+		line, err := readIndexLine(reader)
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: missing checksum", ErrCorruptIndex)
+		} else if err != nil {
+			return nil, err
+		}
+
+		if field, expected, isChecksum := cut(line, HASH_DELIMITER); field == INDEX_CHECKSUM && isChecksum {
+			if expected != hex.EncodeToString(checksum.Sum(nil)) {
+				return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptIndex)
+			}
+			break
+		}
+
+		if err := localIndex.addRecord(line, false); err != nil {
+			return nil, err
+		}
+		checksum.Write([]byte(line + "\n"))
+	}
+
+	if _, err := readIndexLine(reader); err != io.EOF {
+		return nil, fmt.Errorf("%w: data after checksum", ErrCorruptIndex)
+	}
+
+	return localIndex, nil
+}
+
+// EncodeLocalIndex writes the local index in the current format, ordered by filename
+func EncodeLocalIndex(w io.Writer, localIndex *LocalIndex) error {
+	checksum := sha256.New()
+	out := io.MultiWriter(w, checksum)
+
+	if _, err := fmt.Fprintf(out, "%s %d\n", INDEX_HEADER, INDEX_FORMAT_VERSION); err != nil {
+		return err
+	}
+
+	for _, filename := range sortedFilenames(localIndex.Files) {
+		record := encodeIndexRecord(localIndex.Files[filename], localIndex.Stats[filename])
+		if _, err := io.WriteString(out, record+"\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s %s\n", INDEX_CHECKSUM, hex.EncodeToString(checksum.Sum(nil)))
+	return err
+}
+
+// readIndexLine reads the next line, returning io.EOF at the end of the input. A final
+// line without a newline is returned as a line.
+func readIndexLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+func (l *LocalIndex) addRecord(record string, legacy bool) error {
+	fileMetaData, fileStat, err := parseIndexRecord(record, legacy)
+	if err != nil {
+		return err
+	}
+
+	filename := fileMetaData.GetFilename()
+	if _, exists := l.Files[filename]; exists {
+		return fmt.Errorf("%w: duplicate file %q", ErrCorruptIndex, filename)
+	}
+	l.Files[filename] = fileMetaData
+	if fileStat != nil {
+		l.Stats[filename] = fileStat
+	}
+	return nil
+}
+
+// encodeIndexRecord encodes the local index entry of a file, and its stat cache if not nil
+func encodeIndexRecord(fileMetaData *FileMetaData, fileStat *FileStat) string {
+	fields := []string{
+		escapeIndexField(fileMetaData.GetFilename()),
+		strconv.FormatInt(int64(fileMetaData.GetVersion()), 10),
+		strings.Join(fileMetaData.GetBlockHashList(), HASH_DELIMITER),
+		strconv.FormatInt(fileMetaData.GetSize(), 10),
+		strconv.FormatUint(uint64(fileMetaData.GetMode()), 8),
+		strconv.FormatInt(fileMetaData.GetMtime(), 10),
+		xattrsToConfig(fileMetaData.GetXattrs()),
+		hex.EncodeToString([]byte(fileMetaData.GetSymlinkTarget())),
+		hex.EncodeToString([]byte(fileMetaData.GetHardlinkTarget())),
+	}
+
+	if fileStat != nil {
+		fields = append(fields,
+			strconv.FormatInt(fileStat.Size, 10),
+			strconv.FormatInt(fileStat.Mtime, 10),
+			strconv.FormatInt(fileStat.Ctime, 10),
+			strconv.FormatUint(fileStat.Ino, 10),
+		)
+	}
+
+	return strings.Join(fields, CONFIG_DELIMITER)
+}
+
+// parseIndexRecord parses the local index entry of a file and its stat cache, which is nil
+// if the record has none. Legacy records may have been written before file sizes, attributes,
+// links or stat caches were recorded, and have an unescaped filename that may contain commas.
+func parseIndexRecord(record string, legacy bool) (*FileMetaData, *FileStat, error) {
+	fields := strings.Split(record, CONFIG_DELIMITER)
+
+	if !legacy {
+		if len(fields) != INDEX_RECORD_FIELDS && len(fields) != INDEX_RECORD_STAT_FIELDS {
+			return nil, nil, fmt.Errorf("%w: record has %d fields", ErrCorruptIndex, len(fields))
+		}
+		filename, err := unescapeIndexField(fields[FILENAME_INDEX])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrCorruptIndex, err)
+		}
+		return parseIndexFields(filename, fields)
+	}
+
+	// Try each legacy layout, longest first, taking any extra leading fields as part of the filename
+	var err error
+	for _, count := range []int{INDEX_RECORD_STAT_FIELDS, INDEX_RECORD_FIELDS, XATTRS_INDEX + 1, HASH_LIST_INDEX + 1} {
+		if len(fields) < count {
+			continue
+		}
+
+		extra := len(fields) - count
+		filename := strings.Join(fields[:extra+1], CONFIG_DELIMITER)
+		var fileMetaData *FileMetaData
+		var fileStat *FileStat
+		fileMetaData, fileStat, err = parseIndexFields(filename, fields[extra:])
+		if err == nil {
+			return fileMetaData, fileStat, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("%w: record has %d fields", ErrCorruptIndex, len(fields))
+	}
+	return nil, nil, err
+}
+
+// parseIndexFields parses the fields of a record after its filename
+func parseIndexFields(filename string, fields []string) (*FileMetaData, *FileStat, error) {
+	if filename == "" {
+		return nil, nil, fmt.Errorf("%w: record has no filename", ErrCorruptIndex)
+	}
+
+	p := &recordParser{fields: fields}
+	fileMetaData := &FileMetaData{Filename: filename}
+
+	fileMetaData.Version = int32(p.int(VERSION_INDEX, 32))
+	fileMetaData.BlockHashList = p.hashes(HASH_LIST_INDEX)
+
+	if len(fields) > XATTRS_INDEX {
+		fileMetaData.Size = p.int(SIZE_INDEX, 64)
+		fileMetaData.Mode = uint32(p.uint(MODE_INDEX, 8, 32))
+		fileMetaData.Mtime = p.int(MTIME_INDEX, 64)
+		fileMetaData.Xattrs = p.xattrs(XATTRS_INDEX)
+	}
+
+	if len(fields) > HARDLINK_TARGET_INDEX {
+		fileMetaData.SymlinkTarget = string(p.hex(SYMLINK_TARGET_INDEX))
+		fileMetaData.HardlinkTarget = string(p.hex(HARDLINK_TARGET_INDEX))
+	}
+
+	var fileStat *FileStat
+	if len(fields) > STAT_INO_INDEX {
+		fileStat = &FileStat{
+			Size:  p.int(STAT_SIZE_INDEX, 64),
+			Mtime: p.int(STAT_MTIME_INDEX, 64),
+			Ctime: p.int(STAT_CTIME_INDEX, 64),
+			Ino:   p.uint(STAT_INO_INDEX, 10, 64),
+		}
+	}
+
+	if p.err != nil {
+		return nil, nil, fmt.Errorf("%w: file %q: %v", ErrCorruptIndex, fileMetaData.Filename, p.err)
+	}
+	return fileMetaData, fileStat, nil
+}
+
+// recordParser parses the fields of a record, keeping the first error
+type recordParser struct {
+	fields []string
+	err    error
+}
+
+func (p *recordParser) fail(field int, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("field %d: %w", field, err)
+	}
+}
+
+func (p *recordParser) int(field int, bitSize int) int64 {
+	value, err := strconv.ParseInt(p.fields[field], 10, bitSize)
+	if err != nil {
+		p.fail(field, err)
+	}
+	return value
+}
+
+func (p *recordParser) uint(field int, base int, bitSize int) uint64 {
+	value, err := strconv.ParseUint(p.fields[field], base, bitSize)
+	if err != nil {
+		p.fail(field, err)
+	}
+	return value
+}
+
+func (p *recordParser) hex(field int) []byte {
+	value, err := hex.DecodeString(p.fields[field])
+	if err != nil {
+		p.fail(field, err)
+	}
+	return value
+}
+
+// hashes parses a list of block hashes, each either TOMBSTONE_HASH or a hex-encoded SHA-256 hash
+func (p *recordParser) hashes(field int) []string {
+	hashes := strings.Fields(p.fields[field])
+	for _, hash := range hashes {
+		if hash == TOMBSTONE_HASH {
+			continue
+		}
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size || hash != strings.ToLower(hash) {
+			p.fail(field, fmt.Errorf("invalid block hash %q", hash))
+		}
+	}
+	return hashes
+}
+
+func (p *recordParser) xattrs(field int) map[string][]byte {
+	if p.fields[field] == "" {
+		return nil
+	}
+
+	xattrs := make(map[string][]byte)
+	for _, pair := range strings.Split(p.fields[field], XATTR_DELIMITER) {
+		encodedName, encodedValue, valid := cut(pair, XATTR_VALUE_DELIMITER)
+		name, nameErr := hex.DecodeString(encodedName)
+		value, valueErr := hex.DecodeString(encodedValue)
+		if !valid || nameErr != nil || valueErr != nil {
+			p.fail(field, fmt.Errorf("invalid extended attribute %q", pair))
+			continue
+		}
+		xattrs[string(name)] = value
+	}
+	return xattrs
+}
+
+// escapeIndexField percent-encodes the bytes of s that cannot appear in a record field:
+// the delimiters, "%" itself, and control characters
+func escapeIndexField(s string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '%' || c == CONFIG_DELIMITER[0] || c == HASH_DELIMITER[0] || c < 0x20 || c == 0x7f {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		} else {
+			escaped.WriteByte(c)
+		}
+	}
+	return escaped.String()
+}
+
+// unescapeIndexField decodes a field encoded by escapeIndexField
+func unescapeIndexField(s string) (string, error) {
+	var unescaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '%' {
+			unescaped.WriteByte(c)
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		decoded, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		unescaped.WriteByte(decoded[0])
+		i += 2
+	}
+	return unescaped.String(), nil
+}
+
+func sortedFilenames(fileMetaMap map[string]*FileMetaData) []string {
+	filenames := make([]string, 0, len(fileMetaMap))
+	for filename := range fileMetaMap {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	return filenames
+}
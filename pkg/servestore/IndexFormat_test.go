@@ -0,0 +1,154 @@
+//go:build go1.18
+// +build go1.18
+
+package servestore
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const testHash string = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+func testLocalIndex() *LocalIndex {
+	return &LocalIndex{
+		Files: map[string]*FileMetaData{
+			"a.txt":           {Filename: "a.txt", Version: 2, BlockHashList: []string{testHash, testHash}, Size: 12, Mode: 0644, Mtime: 1700000000000000000},
+			"dir/with,comma":  {Filename: "dir/with,comma", Version: 1, BlockHashList: []string{}, Xattrs: map[string][]byte{"user.tag": []byte("x")}},
+			"new\nline %20":   {Filename: "new\nline %20", Version: 3, BlockHashList: []string{TOMBSTONE_HASH}},
+			"link":            {Filename: "link", Version: 1, BlockHashList: []string{testHash}, Size: 6, SymlinkTarget: "a,b c"},
+			"hard link, copy": {Filename: "hard link, copy", Version: 1, BlockHashList: []string{testHash}, HardlinkTarget: "a.txt"},
+		},
+		Stats: map[string]*FileStat{
+			"a.txt": {Size: 12, Mtime: 1700000000000000000, Ctime: 1700000000000000001, Ino: 42},
+		},
+	}
+}
+
+func assertEqualIndexes(t *testing.T, expected *LocalIndex, actual *LocalIndex) {
+	t.Helper()
+
+	if len(actual.Files) != len(expected.Files) || len(actual.Stats) != len(expected.Stats) {
+		t.Fatalf("got %d files and %d stats, want %d and %d", len(actual.Files), len(actual.Stats), len(expected.Files), len(expected.Stats))
+	}
+	for filename, fileMetaData := range expected.Files {
+		if !proto.Equal(actual.Files[filename], fileMetaData) {
+			t.Errorf("file %q: got %v, want %v", filename, actual.Files[filename], fileMetaData)
+		}
+	}
+	for filename, fileStat := range expected.Stats {
+		if actual.Stats[filename] == nil || *actual.Stats[filename] != *fileStat {
+			t.Errorf("stat %q: got %v, want %v", filename, actual.Stats[filename], fileStat)
+		}
+	}
+}
+
+func TestLocalIndexRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeLocalIndex(&buf, testLocalIndex()); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseLocalIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.FormatVersion != INDEX_FORMAT_VERSION {
+		t.Errorf("got format version %d, want %d", parsed.FormatVersion, INDEX_FORMAT_VERSION)
+	}
+	assertEqualIndexes(t, testLocalIndex(), parsed)
+}
+
+func TestLocalIndexMigratesLegacyFormat(t *testing.T) {
+	legacy := "a.txt,2," + testHash + " " + testHash + " \n" +
+		"b.txt,1,,0,644,1700000000000000000,,,\n" +
+		"c.txt,4," + testHash + " ,6,755,1700000000000000000,,,,6,1700000000000000000,1700000000000000001,42\n" +
+		"with,two,commas,1," + testHash + " ,6,644,1700000000000000000,,,\n"
+
+	parsed, err := ParseLocalIndex(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.FormatVersion != LEGACY_INDEX_FORMAT_VERSION {
+		t.Errorf("got format version %d, want %d", parsed.FormatVersion, LEGACY_INDEX_FORMAT_VERSION)
+	}
+
+	expected := &LocalIndex{
+		Files: map[string]*FileMetaData{
+			"a.txt":           {Filename: "a.txt", Version: 2, BlockHashList: []string{testHash, testHash}},
+			"b.txt":           {Filename: "b.txt", Version: 1, Mode: 0644, Mtime: 1700000000000000000},
+			"c.txt":           {Filename: "c.txt", Version: 4, BlockHashList: []string{testHash}, Size: 6, Mode: 0755, Mtime: 1700000000000000000},
+			"with,two,commas": {Filename: "with,two,commas", Version: 1, BlockHashList: []string{testHash}, Size: 6, Mode: 0644, Mtime: 1700000000000000000},
+		},
+		Stats: map[string]*FileStat{
+			"c.txt": {Size: 6, Mtime: 1700000000000000000, Ctime: 1700000000000000001, Ino: 42},
+		},
+	}
+	assertEqualIndexes(t, expected, parsed)
+
+	// The migrated index is written in the current format
+	var buf bytes.Buffer
+	if err := EncodeLocalIndex(&buf, parsed); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), INDEX_HEADER+" 2\n") {
+		t.Errorf("migrated index has no header: %q", buf.String())
+	}
+}
+
+func TestLocalIndexRejectsCorruption(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeLocalIndex(&buf, testLocalIndex()); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.String()
+
+	corruptions := map[string]string{
+		"flipped byte":      strings.Replace(encoded, "a.txt,2", "a.txt,3", 1),
+		"truncated":         encoded[:len(encoded)/2],
+		"missing checksum":  encoded[:strings.LastIndex(encoded, INDEX_CHECKSUM)],
+		"data after":        encoded + "extra\n",
+		"bad version":       strings.Replace(encoded, INDEX_HEADER+" 2", INDEX_HEADER+" x", 1),
+		"bad legacy record": "a.txt,notanumber,\n",
+		"bad legacy hash":   "a.txt,1,nothex \n",
+	}
+	for name, corrupted := range corruptions {
+		_, err := ParseLocalIndex(strings.NewReader(corrupted))
+		if !errors.Is(err, ErrCorruptIndex) {
+			t.Errorf("%s: got error %v, want ErrCorruptIndex", name, err)
+		}
+	}
+}
+
+func FuzzParseLocalIndex(f *testing.F) {
+	var buf bytes.Buffer
+	if err := EncodeLocalIndex(&buf, testLocalIndex()); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	f.Add([]byte("a.txt,2," + testHash + " \nb.txt,1,,0,644,1,,,\n"))
+	f.Add([]byte(INDEX_HEADER + " 2\nchecksum 00\n"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parsed, err := ParseLocalIndex(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		// Anything that parses is written back in the current format without losing data
+		var buf bytes.Buffer
+		if err := EncodeLocalIndex(&buf, parsed); err != nil {
+			t.Fatal(err)
+		}
+		reparsed, err := ParseLocalIndex(&buf)
+		if err != nil {
+			t.Fatalf("reparse of %q: %v", buf.String(), err)
+		}
+		assertEqualIndexes(t, parsed, reparsed)
+	})
+}
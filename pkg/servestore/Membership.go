@@ -0,0 +1,89 @@
+package servestore
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrUnknownBlockStore = errors.New("ErrUnknownBlockStore")
+var ErrNoLiveBlockStores = errors.New("ErrNoLiveBlockStores")
+
+// How often BlockStores send heartbeats to the MetaStore, and how long after its last
+// heartbeat a BlockStore is suspected to be down, and then declared dead. BlockStores
+// that never sent a heartbeat are always live.
+const HEARTBEAT_INTERVAL = time.Second
+const SUSPECT_TIMEOUT = 3 * time.Second
+const DEAD_TIMEOUT = 10 * time.Second
+
+// member is a BlockStore known to the MetaStore, either because it registered or because
+// it is listed in the cluster config
+type member struct {
+	addr          string
+	weight        int
+	lastHeartbeat time.Time // Zero until the BlockStore sends its first heartbeat
+	state         MemberState
+}
+
+// stateAt returns the state of the member at now
+func (m *member) stateAt(now time.Time, suspectTimeout time.Duration, deadTimeout time.Duration) MemberState {
+	if m.lastHeartbeat.IsZero() {
+		return MemberState_LIVE
+	}
+	return memberState(now.Sub(m.lastHeartbeat), suspectTimeout, deadTimeout)
+}
+
+// SendHeartbeats registers the BlockStore at addr with the MetaStore of client, and then
+// sends it a heartbeat every HEARTBEAT_INTERVAL until done is closed. The BlockStore
+// registers again whenever the MetaStore does not know it, e.g. after a restart.
+func SendHeartbeats(client ClientInterface, addr string, weight int, done <-chan struct{}) {
+	ticker := time.NewTicker(HEARTBEAT_INTERVAL)
+	defer ticker.Stop()
+
+	registered := false
+	var lastEpoch int64
+	for {
+		var epoch int64
+		var err error
+		if registered {
+			err = client.Heartbeat(addr, weight, &epoch)
+			if status.Code(err) == codes.NotFound {
+				log.Printf("MetaStore does not know BlockStore %s, registering again", addr)
+				registered = false
+			}
+		} else {
+			err = client.RegisterBlockStore(addr, weight, &epoch)
+			if err == nil {
+				log.Printf("Registered BlockStore %s with the MetaStore", addr)
+				registered = true
+			}
+		}
+		if err != nil {
+			log.Printf("Heartbeat of BlockStore %s: %v", addr, err)
+		} else if epoch != lastEpoch {
+			log.Printf("Ring epoch is %d", epoch)
+			lastEpoch = epoch
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// memberState returns the state of a member given the time since its last heartbeat
+func memberState(sinceHeartbeat time.Duration, suspectTimeout time.Duration, deadTimeout time.Duration) MemberState {
+	switch {
+	case sinceHeartbeat >= deadTimeout:
+		return MemberState_DEAD
+	case sinceHeartbeat >= suspectTimeout:
+		return MemberState_SUSPECT
+	default:
+		return MemberState_LIVE
+	}
+}
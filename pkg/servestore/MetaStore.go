@@ -2,48 +2,548 @@ package servestore
 
 import (
 	context "context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
+// How long to wait before retrying a rebalance that could not copy every block
+const REBALANCE_RETRY_INTERVAL = 30 * time.Second
+
+// Number of versions of each file kept in its history
+const MAX_FILE_VERSIONS = 100
+
 type MetaStore struct {
-	FileMetaMap    map[string]*FileMetaData
-	BlockStoreAddr string
+	FileMetaMap map[string]*FileMetaData
+
+	// Config is the cluster config the MetaStore was started with or last reloaded. Cluster
+	// is the BlockStores on the ring, those members of the cluster that are not dead, and
+	// PreviousCluster the BlockStores before they last changed, while blocks are being
+	// copied to the BlockStores that became their replicas.
+	// Guarded by clusterMutex.
+	Config          *ClusterConfig
+	Cluster         *ClusterConfig
+	PreviousCluster *ClusterConfig
+
+	// How long after its last heartbeat a BlockStore is suspected to be down, and declared dead
+	SuspectTimeout time.Duration
+	DeadTimeout    time.Duration
+
+	// Bytes of blocks copied per second at most while rebalancing, unlimited if zero
+	RebalanceBytesPerSecond int64
+
+	fileMutex      sync.Mutex
+	fileVersions   map[string][]*FileMetaData
+	clusterMutex   sync.Mutex
+	members        map[string]*member
+	epoch          int64
+	pendingCluster *ClusterConfig
+	rebalancing    bool
 	UnimplementedMetaStoreServer
 }
 
 func (m *MetaStore) GetFileInfoMap(ctx context.Context, empty *emptypb.Empty) (*FileInfoMap, error) {
-	return &FileInfoMap{FileInfoMap: m.FileMetaMap}, nil
+	m.fileMutex.Lock()
+	defer m.fileMutex.Unlock()
+
+	// Copy the map, which is marshalled after the lock is released
+	fileInfoMap := make(map[string]*FileMetaData, len(m.FileMetaMap))
+	for filename, fileMetaData := range m.FileMetaMap {
+		fileInfoMap[filename] = fileMetaData
+	}
+	return &FileInfoMap{FileInfoMap: fileInfoMap}, nil
 }
 
 func (m *MetaStore) UpdateFile(ctx context.Context, fileMetaData *FileMetaData) (*Version, error) {
-	// If the file exists in MetaStore already, update if possible
+	m.fileMutex.Lock()
+	defer m.fileMutex.Unlock()
+
 	var latestVersion int32 = -1
-	if metaStoreFileMetaData, exists := m.FileMetaMap[fileMetaData.GetFilename()]; exists {
-		// If `fileMetaData` version is 1 greater than MetaStore version, update MetaStore BlockHashList and Version
-		if fileMetaData.GetVersion() == metaStoreFileMetaData.GetVersion()+1 {
-			metaStoreFileMetaData.BlockHashList = fileMetaData.GetBlockHashList()
-			metaStoreFileMetaData.Version = fileMetaData.GetVersion()
-			latestVersion = metaStoreFileMetaData.GetVersion()
-		}
-	} else {
-		m.FileMetaMap[fileMetaData.GetFilename()] = &FileMetaData{Filename: fileMetaData.GetFilename(), Version: fileMetaData.GetVersion(), BlockHashList: fileMetaData.GetBlockHashList()}
+	if m.accepts(fileMetaData) {
+		m.updateFile(fileMetaData)
 		latestVersion = fileMetaData.GetVersion()
 	}
 
 	return &Version{Version: latestVersion}, nil
 }
 
+// UpdateFiles updates several files in a single transaction: if UpdateFile would accept the
+// version of every file, all of them are updated, and otherwise none is and the files whose
+// versions were rejected are returned with their current versions
+func (m *MetaStore) UpdateFiles(ctx context.Context, fileBatch *FileBatch) (*FileConflicts, error) {
+	m.fileMutex.Lock()
+	defer m.fileMutex.Unlock()
+
+	filenames := make(map[string]bool)
+	conflicts := &FileConflicts{}
+	for _, fileMetaData := range fileBatch.GetFiles() {
+		if filenames[fileMetaData.GetFilename()] {
+			return nil, status.Error(codes.InvalidArgument, "ErrDuplicateFile")
+		}
+		filenames[fileMetaData.GetFilename()] = true
+
+		if !m.accepts(fileMetaData) {
+			conflicts.Conflicts = append(conflicts.Conflicts, &FileConflict{
+				Filename:      fileMetaData.GetFilename(),
+				Version:       fileMetaData.GetVersion(),
+				LatestVersion: m.FileMetaMap[fileMetaData.GetFilename()].GetVersion(),
+			})
+		}
+	}
+
+	if len(conflicts.GetConflicts()) == 0 {
+		for _, fileMetaData := range fileBatch.GetFiles() {
+			m.updateFile(fileMetaData)
+		}
+	}
+	return conflicts, nil
+}
+
+// accepts reports whether `fileMetaData` can update its file: new files are accepted at any
+// version, and existing files at the version following theirs. The caller must hold fileMutex.
+func (m *MetaStore) accepts(fileMetaData *FileMetaData) bool {
+	metaStoreFileMetaData, exists := m.FileMetaMap[fileMetaData.GetFilename()]
+	return !exists || fileMetaData.GetVersion() == metaStoreFileMetaData.GetVersion()+1
+}
+
+// updateFile stores an accepted update of a file. The caller must hold fileMutex.
+func (m *MetaStore) updateFile(fileMetaData *FileMetaData) {
+	// Only RenameFile records renames
+	updated := proto.Clone(fileMetaData).(*FileMetaData)
+	updated.RenamedFrom, updated.RenamedTo = "", ""
+	m.putFile(updated)
+}
+
+// RenameFile renames the file oldFilename at oldVersion to the name and version of
+// fileRename.FileMetaData in a single update. The old name becomes a tombstone recording the
+// new name, and the new name a version with the blocks of the old file recording the old
+// name, so other clients can rename the file locally instead of downloading it again. Like
+// UpdateFile, it returns version -1 if either file has been updated by another client.
+func (m *MetaStore) RenameFile(ctx context.Context, fileRename *FileRename) (*Version, error) {
+	m.fileMutex.Lock()
+	defer m.fileMutex.Unlock()
+
+	oldFilename, fileMetaData := fileRename.GetOldFilename(), fileRename.GetFileMetaData()
+	if fileMetaData.GetFilename() == "" || fileMetaData.GetFilename() == oldFilename {
+		return nil, status.Error(codes.InvalidArgument, "ErrInvalidRename")
+	}
+
+	oldFileMetaData, exists := m.FileMetaMap[oldFilename]
+	if !exists || isTombstone(oldFileMetaData) || oldFileMetaData.GetVersion() != fileRename.GetOldVersion() {
+		return &Version{Version: -1}, nil
+	}
+	if !equalHashLists(oldFileMetaData.GetBlockHashList(), fileMetaData.GetBlockHashList()) {
+		return nil, status.Error(codes.InvalidArgument, "ErrInvalidRename")
+	}
+	if current, exists := m.FileMetaMap[fileMetaData.GetFilename()]; exists && fileMetaData.GetVersion() != current.GetVersion()+1 {
+		return &Version{Version: -1}, nil
+	}
+
+	renamed := proto.Clone(fileMetaData).(*FileMetaData)
+	renamed.RenamedFrom, renamed.RenamedTo = oldFilename, ""
+	m.putFile(renamed)
+	m.putFile(&FileMetaData{Filename: oldFilename, Version: oldFileMetaData.GetVersion() + 1, BlockHashList: []string{TOMBSTONE_HASH}, RenamedTo: renamed.GetFilename()})
+
+	return &Version{Version: renamed.GetVersion()}, nil
+}
+
+// putFile stores an accepted version of a file and records it in the file's history.
+// The caller must hold fileMutex.
+func (m *MetaStore) putFile(fileMetaData *FileMetaData) {
+	m.FileMetaMap[fileMetaData.GetFilename()] = fileMetaData
+
+	versions := append(m.fileVersions[fileMetaData.GetFilename()], fileMetaData)
+	if len(versions) > MAX_FILE_VERSIONS {
+		versions = versions[len(versions)-MAX_FILE_VERSIONS:]
+	}
+	m.fileVersions[fileMetaData.GetFilename()] = versions
+}
+
+// GetFileVersions returns the last MAX_FILE_VERSIONS versions of a file, oldest first
+func (m *MetaStore) GetFileVersions(ctx context.Context, fileName *FileName) (*FileVersions, error) {
+	m.fileMutex.Lock()
+	defer m.fileMutex.Unlock()
+
+	versions, exists := m.fileVersions[fileName.GetFilename()]
+	if !exists {
+		// Files put in FileMetaMap directly have no history
+		fileMetaData, exists := m.FileMetaMap[fileName.GetFilename()]
+		if !exists {
+			return nil, status.Error(codes.NotFound, "ErrFileNotFound")
+		}
+		versions = []*FileMetaData{fileMetaData}
+	}
+	return &FileVersions{Versions: append([]*FileMetaData{}, versions...)}, nil
+}
+
+// GetBlockStoreAddr returns the first live BlockStore on the ring, for clients that do not
+// know about the hash ring. BlockStores that left the cluster stay on the ring, without
+// being members, until the blocks are rebalanced.
 func (m *MetaStore) GetBlockStoreAddr(ctx context.Context, empty *emptypb.Empty) (*BlockStoreAddr, error) {
-	return &BlockStoreAddr{Addr: m.BlockStoreAddr}, nil
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+	m.updateMembership(time.Now())
+
+	addrs := m.Cluster.BlockStoreAddrs
+	if len(addrs) == 0 {
+		return nil, status.Error(codes.Unavailable, ErrNoLiveBlockStores.Error())
+	}
+	for _, addr := range addrs {
+		if member, ok := m.members[addr]; ok && member.state == MemberState_LIVE {
+			return &BlockStoreAddr{Addr: addr}, nil
+		}
+	}
+	return &BlockStoreAddr{Addr: addrs[0]}, nil
+}
+
+// GetBlockStoreRing returns the BlockStores, and while blocks are being rebalanced
+// the previous BlockStores, which still serve the blocks that have not been copied yet
+func (m *MetaStore) GetBlockStoreRing(ctx context.Context, empty *emptypb.Empty) (*BlockStoreRing, error) {
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+	m.updateMembership(time.Now())
+
+	if len(m.Cluster.BlockStoreAddrs) == 0 {
+		return nil, status.Error(codes.Unavailable, ErrNoLiveBlockStores.Error())
+	}
+	ring := m.Cluster.BlockStoreRing()
+	ring.Epoch = m.epoch
+	for _, addr := range m.Cluster.BlockStoreAddrs {
+		if member, ok := m.members[addr]; ok && member.state == MemberState_SUSPECT {
+			ring.SuspectAddrs = append(ring.SuspectAddrs, addr)
+		}
+	}
+	if m.PreviousCluster != nil {
+		ring.Previous = m.PreviousCluster.BlockStoreRing()
+	}
+	return ring, nil
+}
+
+// RegisterBlockStore adds a BlockStore to the cluster, or marks it live again. If the
+// cluster config lists BlockStores, only those can register.
+func (m *MetaStore) RegisterBlockStore(ctx context.Context, heartbeat *BlockStoreHeartbeat) (*RingEpoch, error) {
+	if heartbeat.GetAddr() == "" {
+		return nil, errors.New("ErrNilBlockStoreAddr")
+	}
+
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+
+	if !m.admits(heartbeat.GetAddr()) {
+		return nil, status.Error(codes.PermissionDenied, ErrUnknownBlockStore.Error())
+	}
+	if _, ok := m.members[heartbeat.GetAddr()]; !ok {
+		m.members[heartbeat.GetAddr()] = &member{addr: heartbeat.GetAddr()}
+	}
+	log.Println("BlockStore", heartbeat.GetAddr(), "registered")
+	m.heartbeat(heartbeat)
+	return &RingEpoch{Epoch: m.epoch}, nil
+}
+
+// Heartbeat marks a registered BlockStore live
+func (m *MetaStore) Heartbeat(ctx context.Context, heartbeat *BlockStoreHeartbeat) (*RingEpoch, error) {
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+
+	if _, ok := m.members[heartbeat.GetAddr()]; !ok {
+		return nil, status.Error(codes.NotFound, ErrUnknownBlockStore.Error())
+	}
+	m.heartbeat(heartbeat)
+	return &RingEpoch{Epoch: m.epoch}, nil
+}
+
+// GetMembership returns the state of every BlockStore of the cluster and the ring epoch
+func (m *MetaStore) GetMembership(ctx context.Context, empty *emptypb.Empty) (*Membership, error) {
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+	m.updateMembership(time.Now())
+
+	onRing := make(map[string]bool)
+	for _, addr := range m.Cluster.BlockStoreAddrs {
+		onRing[addr] = true
+	}
+
+	membership := &Membership{Epoch: m.epoch}
+	for _, addr := range m.memberAddrs() {
+		var lastHeartbeat int64
+		if !m.members[addr].lastHeartbeat.IsZero() {
+			lastHeartbeat = m.members[addr].lastHeartbeat.UnixNano()
+		}
+		membership.Members = append(membership.Members, &BlockStoreMember{
+			Addr:          addr,
+			State:         m.members[addr].state,
+			LastHeartbeat: lastHeartbeat,
+			Weight:        int32(m.memberWeight(addr)),
+			OnRing:        onRing[addr],
+		})
+	}
+	return membership, nil
+}
+
+// GetRingEpoch returns the ring epoch, which changes whenever the BlockStores on the ring do
+func (m *MetaStore) GetRingEpoch(ctx context.Context, empty *emptypb.Empty) (*RingEpoch, error) {
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+	m.updateMembership(time.Now())
+	return &RingEpoch{Epoch: m.epoch}, nil
+}
+
+// MonitorMembership declares BlockStores suspect or dead as their heartbeats stop, and
+// takes dead BlockStores off the ring, until done is closed
+func (m *MetaStore) MonitorMembership(done <-chan struct{}) {
+	ticker := time.NewTicker(HEARTBEAT_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			m.clusterMutex.Lock()
+			m.updateMembership(now)
+			m.clusterMutex.Unlock()
+		}
+	}
+}
+
+// UpdateCluster changes the cluster config. BlockStores it lists join the cluster, and
+// BlockStores it no longer lists leave it.
+func (m *MetaStore) UpdateCluster(cluster *ClusterConfig) {
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+
+	if proto.Equal(cluster.BlockStoreRing(), m.Config.BlockStoreRing()) {
+		log.Println("The cluster is unchanged")
+		return
+	}
+	m.Config = cluster
+	m.addConfigMembers()
+	m.updateMembership(time.Now())
+}
+
+// Rebalancing reports whether blocks are being copied after the cluster changed
+func (m *MetaStore) Rebalancing() bool {
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+	return m.rebalancing
+}
+
+// heartbeat records a heartbeat of a member. Guarded by clusterMutex.
+func (m *MetaStore) heartbeat(heartbeat *BlockStoreHeartbeat) {
+	now := time.Now()
+	registered := m.members[heartbeat.GetAddr()]
+	registered.weight = int(heartbeat.GetWeight())
+	registered.lastHeartbeat = now
+	m.updateMembership(now)
+}
+
+// admits reports whether the BlockStore at addr can join the cluster
+func (m *MetaStore) admits(addr string) bool {
+	if len(m.Config.BlockStoreAddrs) == 0 {
+		return true
+	}
+	for _, configAddr := range m.Config.BlockStoreAddrs {
+		if configAddr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// addConfigMembers adds the BlockStores listed in the cluster config as members that have
+// not sent a heartbeat yet, and removes the members it does not admit. Guarded by clusterMutex.
+func (m *MetaStore) addConfigMembers() {
+	for _, addr := range m.Config.BlockStoreAddrs {
+		if _, ok := m.members[addr]; !ok {
+			m.members[addr] = &member{addr: addr}
+		}
+	}
+	for addr := range m.members {
+		if !m.admits(addr) {
+			log.Println("BlockStore", addr, "left the cluster")
+			delete(m.members, addr)
+		}
+	}
+}
+
+// memberAddrs returns the addresses of the members in the order of the cluster config,
+// or sorted if it lists none. Guarded by clusterMutex.
+func (m *MetaStore) memberAddrs() []string {
+	if len(m.Config.BlockStoreAddrs) > 0 {
+		return m.Config.BlockStoreAddrs
+	}
+	addrs := make([]string, 0, len(m.members))
+	for addr := range m.members {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// memberWeight returns the weight of a member, as set by the cluster config, or else as
+// sent in its heartbeats. Guarded by clusterMutex.
+func (m *MetaStore) memberWeight(addr string) int {
+	if weight, ok := m.Config.Weights[addr]; ok {
+		return weight
+	}
+	if m.members[addr].weight > 0 {
+		return m.members[addr].weight
+	}
+	return 1
+}
+
+// memberDead reports whether the BlockStore at addr is a dead member of the cluster
+func (m *MetaStore) memberDead(addr string) bool {
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+
+	member, ok := m.members[addr]
+	return ok && member.stateAt(time.Now(), m.SuspectTimeout, m.DeadTimeout) == MemberState_DEAD
+}
+
+// updateMembership updates the state of every member from its last heartbeat, and puts
+// the members that are not dead on the ring. Guarded by clusterMutex.
+func (m *MetaStore) updateMembership(now time.Time) {
+	for _, addr := range m.memberAddrs() {
+		member := m.members[addr]
+		state := member.stateAt(now, m.SuspectTimeout, m.DeadTimeout)
+		if state != member.state {
+			log.Println("BlockStore", addr, "is", state)
+			member.state = state
+		}
+	}
+	m.setCluster(m.liveCluster())
+}
+
+// liveCluster returns the cluster config of the members that are not dead. While fewer
+// of them are live than the replication factor, blocks are stored on all of them.
+// Guarded by clusterMutex.
+func (m *MetaStore) liveCluster() *ClusterConfig {
+	cluster := &ClusterConfig{
+		BlockStoreAddrs:   make([]string, 0),
+		VirtualNodes:      m.Config.VirtualNodes,
+		Weights:           make(map[string]int),
+		ReplicationFactor: m.Config.ReplicationFactor,
+		WriteQuorum:       m.Config.WriteQuorum,
+		ReadQuorum:        m.Config.ReadQuorum,
+	}
+	for _, addr := range m.memberAddrs() {
+		if m.members[addr].state == MemberState_DEAD {
+			continue
+		}
+		cluster.BlockStoreAddrs = append(cluster.BlockStoreAddrs, addr)
+		if weight := m.memberWeight(addr); weight != 1 {
+			cluster.Weights[addr] = weight
+		}
+	}
+
+	if live := len(cluster.BlockStoreAddrs); live > 0 && cluster.Replicas() > live {
+		cluster.ReplicationFactor = live
+		if cluster.WriteQuorum > live {
+			cluster.WriteQuorum = live
+		}
+		if cluster.ReadQuorum > live {
+			cluster.ReadQuorum = live
+		}
+	}
+	return cluster
+}
+
+// setCluster changes the BlockStores on the ring, and copies the blocks to the BlockStores
+// that became their replicas in the background. Clients read blocks that have not been
+// copied yet from their previous replicas. A change made while blocks are being copied is
+// applied once they all are. Guarded by clusterMutex.
+func (m *MetaStore) setCluster(cluster *ClusterConfig) {
+	ring := cluster.BlockStoreRing()
+	if m.rebalancing {
+		if !proto.Equal(ring, m.Cluster.BlockStoreRing()) &&
+			(m.pendingCluster == nil || !proto.Equal(ring, m.pendingCluster.BlockStoreRing())) {
+			log.Println("Blocks are being rebalanced, the cluster will change once they are")
+		}
+		m.pendingCluster = cluster
+		return
+	}
+	if m.Cluster != nil && proto.Equal(ring, m.Cluster.BlockStoreRing()) {
+		return
+	}
+
+	m.PreviousCluster, m.Cluster = m.Cluster, cluster
+	m.epoch++
+	log.Printf("Ring epoch %d: BlockStores %v", m.epoch, cluster.BlockStoreAddrs)
+
+	// There is nothing to copy blocks from or to
+	if m.PreviousCluster == nil || len(m.PreviousCluster.BlockStoreAddrs) == 0 || len(cluster.BlockStoreAddrs) == 0 {
+		m.PreviousCluster = nil
+		return
+	}
+	m.rebalancing = true
+	go m.rebalance()
+}
+
+// rebalance copies blocks from the previous to the current BlockStores until every block
+// is copied, and then applies the pending cluster change, if any. Dead BlockStores are
+// neither copied from nor to.
+func (m *MetaStore) rebalance() {
+	for {
+		m.clusterMutex.Lock()
+		rebalancer := &Rebalancer{
+			Client:         &RPCClient{},
+			From:           NewBlockPlacement(m.PreviousCluster),
+			To:             NewBlockPlacement(m.Cluster),
+			BytesPerSecond: m.RebalanceBytesPerSecond,
+			Unavailable:    m.memberDead,
+		}
+		m.clusterMutex.Unlock()
+
+		progress, err := rebalancer.Run()
+		if err != nil {
+			log.Printf("Rebalance: %v, retrying in %v", err, REBALANCE_RETRY_INTERVAL)
+			time.Sleep(REBALANCE_RETRY_INTERVAL)
+			continue
+		}
+		log.Println("Rebalance complete:", progress)
+
+		m.clusterMutex.Lock()
+		pendingCluster := m.pendingCluster
+		m.PreviousCluster, m.pendingCluster = nil, nil
+		m.rebalancing = false
+		if pendingCluster != nil {
+			m.setCluster(pendingCluster)
+		}
+		m.clusterMutex.Unlock()
+		return
+	}
 }
 
 // This line guarantees all method for MetaStore are implemented
 var _ MetaStoreInterface = new(MetaStore)
 
 func NewMetaStore(blockStoreAddr string) *MetaStore {
-	return &MetaStore{
+	return NewMetaStoreFromConfig(NewClusterConfig([]string{blockStoreAddr}))
+}
+
+// NewMetaStoreFromConfig returns a MetaStore for the BlockStores of the cluster, which may
+// be nil for a cluster that any BlockStore can join
+func NewMetaStoreFromConfig(cluster *ClusterConfig) *MetaStore {
+	if cluster == nil {
+		cluster = NewClusterConfig(nil)
+	}
+	m := &MetaStore{
 		FileMetaMap:    map[string]*FileMetaData{},
-		BlockStoreAddr: blockStoreAddr,
+		fileVersions:   make(map[string][]*FileMetaData),
+		Config:         cluster,
+		SuspectTimeout: SUSPECT_TIMEOUT,
+		DeadTimeout:    DEAD_TIMEOUT,
+		members:        make(map[string]*member),
 	}
+	m.addConfigMembers()
+	m.updateMembership(time.Now())
+	return m
 }
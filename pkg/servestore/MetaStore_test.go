@@ -0,0 +1,309 @@
+package servestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ageHeartbeat moves the last heartbeat of a member age into the past
+func ageHeartbeat(m *MetaStore, addr string, age time.Duration) {
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+	m.members[addr].lastHeartbeat = time.Now().Add(-age)
+}
+
+func memberStates(t *testing.T, m *MetaStore) (map[string]MemberState, int64) {
+	membership, err := m.GetMembership(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := make(map[string]MemberState)
+	for _, member := range membership.GetMembers() {
+		states[member.GetAddr()] = member.GetState()
+	}
+	return states, membership.GetEpoch()
+}
+
+func TestRegisteredBlockStoresJoinTheRing(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetaStoreFromConfig(nil)
+
+	if _, err := m.GetBlockStoreRing(ctx, &emptypb.Empty{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("ring of an empty cluster: %v, want Unavailable", err)
+	}
+	if _, err := m.Heartbeat(ctx, &BlockStoreHeartbeat{Addr: "a:1"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("heartbeat before registering: %v, want NotFound", err)
+	}
+
+	first, err := m.RegisterBlockStore(ctx, &BlockStoreHeartbeat{Addr: "b:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.RegisterBlockStore(ctx, &BlockStoreHeartbeat{Addr: "a:1", Weight: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.GetEpoch() <= first.GetEpoch() {
+		t.Errorf("epoch %d after a BlockStore joined, was %d", second.GetEpoch(), first.GetEpoch())
+	}
+
+	ring, err := m.GetBlockStoreRing(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ring.GetAddrs()) != 2 || ring.GetAddrs()[0] != "a:1" || ring.GetAddrs()[1] != "b:1" {
+		t.Errorf("ring %v, want [a:1 b:1]", ring.GetAddrs())
+	}
+	if ring.GetWeights()["a:1"] != 2 {
+		t.Errorf("weight of a:1 is %d, want 2", ring.GetWeights()["a:1"])
+	}
+	if ring.GetEpoch() != second.GetEpoch() {
+		t.Errorf("ring epoch %d, want %d", ring.GetEpoch(), second.GetEpoch())
+	}
+}
+
+func TestMissedHeartbeatsMakeBlockStoresSuspectAndDead(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetaStoreFromConfig(NewClusterConfig([]string{"a:1", "b:1", "c:1"}))
+
+	states, epoch := memberStates(t, m)
+	for _, addr := range []string{"a:1", "b:1", "c:1"} {
+		if states[addr] != MemberState_LIVE {
+			t.Errorf("%s is %v at startup, want LIVE", addr, states[addr])
+		}
+	}
+
+	ageHeartbeat(m, "a:1", m.SuspectTimeout)
+	ageHeartbeat(m, "b:1", m.DeadTimeout)
+	states, deadEpoch := memberStates(t, m)
+	if states["a:1"] != MemberState_SUSPECT || states["b:1"] != MemberState_DEAD || states["c:1"] != MemberState_LIVE {
+		t.Fatalf("states %v, want a:1 SUSPECT, b:1 DEAD, c:1 LIVE", states)
+	}
+	if deadEpoch == epoch {
+		t.Errorf("epoch unchanged after a BlockStore died")
+	}
+
+	// Suspect BlockStores stay on the ring, dead ones leave it
+	ring, err := m.GetBlockStoreRing(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ring.GetAddrs()) != 2 || ring.GetAddrs()[0] != "a:1" || ring.GetAddrs()[1] != "c:1" {
+		t.Errorf("ring %v, want [a:1 c:1]", ring.GetAddrs())
+	}
+	if len(ring.GetSuspectAddrs()) != 1 || ring.GetSuspectAddrs()[0] != "a:1" {
+		t.Errorf("suspect %v, want [a:1]", ring.GetSuspectAddrs())
+	}
+
+	// Clients that do not know about the ring get a live BlockStore
+	blockStoreAddr, err := m.GetBlockStoreAddr(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockStoreAddr.GetAddr() != "c:1" {
+		t.Errorf("BlockStore address %s, want c:1", blockStoreAddr.GetAddr())
+	}
+
+	// A dead BlockStore that sends a heartbeat again rejoins the ring
+	if _, err := m.Heartbeat(ctx, &BlockStoreHeartbeat{Addr: "b:1"}); err != nil {
+		t.Fatal(err)
+	}
+	states, _ = memberStates(t, m)
+	if states["b:1"] != MemberState_LIVE {
+		t.Errorf("b:1 is %v after a heartbeat, want LIVE", states["b:1"])
+	}
+
+	// It rejoins the ring once the blocks of the dead BlockStore are rebalanced
+	m.clusterMutex.Lock()
+	defer m.clusterMutex.Unlock()
+	if !m.rebalancing || m.pendingCluster == nil || len(m.pendingCluster.BlockStoreAddrs) != 3 {
+		t.Errorf("rejoin of b:1 is not pending while blocks are rebalanced")
+	}
+}
+
+func TestBlockStoresWithoutHeartbeatsStayLive(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetaStoreFromConfig(NewClusterConfig([]string{"a:1", "b:1", "c:1"}))
+	m.SuspectTimeout, m.DeadTimeout = time.Millisecond, 2*time.Millisecond
+
+	time.Sleep(2 * m.DeadTimeout)
+	ring, err := m.GetBlockStoreRing(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ring.GetAddrs()) != 3 || m.memberDead("a:1") {
+		t.Fatalf("ring %v past DeadTimeout without heartbeats, want [a:1 b:1 c:1]", ring.GetAddrs())
+	}
+
+	// Only BlockStores that sent a heartbeat are expected to keep sending them
+	if _, err := m.Heartbeat(ctx, &BlockStoreHeartbeat{Addr: "a:1"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * m.DeadTimeout)
+	states, _ := memberStates(t, m)
+	if states["a:1"] != MemberState_DEAD || states["b:1"] != MemberState_LIVE || states["c:1"] != MemberState_LIVE {
+		t.Errorf("states %v, want a:1 DEAD, b:1 and c:1 LIVE", states)
+	}
+}
+
+func TestClusterConfigRestrictsMembership(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetaStoreFromConfig(NewClusterConfig([]string{"a:1"}))
+
+	if _, err := m.RegisterBlockStore(ctx, &BlockStoreHeartbeat{Addr: "b:1"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("register unlisted BlockStore: %v, want PermissionDenied", err)
+	}
+	if _, err := m.RegisterBlockStore(ctx, &BlockStoreHeartbeat{Addr: ""}); err == nil {
+		t.Errorf("registered a BlockStore without address")
+	}
+
+	m.UpdateCluster(NewClusterConfig([]string{"b:1"}))
+	states, _ := memberStates(t, m)
+	if _, ok := states["a:1"]; ok || states["b:1"] != MemberState_LIVE {
+		t.Errorf("states %v after the config changed, want only b:1", states)
+	}
+}
+
+func TestBlockStoreLeavesTheClusterWhileRebalancing(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetaStoreFromConfig(NewClusterConfig([]string{"a:1", "b:1", "c:1"}))
+
+	// b:1 dies and its blocks are rebalanced. a:1 leaves the cluster meanwhile, but stays on
+	// the ring until they are.
+	ageHeartbeat(m, "b:1", m.DeadTimeout)
+	if states, _ := memberStates(t, m); states["b:1"] != MemberState_DEAD || !m.Rebalancing() {
+		t.Fatal("blocks of the dead BlockStore are not rebalanced")
+	}
+	m.UpdateCluster(NewClusterConfig([]string{"b:1", "c:1"}))
+
+	ring, err := m.GetBlockStoreRing(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ring.GetAddrs()) != 2 || ring.GetAddrs()[0] != "a:1" || ring.GetAddrs()[1] != "c:1" {
+		t.Errorf("ring %v while rebalancing, want [a:1 c:1]", ring.GetAddrs())
+	}
+	blockStoreAddr, err := m.GetBlockStoreAddr(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockStoreAddr.GetAddr() != "c:1" {
+		t.Errorf("BlockStore address %s, want c:1", blockStoreAddr.GetAddr())
+	}
+}
+
+func TestFewerLiveBlockStoresThanReplicas(t *testing.T) {
+	config := &ClusterConfig{BlockStoreAddrs: []string{"a:1", "b:1", "c:1"}, ReplicationFactor: 3, WriteQuorum: 3, ReadQuorum: 2}
+	m := NewMetaStoreFromConfig(config)
+
+	ageHeartbeat(m, "a:1", m.DeadTimeout)
+	ageHeartbeat(m, "b:1", m.DeadTimeout)
+	ring, err := m.GetBlockStoreRing(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ring.GetReplicationFactor() != 1 || ring.GetWriteQuorum() != 1 || ring.GetReadQuorum() != 1 {
+		t.Errorf("replication %d, quorums %d/%d with one live BlockStore, want 1",
+			ring.GetReplicationFactor(), ring.GetWriteQuorum(), ring.GetReadQuorum())
+	}
+	if _, err := NewClusterConfigFromRing(ring); err != nil {
+		t.Errorf("ring is invalid: %v", err)
+	}
+}
+
+func TestRenameFile(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetaStoreFromConfig(nil)
+	update := func(fileMetaData *FileMetaData) int32 {
+		version, err := m.UpdateFile(ctx, fileMetaData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return version.GetVersion()
+	}
+	rename := func(oldFilename string, oldVersion int32, fileMetaData *FileMetaData) (int32, error) {
+		version, err := m.RenameFile(ctx, &FileRename{OldFilename: oldFilename, OldVersion: oldVersion, FileMetaData: fileMetaData})
+		return version.GetVersion(), err
+	}
+
+	update(&FileMetaData{Filename: "a.txt", Version: 1, BlockHashList: []string{"h1", "h2"}})
+	update(&FileMetaData{Filename: "b.txt", Version: 4, BlockHashList: []string{TOMBSTONE_HASH}})
+
+	if _, err := rename("a.txt", 1, &FileMetaData{Filename: "b.txt", Version: 5, BlockHashList: []string{"h1"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("rename changing the blocks: %v, want InvalidArgument", err)
+	}
+	if version, err := rename("a.txt", 0, &FileMetaData{Filename: "b.txt", Version: 5, BlockHashList: []string{"h1", "h2"}}); err != nil || version != -1 {
+		t.Errorf("rename of an old version: %d, %v", version, err)
+	}
+	if version, err := rename("a.txt", 1, &FileMetaData{Filename: "b.txt", Version: 4, BlockHashList: []string{"h1", "h2"}}); err != nil || version != -1 {
+		t.Errorf("rename onto an old version: %d, %v", version, err)
+	}
+
+	if version, err := rename("a.txt", 1, &FileMetaData{Filename: "b.txt", Version: 5, BlockHashList: []string{"h1", "h2"}, Mode: 0600}); err != nil || version != 5 {
+		t.Fatalf("rename: %d, %v", version, err)
+	}
+	old, renamed := m.FileMetaMap["a.txt"], m.FileMetaMap["b.txt"]
+	if !isTombstone(old) || old.GetVersion() != 2 || old.GetRenamedTo() != "b.txt" {
+		t.Errorf("old name after rename: %v", old)
+	}
+	if renamed.GetVersion() != 5 || renamed.GetMode() != 0600 || renamed.GetRenamedFrom() != "a.txt" {
+		t.Errorf("new name after rename: %v", renamed)
+	}
+	if versions, err := m.GetFileVersions(ctx, &FileName{Filename: "a.txt"}); err != nil || len(versions.GetVersions()) != 2 {
+		t.Errorf("history of the old name: %v, %v", versions, err)
+	}
+
+	// A rename is recorded on the version it made only
+	update(&FileMetaData{Filename: "b.txt", Version: 6, BlockHashList: []string{"h3"}, RenamedFrom: "a.txt"})
+	if m.FileMetaMap["b.txt"].GetRenamedFrom() != "" {
+		t.Errorf("update kept the rename: %v", m.FileMetaMap["b.txt"])
+	}
+}
+
+func TestUpdateFilesIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetaStoreFromConfig(nil)
+	m.UpdateFile(ctx, &FileMetaData{Filename: "src/a.c", Version: 1, BlockHashList: []string{"a1"}})
+	m.UpdateFile(ctx, &FileMetaData{Filename: "src/a.h", Version: 3, BlockHashList: []string{"h3"}})
+
+	conflicts, err := m.UpdateFiles(ctx, &FileBatch{Files: []*FileMetaData{
+		{Filename: "src/a.c", Version: 2, BlockHashList: []string{"a2"}},
+		{Filename: "src/a.h", Version: 3, BlockHashList: []string{"h4"}},
+		{Filename: "src/b.c", Version: 1, BlockHashList: []string{"b1"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts.GetConflicts()) != 1 || conflicts.GetConflicts()[0].GetFilename() != "src/a.h" ||
+		conflicts.GetConflicts()[0].GetVersion() != 3 || conflicts.GetConflicts()[0].GetLatestVersion() != 3 {
+		t.Errorf("conflicts %v, want src/a.h at version 3", conflicts.GetConflicts())
+	}
+	if m.FileMetaMap["src/a.c"].GetVersion() != 1 || m.FileMetaMap["src/b.c"] != nil {
+		t.Errorf("a rejected transaction was partly applied: %v", m.FileMetaMap)
+	}
+
+	conflicts, err = m.UpdateFiles(ctx, &FileBatch{Files: []*FileMetaData{
+		{Filename: "src/a.c", Version: 2, BlockHashList: []string{"a2"}},
+		{Filename: "src/a.h", Version: 4, BlockHashList: []string{"h4"}},
+		{Filename: "src/b.c", Version: 1, BlockHashList: []string{"b1"}},
+	}})
+	if err != nil || len(conflicts.GetConflicts()) != 0 {
+		t.Fatalf("commit transaction: %v, %v", conflicts.GetConflicts(), err)
+	}
+	if m.FileMetaMap["src/a.c"].GetVersion() != 2 || m.FileMetaMap["src/a.h"].GetVersion() != 4 || m.FileMetaMap["src/b.c"].GetVersion() != 1 {
+		t.Errorf("committed transaction not applied: %v", m.FileMetaMap)
+	}
+
+	_, err = m.UpdateFiles(ctx, &FileBatch{Files: []*FileMetaData{
+		{Filename: "src/c.c", Version: 1, BlockHashList: []string{"c1"}},
+		{Filename: "src/c.c", Version: 2, BlockHashList: []string{"c2"}},
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("transaction updating a file twice: %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,90 @@
+package servestore
+
+import (
+	"sync"
+	"time"
+)
+
+// How often a transfer waiting on a RateLimiter checks whether its rate was changed
+const RATE_LIMIT_POLL_INTERVAL time.Duration = 100 * time.Millisecond
+
+// RateLimiter is a token bucket limiting the bytes transferred per second by any number of
+// goroutines. Up to a second's worth of unused bytes can be transferred in a burst. Its rate
+// can be changed at any time, including while transfers wait on it. A nil RateLimiter, or
+// one with a rate of zero, does not limit transfers.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   int64     // Bytes per second
+	tokens float64   // Bytes that can be transferred without waiting, negative once overdrawn
+	last   time.Time // When tokens was last refilled
+}
+
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	return &RateLimiter{rate: bytesPerSecond, last: time.Now()}
+}
+
+// Rate returns the bytes per second the limiter allows, zero if unlimited
+func (l *RateLimiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate
+}
+
+// SetRate changes the bytes per second the limiter allows, zero for unlimited
+func (l *RateLimiter) SetRate(bytesPerSecond int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	l.rate = bytesPerSecond
+	if l.rate <= 0 || l.tokens > float64(l.rate) {
+		l.tokens = float64(l.rate)
+	}
+}
+
+// wait blocks until `n` more bytes can be transferred. A transfer is let through as soon as
+// the bucket is not overdrawn, and overdraws it by the bytes it transfers beyond those in
+// the bucket, so that transfers larger than the bucket cannot wait forever while the
+// average rate is still kept.
+func (l *RateLimiter) wait(n int) {
+	if l == nil {
+		return
+	}
+
+	for {
+		l.mu.Lock()
+		if l.rate <= 0 {
+			l.mu.Unlock()
+			return
+		}
+		l.refill(time.Now())
+		if l.tokens >= 0 {
+			l.tokens -= float64(n)
+			l.mu.Unlock()
+			return
+		}
+		delay := time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+		l.mu.Unlock()
+
+		if delay > RATE_LIMIT_POLL_INTERVAL {
+			delay = RATE_LIMIT_POLL_INTERVAL
+		}
+		time.Sleep(delay)
+	}
+}
+
+// refill adds the bytes earned since the last refill, up to a second's worth. Must be
+// called with mu held.
+func (l *RateLimiter) refill(now time.Time) {
+	if l.rate > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+		if l.tokens > float64(l.rate) {
+			l.tokens = float64(l.rate)
+		}
+	}
+	l.last = now
+}
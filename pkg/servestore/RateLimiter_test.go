@@ -0,0 +1,106 @@
+package servestore
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterIsSharedByConcurrentTransfers(t *testing.T) {
+	limiter := NewRateLimiter(1000000)
+
+	// 10 transfers of 100000 bytes: the first goes through at once, the rest take 0.9s
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.wait(100000)
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("1000000 bytes at 1000000 bytes/s took %v, want about 0.9s", elapsed)
+	}
+}
+
+func TestRateLimiterRateChangesApplyToWaitingTransfers(t *testing.T) {
+	limiter := NewRateLimiter(1)
+	limiter.wait(1000000)
+
+	done := make(chan struct{})
+	go func() {
+		limiter.wait(1)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("transfer not limited")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	limiter.SetRate(0)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("transfer still waiting after the limit was lifted")
+	}
+
+	var unlimited *RateLimiter
+	unlimited.wait(1000000)
+	if unlimited.Rate() != 0 {
+		t.Errorf("nil limiter rate %d, want 0", unlimited.Rate())
+	}
+}
+
+func TestSetTransferLimits(t *testing.T) {
+	loopback, err := routeInterface("127.0.0.1:1")
+	if err != nil {
+		t.Skipf("no loopback interface: %v", err)
+	}
+
+	client := RPCClient{MetaStoreAddr: net.JoinHostPort("127.0.0.1", "1")}
+	limits := &TransferLimits{UploadBytesPerSecond: 100, DownloadBytesPerSecond: 200, MeteredUploadBytesPerSecond: 10}
+
+	metered, err := client.SetTransferLimits(limits)
+	if err != nil || metered || client.UploadLimiter.Rate() != 100 || client.DownloadLimiter.Rate() != 200 {
+		t.Errorf("unmetered limits: %v, %v, %d up, %d down", metered, err, client.UploadLimiter.Rate(), client.DownloadLimiter.Rate())
+	}
+
+	// Copies of the client share its limiters
+	copied := client
+	limits.MeteredInterfaces = []string{"none", loopback}
+	metered, err = client.SetTransferLimits(limits)
+	if err != nil || !metered || copied.UploadLimiter.Rate() != 10 || copied.DownloadLimiter.Rate() != 200 {
+		t.Errorf("metered limits: %v, %v, %d up, %d down", metered, err, copied.UploadLimiter.Rate(), copied.DownloadLimiter.Rate())
+	}
+
+	if _, err := client.SetTransferLimits(&TransferLimits{DownloadBytesPerSecond: -1}); !errors.Is(err, ErrInvalidTransferLimits) {
+		t.Errorf("negative limit: %v, want ErrInvalidTransferLimits", err)
+	}
+}
+
+func TestLoadTransferLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(`{"uploadBytesPerSecond": 1000, "meteredInterfaces": ["wwan0"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	limits, err := LoadTransferLimits(path)
+	if err != nil || limits.UploadBytesPerSecond != 1000 || len(limits.MeteredInterfaces) != 1 {
+		t.Errorf("load: %+v, %v", limits, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"uploadBytesPerSecond": "fast"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTransferLimits(path); !errors.Is(err, ErrInvalidTransferLimits) {
+		t.Errorf("load invalid file: %v, want ErrInvalidTransferLimits", err)
+	}
+}
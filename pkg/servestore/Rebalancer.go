@@ -0,0 +1,282 @@
+package servestore
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+var ErrRebalanceIncomplete = errors.New("ErrRebalanceIncomplete")
+
+// Rebalancer copies blocks to the BlockStores that became their replicas when the cluster
+// changed from placement From to placement To. Blocks are copied from the replicas they
+// had in From, and only to new replicas that do not hold them yet. Blocks are not removed
+// from BlockStores that are no longer their replicas, so that they can still be read from
+// there while blocks are being copied.
+type Rebalancer struct {
+	Client ClientInterface
+	From   *BlockPlacement
+	To     *BlockPlacement
+
+	// Bytes of blocks copied per second at most, unlimited if zero
+	BytesPerSecond int64
+
+	// Called after each changed range of the ring is rebalanced, if set
+	Progress func(progress RebalanceProgress)
+
+	// Reports whether a BlockStore is known to be down for good, if set. Blocks are neither
+	// copied from nor to such BlockStores.
+	Unavailable func(server string) bool
+}
+
+// RebalanceProgress counts the ranges of the ring whose replicas changed, and the blocks
+// in them that were copied, or skipped because all of their new replicas already held them.
+// Blocks that could not be copied, and ranges none of whose previous replicas could be
+// reached, are counted as failed, and are copied by the next run. Ranges all of whose
+// previous replicas are unavailable are counted as lost.
+type RebalanceProgress struct {
+	Ranges        int
+	RangesDone    int
+	RangesFailed  int
+	RangesLost    int
+	BlocksCopied  int
+	BlocksSkipped int
+	BlocksFailed  int
+	BytesCopied   int64
+}
+
+func (progress RebalanceProgress) String() string {
+	return fmt.Sprintf("%d/%d ranges (%d failed, %d lost), %d blocks copied (%d bytes), %d skipped, %d failed",
+		progress.RangesDone, progress.Ranges, progress.RangesFailed, progress.RangesLost, progress.BlocksCopied,
+		progress.BytesCopied, progress.BlocksSkipped, progress.BlocksFailed)
+}
+
+// hashRange is a range of the hash ring from Start, inclusive, to End, exclusive, whose
+// blocks were stored on From and are stored on To. See inHashRange.
+type hashRange struct {
+	Start string
+	End   string
+	From  []string
+	To    []string
+}
+
+// Run copies the blocks of every range of the ring whose replicas changed. It returns an
+// error wrapping ErrRebalanceIncomplete if some blocks could not be copied.
+func (r *Rebalancer) Run() (RebalanceProgress, error) {
+	ranges := changedRanges(r.From, r.To)
+	progress := RebalanceProgress{Ranges: len(ranges)}
+	log.Println("Rebalancing", len(ranges), "ranges of the hash ring")
+
+	throttle := newThrottle(r.BytesPerSecond)
+	for _, changed := range ranges {
+		r.rebalanceRange(changed, throttle, &progress)
+		progress.RangesDone++
+		log.Println("Rebalance progress:", progress)
+		if r.Progress != nil {
+			r.Progress(progress)
+		}
+	}
+
+	if progress.RangesFailed > 0 || progress.BlocksFailed > 0 {
+		return progress, fmt.Errorf("%w: %d ranges and %d blocks could not be copied",
+			ErrRebalanceIncomplete, progress.RangesFailed, progress.BlocksFailed)
+	}
+	return progress, nil
+}
+
+// rebalanceRange copies the blocks of the range to its new replicas
+func (r *Rebalancer) rebalanceRange(changed *hashRange, throttle *throttle, progress *RebalanceProgress) {
+	sources := r.available(changed.From)
+	targets := r.available(addedServers(changed.From, changed.To))
+	if len(sources) == 0 {
+		log.Printf("Every previous replica of range %s-%s is unavailable, its blocks are lost", changed.Start, changed.End)
+		progress.RangesLost++
+		return
+	}
+
+	// List the blocks held by any of the previous replicas
+	listed := make(map[string]bool)
+	reachable := 0
+	for _, source := range sources {
+		var blockHashes []string
+		if err := r.Client.ListBlocks(changed.Start, changed.End, source, &blockHashes); err != nil {
+			log.Printf("List blocks on %s: %v", source, err)
+			continue
+		}
+		reachable++
+		for _, blockHash := range blockHashes {
+			listed[blockHash] = true
+		}
+	}
+	if reachable == 0 {
+		log.Printf("No previous replica of range %s-%s can be reached", changed.Start, changed.End)
+		progress.RangesFailed++
+		return
+	}
+
+	blockHashes := make([]string, 0, len(listed))
+	for blockHash := range listed {
+		blockHashes = append(blockHashes, blockHash)
+	}
+	sort.Strings(blockHashes)
+
+	// Find the blocks each new replica is missing
+	missing := make(map[string][]string)
+	for _, target := range targets {
+		var present []string
+		if err := r.Client.HasBlocks(blockHashes, target, &present); err != nil {
+			log.Printf("Check blocks on %s: %v", target, err)
+			progress.BlocksFailed += len(blockHashes)
+			continue
+		}
+		held := make(map[string]bool)
+		for _, blockHash := range present {
+			held[blockHash] = true
+		}
+		for _, blockHash := range blockHashes {
+			if !held[blockHash] {
+				missing[blockHash] = append(missing[blockHash], target)
+			}
+		}
+	}
+
+	for _, blockHash := range blockHashes {
+		if len(missing[blockHash]) == 0 {
+			progress.BlocksSkipped++
+			continue
+		}
+
+		block, err := r.readBlock(blockHash, sources)
+		if err != nil {
+			log.Printf("Copy block %s: %v", blockHash, err)
+			progress.BlocksFailed++
+			continue
+		}
+
+		for _, target := range missing[blockHash] {
+			throttle.wait(int64(len(block.GetBlockData())))
+
+			var success bool
+			err := r.Client.PutBlock(block, target, &success)
+			if err == nil && !success {
+				err = errors.New("rejected by BlockStore")
+			}
+			if err != nil {
+				log.Printf("Copy block %s to %s: %v", blockHash, target, err)
+				progress.BlocksFailed++
+				continue
+			}
+			progress.BlocksCopied++
+			progress.BytesCopied += int64(len(block.GetBlockData()))
+		}
+	}
+}
+
+// available returns the servers that are not unavailable
+func (r *Rebalancer) available(servers []string) []string {
+	if r.Unavailable == nil {
+		return servers
+	}
+	available := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if !r.Unavailable(server) {
+			available = append(available, server)
+		}
+	}
+	return available
+}
+
+// readBlock reads the block from the first of sources that returns it intact
+func (r *Rebalancer) readBlock(blockHash string, sources []string) (*Block, error) {
+	var lastErr error
+	for _, source := range sources {
+		block := &Block{}
+		if err := r.Client.GetBlock(blockHash, source, block); err != nil {
+			lastErr = fmt.Errorf("%s: %w", source, err)
+			continue
+		}
+		if GetBlockHashString(block.GetBlockData()) != blockHash {
+			lastErr = fmt.Errorf("%s: content does not match hash", source)
+			continue
+		}
+		return block, nil
+	}
+	return nil, lastErr
+}
+
+// changedRanges returns the ranges of the ring whose replicas gained a server from
+// placement from to placement to. Between two consecutive points of either ring, every
+// block has the same replicas in each placement, namely those of a block at the first point.
+func changedRanges(from *BlockPlacement, to *BlockPlacement) []*hashRange {
+	points := make(map[string]bool)
+	for _, serverHash := range from.serverHashes {
+		points[serverHash] = true
+	}
+	for _, serverHash := range to.serverHashes {
+		points[serverHash] = true
+	}
+	sortedPoints := make([]string, 0, len(points))
+	for point := range points {
+		sortedPoints = append(sortedPoints, point)
+	}
+	sort.Strings(sortedPoints)
+
+	ranges := make([]*hashRange, 0)
+	for i, start := range sortedPoints {
+		end := sortedPoints[(i+1)%len(sortedPoints)]
+		fromReplicas, toReplicas := from.Replicas(start), to.Replicas(start)
+		if len(addedServers(fromReplicas, toReplicas)) == 0 {
+			continue
+		}
+
+		// Merge with the previous range if its replicas changed the same way
+		if last := len(ranges) - 1; last >= 0 && ranges[last].End == start &&
+			equalHashLists(ranges[last].From, fromReplicas) && equalHashLists(ranges[last].To, toReplicas) {
+			ranges[last].End = end
+			continue
+		}
+		ranges = append(ranges, &hashRange{Start: start, End: end, From: fromReplicas, To: toReplicas})
+	}
+	return ranges
+}
+
+// addedServers returns the servers in to that are not in from
+func addedServers(from []string, to []string) []string {
+	inFrom := make(map[string]bool)
+	for _, server := range from {
+		inFrom[server] = true
+	}
+
+	added := make([]string, 0)
+	for _, server := range to {
+		if !inFrom[server] {
+			added = append(added, server)
+		}
+	}
+	return added
+}
+
+// throttle paces a stream of bytes to at most rate bytes per second on average
+type throttle struct {
+	rate  int64
+	start time.Time
+	bytes int64
+}
+
+func newThrottle(rate int64) *throttle {
+	return &throttle{rate: rate, start: time.Now()}
+}
+
+// wait blocks until n more bytes can be sent without exceeding the rate
+func (t *throttle) wait(n int64) {
+	if t.rate <= 0 {
+		return
+	}
+	t.bytes += n
+	due := time.Duration(float64(t.bytes) / float64(t.rate) * float64(time.Second))
+	if delay := due - time.Since(t.start); delay > 0 {
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,146 @@
+package servestore
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func rebalanceTestPlacement(addrs []string) *BlockPlacement {
+	return NewBlockPlacement(&ClusterConfig{BlockStoreAddrs: addrs, VirtualNodes: 20, ReplicationFactor: 2, WriteQuorum: 2})
+}
+
+func putTestBlocks(t *testing.T, stores *fakeCluster, placement *BlockPlacement, n int) []string {
+	blockHashes := make([]string, n)
+	for i := range blockHashes {
+		block := &Block{BlockData: []byte("block" + strconv.Itoa(i))}
+		block.BlockSize = int32(len(block.BlockData))
+		blockHashes[i] = GetBlockHashString(block.BlockData)
+		if _, err := placement.PutBlock(stores, block, blockHashes[i], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return blockHashes
+}
+
+func TestChangedRangesCoverMovedBlocks(t *testing.T) {
+	from := rebalanceTestPlacement([]string{"a:1", "b:1", "c:1"})
+	to := rebalanceTestPlacement([]string{"a:1", "b:1", "c:1", "d:1"})
+	ranges := changedRanges(from, to)
+	if len(ranges) == 0 {
+		t.Fatal("no ranges changed after adding a server")
+	}
+
+	for _, blockHash := range testBlockHashes(2000) {
+		gained := len(addedServers(from.Replicas(blockHash), to.Replicas(blockHash))) > 0
+		covered := false
+		for _, changed := range ranges {
+			if inHashRange(blockHash, changed.Start, changed.End) {
+				covered = true
+				if !equalHashLists(changed.To, to.Replicas(blockHash)) {
+					t.Fatalf("block %s is in a range with replicas %v, has %v", blockHash, changed.To, to.Replicas(blockHash))
+				}
+			}
+		}
+		if gained != covered {
+			t.Fatalf("block %s gained a replica: %v, in a changed range: %v", blockHash, gained, covered)
+		}
+	}
+
+	if ranges := changedRanges(from, from); len(ranges) != 0 {
+		t.Errorf("%d ranges changed without a change to the cluster", len(ranges))
+	}
+}
+
+func TestRebalanceCopiesBlocksToNewReplicas(t *testing.T) {
+	stores := newFakeCluster()
+	from := rebalanceTestPlacement([]string{"a:1", "b:1", "c:1"})
+	to := rebalanceTestPlacement([]string{"a:1", "c:1", "d:1", "e:1"})
+	blockHashes := putTestBlocks(t, stores, from, 300)
+
+	// One previous replica of each block is enough to copy it
+	stores.down["b:1"] = true
+
+	var updates int
+	rebalancer := &Rebalancer{Client: stores, From: from, To: to, Progress: func(RebalanceProgress) { updates++ }}
+	progress, err := rebalancer.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.RangesDone != progress.Ranges || updates != progress.Ranges {
+		t.Errorf("%d of %d ranges done, %d progress updates", progress.RangesDone, progress.Ranges, updates)
+	}
+	if progress.BlocksCopied == 0 {
+		t.Error("no blocks copied")
+	}
+
+	for _, blockHash := range blockHashes {
+		for _, server := range to.Replicas(blockHash) {
+			if _, ok := stores.blocks[server][blockHash]; !ok {
+				t.Fatalf("block %s not copied to its replica %s", blockHash, server)
+			}
+		}
+	}
+
+	// Running again finds every block already copied
+	stores.down["b:1"] = false
+	progress, err = (&Rebalancer{Client: stores, From: from, To: to}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.BlocksCopied != 0 || progress.BlocksSkipped == 0 {
+		t.Errorf("second run copied %d blocks and skipped %d", progress.BlocksCopied, progress.BlocksSkipped)
+	}
+}
+
+func TestRebalanceFailsWhenNewReplicaIsDown(t *testing.T) {
+	stores := newFakeCluster()
+	from := rebalanceTestPlacement([]string{"a:1", "b:1"})
+	to := rebalanceTestPlacement([]string{"a:1", "b:1", "c:1"})
+	putTestBlocks(t, stores, from, 100)
+
+	stores.down["c:1"] = true
+	if _, err := (&Rebalancer{Client: stores, From: from, To: to}).Run(); !errors.Is(err, ErrRebalanceIncomplete) {
+		t.Errorf("got %v, want ErrRebalanceIncomplete", err)
+	}
+}
+
+func TestGetBlockReadsFromPreviousReplicas(t *testing.T) {
+	stores := newFakeCluster()
+	from := rebalanceTestPlacement([]string{"a:1", "b:1", "c:1"})
+	blockHashes := putTestBlocks(t, stores, from, 50)
+
+	// Nothing has been copied to the new BlockStores yet
+	to := rebalanceTestPlacement([]string{"d:1", "e:1", "f:1"})
+	if err := to.GetBlock(stores, blockHashes[0], &Block{}); err == nil {
+		t.Fatal("read a block that was never copied")
+	}
+
+	to.Previous = from
+	for _, blockHash := range blockHashes {
+		if err := to.GetBlock(stores, blockHash, &Block{}); err != nil {
+			t.Fatalf("block %s: %v", blockHash, err)
+		}
+	}
+}
+
+func TestRebalanceSkipsUnavailableBlockStores(t *testing.T) {
+	stores := newFakeCluster()
+	from := rebalanceTestPlacement([]string{"a:1", "b:1", "e:1"})
+	to := rebalanceTestPlacement([]string{"a:1", "c:1", "d:1"})
+	putTestBlocks(t, stores, from, 100)
+
+	// b:1 and e:1 died, and so did c:1 right after joining
+	stores.down["b:1"], stores.down["c:1"], stores.down["e:1"] = true, true, true
+	unavailable := func(server string) bool { return stores.down[server] }
+	progress, err := (&Rebalancer{Client: stores, From: from, To: to, Unavailable: unavailable}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.RangesLost == 0 {
+		t.Errorf("no range lost, but b:1 and e:1 held some blocks alone")
+	}
+	if progress.BlocksCopied == 0 {
+		t.Errorf("no blocks copied from a:1 to d:1")
+	}
+}
@@ -0,0 +1,637 @@
+package servestore
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespace of the XML documents of the S3 API
+const S3_XMLNS string = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// Most keys listed at once, and highest part number of a multipart upload
+const S3_MAX_KEYS int = 1000
+const S3_MAX_PART_NUMBER int = 10000
+
+// Longest object key, in bytes
+const S3_MAX_KEY_LENGTH int = 1024
+
+// Times an object is committed to the MetaStore before giving up, when other writers keep
+// updating it first
+const S3_COMMIT_ATTEMPTS int = 5
+
+// s3Error is an error returned to S3 clients with its HTTP status and S3 error code
+type s3Error struct {
+	statusCode int
+	code       string
+	message    string
+}
+
+func (e *s3Error) Error() string {
+	return e.code + ": " + e.message
+}
+
+var (
+	s3ErrAccessDenied           = &s3Error{http.StatusForbidden, "AccessDenied", "Access Denied"}
+	s3ErrInvalidAccessKeyId     = &s3Error{http.StatusForbidden, "InvalidAccessKeyId", "The access key ID does not exist"}
+	s3ErrSignatureDoesNotMatch  = &s3Error{http.StatusForbidden, "SignatureDoesNotMatch", "The request signature does not match the signature calculated by the server"}
+	s3ErrRequestTimeTooSkewed   = &s3Error{http.StatusForbidden, "RequestTimeTooSkewed", "The difference between the request time and the server's time is too large"}
+	s3ErrBadDigest              = &s3Error{http.StatusBadRequest, "BadDigest", "The Content-MD5 does not match the body"}
+	s3ErrContentSHA256Mismatch  = &s3Error{http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The X-Amz-Content-Sha256 does not match the body"}
+	s3ErrInvalidKey             = &s3Error{http.StatusBadRequest, "InvalidArgument", "Object keys must be relative slash-separated paths without empty, . or .. segments"}
+	s3ErrInvalidPart            = &s3Error{http.StatusBadRequest, "InvalidPart", "A part was not uploaded or its ETag does not match"}
+	s3ErrInvalidPartOrder       = &s3Error{http.StatusBadRequest, "InvalidPartOrder", "The parts are not in ascending order"}
+	s3ErrMalformedXML           = &s3Error{http.StatusBadRequest, "MalformedXML", "The XML is not well-formed or does not list any parts"}
+	s3ErrNoSuchBucket           = &s3Error{http.StatusNotFound, "NoSuchBucket", "The bucket does not exist"}
+	s3ErrNoSuchKey              = &s3Error{http.StatusNotFound, "NoSuchKey", "The key does not exist"}
+	s3ErrNoSuchUpload           = &s3Error{http.StatusNotFound, "NoSuchUpload", "The multipart upload does not exist"}
+	s3ErrMethodNotAllowed       = &s3Error{http.StatusMethodNotAllowed, "MethodNotAllowed", "The method is not allowed against this resource"}
+	s3ErrOperationAborted       = &s3Error{http.StatusConflict, "OperationAborted", "The object kept being updated by other writers"}
+	s3ErrNotImplemented         = &s3Error{http.StatusNotImplemented, "NotImplemented", "The request is not supported"}
+	s3ErrServiceUnavailable     = &s3Error{http.StatusServiceUnavailable, "ServiceUnavailable", "The MetaStore or BlockStores are unavailable"}
+	s3ErrInvalidArgumentMaxKeys = &s3Error{http.StatusBadRequest, "InvalidArgument", "max-keys must be a non-negative integer"}
+	s3ErrInvalidArgumentToken   = &s3Error{http.StatusBadRequest, "InvalidArgument", "The continuation token is not valid"}
+	s3ErrInvalidArgumentPart    = &s3Error{http.StatusBadRequest, "InvalidArgument", fmt.Sprintf("Part number must be an integer between 1 and %d", S3_MAX_PART_NUMBER)}
+)
+
+// S3Server serves a subset of the S3 API on top of a MetaStore and its BlockStores: one
+// bucket, whose object keys are the filenames synced by clients. Objects are cut into blocks
+// and only the blocks that their replicas do not hold yet are uploaded, as ClientSync does.
+// Every request has to be signed with AWS Signature Version 4 by one of the credentials.
+type S3Server struct {
+	client      ClientInterface
+	bucket      string
+	region      string
+	credentials map[string]string
+	blockSize   int
+	created     time.Time
+
+	uploadsMutex sync.Mutex
+	uploads      map[string]*multipartUpload
+}
+
+// multipartUpload is an upload in progress, holding the blocks of each part uploaded so far
+type multipartUpload struct {
+	key   string
+	parts map[int]*FileMetaData
+}
+
+// NewS3Server returns an S3Server serving bucket, signed for region with the secret key of
+// each access key in credentials. Objects are cut into blocks of blockSize bytes.
+func NewS3Server(client ClientInterface, bucket string, region string, credentials map[string]string, blockSize int) *S3Server {
+	return &S3Server{
+		client:      client,
+		bucket:      bucket,
+		region:      region,
+		credentials: credentials,
+		blockSize:   blockSize,
+		created:     time.Now(),
+		uploads:     make(map[string]*multipartUpload),
+	}
+}
+
+func (s *S3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	payloadHash, err := verifyS3Request(r, s.credentials, s.region, time.Now())
+	if err != nil {
+		writeS3Error(w, r, err)
+		return
+	}
+	r.Body = newS3Body(r.Body, payloadHash, r.Header.Get("Content-Md5"))
+
+	bucket, key := splitS3Path(r.URL.Path)
+	switch {
+	case bucket == "" && r.Method == http.MethodGet:
+		err = s.listBuckets(w)
+	case bucket == "":
+		err = s3ErrMethodNotAllowed
+	case bucket != s.bucket:
+		err = s3ErrNoSuchBucket
+	case key == "":
+		err = s.serveBucket(w, r)
+	default:
+		err = s.serveObject(w, r, key)
+	}
+	if err != nil {
+		writeS3Error(w, r, err)
+	}
+}
+
+// splitS3Path splits a path-style request path into its bucket and object key
+func splitS3Path(requestPath string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(requestPath, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// validS3Key reports whether a key can be synced by clients as a file in their base directory
+func validS3Key(key string) bool {
+	return len(key) <= S3_MAX_KEY_LENGTH && validFilename(key) && !isClientFile(key)
+}
+
+func (s *S3Server) serveBucket(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && query.Has("location"):
+		return writeS3XML(w, http.StatusOK, &s3LocationConstraint{XMLNS: S3_XMLNS, Region: s.region})
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		return s.listObjects(w, query)
+	case r.Method == http.MethodGet:
+		return s3ErrNotImplemented
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return s3ErrMethodNotAllowed
+}
+
+func (s *S3Server) serveObject(w http.ResponseWriter, r *http.Request, key string) error {
+	if !validS3Key(key) {
+		return s3ErrInvalidKey
+	}
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		return s.createMultipartUpload(w, key)
+	case query.Has("uploadId") && r.Method == http.MethodPut:
+		return s.uploadPart(w, r, key, query)
+	case query.Has("uploadId") && r.Method == http.MethodPost:
+		return s.completeMultipartUpload(w, r, key, query.Get("uploadId"))
+	case query.Has("uploadId") && r.Method == http.MethodDelete:
+		return s.abortMultipartUpload(w, key, query.Get("uploadId"))
+	case query.Has("uploadId"):
+		return s3ErrNotImplemented
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		return s3ErrNotImplemented
+	case r.Method == http.MethodPut:
+		return s.putObject(w, r, key)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return s.getObject(w, r, key)
+	case r.Method == http.MethodDelete:
+		return s.deleteObject(w, key)
+	}
+	return s3ErrMethodNotAllowed
+}
+
+// liveFile returns the metadata of the file named key, unless it does not exist or is deleted
+func (s *S3Server) liveFile(key string) (*FileMetaData, error) {
+	fileInfoMap := make(map[string]*FileMetaData)
+	if err := s.client.GetFileInfoMap(&fileInfoMap); err != nil {
+		return nil, err
+	}
+	fileMetaData, ok := fileInfoMap[key]
+	if !ok || isTombstone(fileMetaData) {
+		return nil, s3ErrNoSuchKey
+	}
+	return fileMetaData, nil
+}
+
+func (s *S3Server) putObject(w http.ResponseWriter, r *http.Request, key string) error {
+	fileMetaData, err := s.putBlocks(r.Body)
+	if err != nil {
+		return err
+	}
+	if err := s.commitObject(key, fileMetaData); err != nil {
+		return err
+	}
+	w.Header().Set("ETag", fileETag(fileMetaData))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *S3Server) getObject(w http.ResponseWriter, r *http.Request, key string) error {
+	fileMetaData, err := s.liveFile(key)
+	if err != nil {
+		return err
+	}
+	return serveFileBlocks(w, r, s.client, fileMetaData)
+}
+
+// deleteObject writes a tombstone for the file named key. Deleting a key that does not
+// exist succeeds, as in S3.
+func (s *S3Server) deleteObject(w http.ResponseWriter, key string) error {
+	_, err := s.liveFile(key)
+	if err == nil {
+		err = s.commitObject(key, &FileMetaData{BlockHashList: []string{TOMBSTONE_HASH}})
+	}
+	if err != nil && err != s3ErrNoSuchKey {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// putBlocks cuts body into blocks and uploads those that are not present on all of their
+// replicas yet, returning the metadata of the blocks
+func (s *S3Server) putBlocks(body io.Reader) (*FileMetaData, error) {
+	placement, err := FetchBlockPlacement(s.client)
+	if err != nil {
+		return nil, err
+	}
+
+	fileMetaData, _, err := putReaderBlocks(s.client, placement, body, s.blockSize)
+	return fileMetaData, err
+}
+
+// commitObject records fileMetaData as the next version of the file named key. Like in S3,
+// the last writer wins: the version is retried when another writer updated the file first.
+func (s *S3Server) commitObject(key string, fileMetaData *FileMetaData) error {
+	fileMetaData.Filename = key
+	fileMetaData.Mtime = time.Now().UnixNano()
+	for attempt := 0; attempt < S3_COMMIT_ATTEMPTS; attempt++ {
+		fileInfoMap := make(map[string]*FileMetaData)
+		if err := s.client.GetFileInfoMap(&fileInfoMap); err != nil {
+			return err
+		}
+		fileMetaData.Version = fileInfoMap[key].GetVersion() + 1
+
+		var latestVersion int32
+		if err := s.client.UpdateFile(fileMetaData, &latestVersion); err != nil {
+			return err
+		}
+		if latestVersion == fileMetaData.GetVersion() {
+			return nil
+		}
+		log.Printf("Version %d of %s was written by another writer, retrying", fileMetaData.GetVersion(), key)
+	}
+	return s3ErrOperationAborted
+}
+
+func (s *S3Server) listBuckets(w http.ResponseWriter) error {
+	return writeS3XML(w, http.StatusOK, &s3ListAllMyBucketsResult{
+		XMLNS:   S3_XMLNS,
+		Owner:   s3Owner{ID: s.bucket, DisplayName: s.bucket},
+		Buckets: []s3Bucket{{Name: s.bucket, CreationDate: s.created.UTC().Format(time.RFC3339)}},
+	})
+}
+
+// listObjects lists the keys of the bucket in order, as ListObjectsV2 does. With a
+// delimiter, the keys sharing a prefix up to the delimiter are listed once as that prefix.
+// The continuation token is the last key or prefix listed.
+func (s *S3Server) listObjects(w http.ResponseWriter, query url.Values) error {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	maxKeys := S3_MAX_KEYS
+	if value := query.Get("max-keys"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return s3ErrInvalidArgumentMaxKeys
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+	after := query.Get("start-after")
+	if token := query.Get("continuation-token"); token != "" {
+		decoded, err := base64.URLEncoding.DecodeString(token)
+		if err != nil {
+			return s3ErrInvalidArgumentToken
+		}
+		after = string(decoded)
+	}
+
+	fileInfoMap := make(map[string]*FileMetaData)
+	if err := s.client.GetFileInfoMap(&fileInfoMap); err != nil {
+		return err
+	}
+	var keys []string
+	for key, fileMetaData := range fileInfoMap {
+		if strings.HasPrefix(key, prefix) && !isTombstone(fileMetaData) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	encode := func(s string) string { return s }
+	result := &s3ListBucketResult{XMLNS: S3_XMLNS, Name: s.bucket, MaxKeys: maxKeys, ContinuationToken: query.Get("continuation-token")}
+	if query.Get("encoding-type") == "url" {
+		encode = func(s string) string { return s3URIEncode(s, false) }
+		result.EncodingType = "url"
+	}
+	result.Prefix, result.Delimiter, result.StartAfter = encode(prefix), encode(delimiter), encode(query.Get("start-after"))
+
+	last := ""
+	for _, key := range keys {
+		entry := key
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			entry = key[:len(prefix)+i+len(delimiter)]
+		}
+		if entry <= after || entry == last {
+			continue
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(last))
+			break
+		}
+
+		if entry != key {
+			result.CommonPrefixes = append(result.CommonPrefixes, s3CommonPrefix{Prefix: encode(entry)})
+		} else {
+			fileMetaData := fileInfoMap[key]
+			result.Contents = append(result.Contents, s3Object{
+				Key:          encode(key),
+				LastModified: modTime(fileMetaData).Format("2006-01-02T15:04:05.000Z"),
+				ETag:         fileETag(fileMetaData),
+				Size:         fileMetaData.GetSize(),
+				StorageClass: "STANDARD",
+			})
+		}
+		result.KeyCount++
+		last = entry
+	}
+	return writeS3XML(w, http.StatusOK, result)
+}
+
+func (s *S3Server) createMultipartUpload(w http.ResponseWriter, key string) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	s.uploadsMutex.Lock()
+	s.uploads[uploadID] = &multipartUpload{key: key, parts: make(map[int]*FileMetaData)}
+	s.uploadsMutex.Unlock()
+
+	return writeS3XML(w, http.StatusOK, &s3InitiateMultipartUploadResult{XMLNS: S3_XMLNS, Bucket: s.bucket, Key: key, UploadID: uploadID})
+}
+
+// upload returns the multipart upload of key with the given ID
+func (s *S3Server) upload(key string, uploadID string) (*multipartUpload, error) {
+	s.uploadsMutex.Lock()
+	defer s.uploadsMutex.Unlock()
+	upload, ok := s.uploads[uploadID]
+	if !ok || upload.key != key {
+		return nil, s3ErrNoSuchUpload
+	}
+	return upload, nil
+}
+
+// uploadPart uploads the blocks of a part. Parts are cut into blocks on their own, so
+// parts whose size is not a multiple of the block size are cut again on completion.
+func (s *S3Server) uploadPart(w http.ResponseWriter, r *http.Request, key string, query url.Values) error {
+	partNumber, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > S3_MAX_PART_NUMBER {
+		return s3ErrInvalidArgumentPart
+	}
+	if _, err := s.upload(key, query.Get("uploadId")); err != nil {
+		return err
+	}
+
+	part, err := s.putBlocks(r.Body)
+	if err != nil {
+		return err
+	}
+
+	// The upload may have been completed or aborted meanwhile
+	upload, err := s.upload(key, query.Get("uploadId"))
+	if err != nil {
+		return err
+	}
+	s.uploadsMutex.Lock()
+	upload.parts[partNumber] = part
+	s.uploadsMutex.Unlock()
+
+	w.Header().Set("ETag", fileETag(part))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *S3Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, key string, uploadID string) error {
+	upload, err := s.upload(key, uploadID)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	var request s3CompleteMultipartUpload
+	if err := xml.Unmarshal(body, &request); err != nil || len(request.Parts) == 0 {
+		return s3ErrMalformedXML
+	}
+
+	var parts []*FileMetaData
+	aligned := true
+	s.uploadsMutex.Lock()
+	for i, requestPart := range request.Parts {
+		if i > 0 && requestPart.PartNumber <= request.Parts[i-1].PartNumber {
+			s.uploadsMutex.Unlock()
+			return s3ErrInvalidPartOrder
+		}
+		part, ok := upload.parts[requestPart.PartNumber]
+		if !ok || strings.Trim(requestPart.ETag, `"`) != strings.Trim(fileETag(part), `"`) {
+			s.uploadsMutex.Unlock()
+			return s3ErrInvalidPart
+		}
+		if i < len(request.Parts)-1 && part.GetSize()%int64(s.blockSize) != 0 {
+			aligned = false
+		}
+		parts = append(parts, part)
+	}
+	s.uploadsMutex.Unlock()
+
+	// Blocks hold whole parts, so the parts can be joined unless one ends within a block
+	fileMetaData := &FileMetaData{BlockSize: int32(s.blockSize)}
+	if aligned {
+		for _, part := range parts {
+			fileMetaData.BlockHashList = append(fileMetaData.BlockHashList, part.GetBlockHashList()...)
+			fileMetaData.Size += part.GetSize()
+		}
+	} else {
+		log.Printf("Cutting the parts of %s into blocks again", key)
+		placement, err := FetchBlockPlacement(s.client)
+		if err != nil {
+			return err
+		}
+		var readers []io.Reader
+		for _, part := range parts {
+			readers = append(readers, newBlockReader(s.client, placement, part))
+		}
+		if fileMetaData, err = s.putBlocks(io.MultiReader(readers...)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.commitObject(key, fileMetaData); err != nil {
+		return err
+	}
+	s.uploadsMutex.Lock()
+	delete(s.uploads, uploadID)
+	s.uploadsMutex.Unlock()
+
+	return writeS3XML(w, http.StatusOK, &s3CompleteMultipartUploadResult{
+		XMLNS:    S3_XMLNS,
+		Location: "/" + s.bucket + "/" + s3URIEncode(key, false),
+		Bucket:   s.bucket,
+		Key:      key,
+		ETag:     fileETag(fileMetaData),
+	})
+}
+
+func (s *S3Server) abortMultipartUpload(w http.ResponseWriter, key string, uploadID string) error {
+	if _, err := s.upload(key, uploadID); err != nil {
+		return err
+	}
+	s.uploadsMutex.Lock()
+	delete(s.uploads, uploadID)
+	s.uploadsMutex.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// s3Body checks the body of a request against the SHA-256 it was signed with and its
+// Content-MD5, once it is read to the end
+type s3Body struct {
+	io.ReadCloser
+	sha256     hash.Hash
+	md5        hash.Hash
+	wantSHA256 string
+	wantMD5    string
+}
+
+func newS3Body(body io.ReadCloser, payloadHash string, contentMD5 string) *s3Body {
+	if payloadHash == S3_UNSIGNED_PAYLOAD {
+		payloadHash = ""
+	}
+	return &s3Body{ReadCloser: body, sha256: sha256.New(), md5: md5.New(), wantSHA256: payloadHash, wantMD5: contentMD5}
+}
+
+func (b *s3Body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.sha256.Write(p[:n])
+	b.md5.Write(p[:n])
+	if err == io.EOF {
+		if b.wantSHA256 != "" && hex.EncodeToString(b.sha256.Sum(nil)) != b.wantSHA256 {
+			return n, s3ErrContentSHA256Mismatch
+		}
+		if b.wantMD5 != "" && base64.StdEncoding.EncodeToString(b.md5.Sum(nil)) != b.wantMD5 {
+			return n, s3ErrBadDigest
+		}
+	}
+	return n, err
+}
+
+// writeS3Error writes an S3 error document. Errors that are not S3 errors come from the
+// MetaStore or BlockStores, and are logged.
+func writeS3Error(w http.ResponseWriter, r *http.Request, err error) {
+	var s3Err *s3Error
+	if !errors.As(err, &s3Err) {
+		log.Printf("S3 %s %s: %v", r.Method, r.URL.Path, err)
+		s3Err = s3ErrServiceUnavailable
+	}
+	if err := writeS3XML(w, s3Err.statusCode, &s3ErrorResponse{Code: s3Err.code, Message: s3Err.message, Resource: r.URL.Path}); err != nil {
+		log.Printf("S3 %s %s: %v", r.Method, r.URL.Path, err)
+	}
+}
+
+func writeS3XML(w http.ResponseWriter, statusCode int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(statusCode)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// XML documents of the S3 API
+type s3ErrorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string
+	Message  string
+	Resource string
+}
+
+type s3LocationConstraint struct {
+	XMLName xml.Name `xml:"LocationConstraint"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Region  string   `xml:",chardata"`
+}
+
+type s3Owner struct {
+	ID          string
+	DisplayName string
+}
+
+type s3Bucket struct {
+	Name         string
+	CreationDate string
+}
+
+type s3ListAllMyBucketsResult struct {
+	XMLName xml.Name   `xml:"ListAllMyBucketsResult"`
+	XMLNS   string     `xml:"xmlns,attr"`
+	Owner   s3Owner    `xml:"Owner"`
+	Buckets []s3Bucket `xml:"Buckets>Bucket"`
+}
+
+type s3Object struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass string
+}
+
+type s3CommonPrefix struct {
+	Prefix string
+}
+
+type s3ListBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	XMLNS                 string   `xml:"xmlns,attr"`
+	Name                  string
+	Prefix                string
+	Delimiter             string `xml:",omitempty"`
+	StartAfter            string `xml:",omitempty"`
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	EncodingType          string `xml:",omitempty"`
+	MaxKeys               int
+	KeyCount              int
+	IsTruncated           bool
+	Contents              []s3Object
+	CommonPrefixes        []s3CommonPrefix
+}
+
+type s3InitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	XMLNS    string   `xml:"xmlns,attr"`
+	Bucket   string
+	Key      string
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompleteMultipartUpload struct {
+	Parts []struct {
+		PartNumber int
+		ETag       string
+	} `xml:"Part"`
+}
+
+type s3CompleteMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	XMLNS    string   `xml:"xmlns,attr"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
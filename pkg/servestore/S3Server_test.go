@@ -0,0 +1,322 @@
+package servestore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// s3TestClient signs requests to an S3Server as an S3 client does
+type s3TestClient struct {
+	t       *testing.T
+	cluster *fakeCluster
+	url     string
+}
+
+func newS3TestClient(t *testing.T, blockSize int) *s3TestClient {
+	cluster := newFakeCluster()
+	server := httptest.NewServer(NewS3Server(cluster, "bucket", "us-east-1", map[string]string{"key": "secret"}, blockSize))
+	t.Cleanup(server.Close)
+	return &s3TestClient{t: t, cluster: cluster, url: server.URL}
+}
+
+func (c *s3TestClient) do(method string, target string, body string, header map[string]string) (*http.Response, string) {
+	request, err := http.NewRequest(method, c.url+target, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+	payloadHash := sha256.Sum256([]byte(body))
+	SignS3Request(request, hex.EncodeToString(payloadHash[:]), "key", "secret", "us-east-1", time.Now())
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return response, string(responseBody)
+}
+
+// errorCode returns the S3 error code of a response body
+func errorCode(body string) string {
+	var response struct {
+		Code string
+	}
+	xml.Unmarshal([]byte(body), &response)
+	return response.Code
+}
+
+func TestS3PutGetDeleteObject(t *testing.T) {
+	c := newS3TestClient(t, 4)
+
+	response, _ := c.do(http.MethodPut, "/bucket/docs/a.txt", "the quick brown fox", nil)
+	if response.StatusCode != http.StatusOK || response.Header.Get("ETag") == "" {
+		t.Fatalf("PUT a.txt: %s", response.Status)
+	}
+	fileMetaData := c.cluster.files["docs/a.txt"]
+	if fileMetaData.GetVersion() != 1 || fileMetaData.GetSize() != 19 || len(fileMetaData.GetBlockHashList()) != 5 || fileMetaData.GetBlockSize() != 4 {
+		t.Errorf("PUT a.txt stored %v", fileMetaData)
+	}
+
+	response, body := c.do(http.MethodGet, "/bucket/docs/a.txt", "", nil)
+	if response.StatusCode != http.StatusOK || body != "the quick brown fox" || response.Header.Get("ETag") != fileETag(fileMetaData) {
+		t.Errorf("GET a.txt: %s %q", response.Status, body)
+	}
+	response, body = c.do(http.MethodGet, "/bucket/docs/a.txt", "", map[string]string{"Range": "bytes=4-8"})
+	if response.StatusCode != http.StatusPartialContent || body != "quick" {
+		t.Errorf("GET a.txt bytes 4-8: %s %q", response.Status, body)
+	}
+	response, _ = c.do(http.MethodHead, "/bucket/docs/a.txt", "", nil)
+	if response.StatusCode != http.StatusOK || response.ContentLength != 19 {
+		t.Errorf("HEAD a.txt: %s, length %d", response.Status, response.ContentLength)
+	}
+
+	// Overwriting creates a new version, and only blocks not stored yet are uploaded
+	puts := 0
+	for _, blocks := range c.cluster.blocks {
+		puts += len(blocks)
+	}
+	c.do(http.MethodPut, "/bucket/docs/a.txt", "the quick brown cat", nil)
+	if c.cluster.files["docs/a.txt"].GetVersion() != 2 || len(c.cluster.blocks["a:1"]) != puts+1 {
+		t.Errorf("overwrite stored version %d, %d blocks", c.cluster.files["docs/a.txt"].GetVersion(), len(c.cluster.blocks["a:1"]))
+	}
+
+	response, _ = c.do(http.MethodDelete, "/bucket/docs/a.txt", "", nil)
+	if response.StatusCode != http.StatusNoContent || !isTombstone(c.cluster.files["docs/a.txt"]) || c.cluster.files["docs/a.txt"].GetVersion() != 3 {
+		t.Errorf("DELETE a.txt: %s, stored %v", response.Status, c.cluster.files["docs/a.txt"])
+	}
+	if response, body := c.do(http.MethodGet, "/bucket/docs/a.txt", "", nil); response.StatusCode != http.StatusNotFound || errorCode(body) != "NoSuchKey" {
+		t.Errorf("GET deleted a.txt: %s %s", response.Status, body)
+	}
+	if response, _ := c.do(http.MethodDelete, "/bucket/docs/a.txt", "", nil); response.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE deleted a.txt: %s", response.Status)
+	}
+}
+
+func TestS3RejectsBadRequests(t *testing.T) {
+	c := newS3TestClient(t, 4)
+
+	tests := []struct {
+		method string
+		target string
+		header map[string]string
+		code   string
+	}{
+		{http.MethodGet, "/other/a.txt", nil, "NoSuchBucket"},
+		{http.MethodPut, "/bucket/../a.txt", nil, "InvalidArgument"},
+		{http.MethodPut, "/bucket/dir/", nil, "InvalidArgument"},
+		{http.MethodPut, "/bucket/index.txt", nil, "InvalidArgument"},
+		{http.MethodPut, "/bucket/a.txt", map[string]string{"Content-MD5": "1B2M2Y8AsgTpgAmY7PhCfg=="}, "BadDigest"},
+		{http.MethodPost, "/bucket/a.txt", nil, "MethodNotAllowed"},
+		{http.MethodGet, "/bucket?list-type=2&max-keys=x", nil, "InvalidArgument"},
+		{http.MethodPut, "/bucket/a.txt?partNumber=1&uploadId=missing", nil, "NoSuchUpload"},
+	}
+	for _, test := range tests {
+		response, body := c.do(test.method, test.target, "data", test.header)
+		if errorCode(body) != test.code {
+			t.Errorf("%s %s: %s %s, want %s", test.method, test.target, response.Status, body, test.code)
+		}
+	}
+
+	// A body that does not match the hash it was signed with is not stored
+	request, _ := http.NewRequest(http.MethodPut, c.url+"/bucket/a.txt", strings.NewReader("tampered"))
+	SignS3Request(request, S3_EMPTY_PAYLOAD_HASH, "key", "secret", "us-east-1", time.Now())
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if errorCode(string(body)) != "XAmzContentSHA256Mismatch" || c.cluster.files["a.txt"] != nil {
+		t.Errorf("PUT tampered body: %s %s", response.Status, body)
+	}
+
+	// Unsigned requests are refused
+	response, err = http.Get(c.url + "/bucket/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("unsigned GET: %s, want 403", response.Status)
+	}
+}
+
+type testListBucketResult struct {
+	KeyCount              int
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key  string
+		Size int64
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
+
+func (c *s3TestClient) list(query string) *testListBucketResult {
+	response, body := c.do(http.MethodGet, "/bucket?list-type=2&"+query, "", nil)
+	if response.StatusCode != http.StatusOK {
+		c.t.Fatalf("list %s: %s %s", query, response.Status, body)
+	}
+	result := &testListBucketResult{}
+	if err := xml.Unmarshal([]byte(body), result); err != nil {
+		c.t.Fatal(err)
+	}
+	return result
+}
+
+func TestS3ListObjects(t *testing.T) {
+	c := newS3TestClient(t, 4)
+	for _, key := range []string{"a.txt", "docs/b.txt", "docs/c.txt", "docs/sub/d.txt", "e f.txt"} {
+		c.do(http.MethodPut, "/bucket/"+key, key, nil)
+	}
+	c.do(http.MethodDelete, "/bucket/docs/c.txt", "", nil)
+
+	result := c.list("")
+	if result.KeyCount != 4 || len(result.Contents) != 4 || result.Contents[1].Key != "docs/b.txt" || result.Contents[1].Size != 10 {
+		t.Errorf("list all: %+v", result)
+	}
+
+	result = c.list("delimiter=/&prefix=docs/")
+	if len(result.Contents) != 1 || result.Contents[0].Key != "docs/b.txt" || len(result.CommonPrefixes) != 1 || result.CommonPrefixes[0].Prefix != "docs/sub/" {
+		t.Errorf("list docs/: %+v", result)
+	}
+
+	// Pages continue after the last key or prefix listed
+	var listed []string
+	token := ""
+	for page := 0; page < 5; page++ {
+		result = c.list("delimiter=/&max-keys=1&continuation-token=" + token)
+		for _, object := range result.Contents {
+			listed = append(listed, object.Key)
+		}
+		for _, prefix := range result.CommonPrefixes {
+			listed = append(listed, prefix.Prefix)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if strings.Join(listed, ",") != "a.txt,docs/,e f.txt" {
+		t.Errorf("listed %v in pages of one", listed)
+	}
+
+	result = c.list("encoding-type=url&prefix=e")
+	if len(result.Contents) != 1 || result.Contents[0].Key != "e%20f.txt" {
+		t.Errorf("list with URL encoding: %+v", result)
+	}
+}
+
+func (c *s3TestClient) multipartUpload(key string, parts []string) (*http.Response, string) {
+	response, body := c.do(http.MethodPost, "/bucket/"+key+"?uploads", "", nil)
+	var initiated struct {
+		UploadId string
+	}
+	if err := xml.Unmarshal([]byte(body), &initiated); err != nil || initiated.UploadId == "" {
+		c.t.Fatalf("create multipart upload: %s %s", response.Status, body)
+	}
+
+	etags := make([]string, len(parts))
+	for i := len(parts) - 1; i >= 0; i-- { // Parts may be uploaded in any order
+		response, _ := c.do(http.MethodPut, fmt.Sprintf("/bucket/%s?partNumber=%d&uploadId=%s", key, i+1, initiated.UploadId), parts[i], nil)
+		if response.StatusCode != http.StatusOK {
+			c.t.Fatalf("upload part %d: %s", i+1, response.Status)
+		}
+		etags[i] = response.Header.Get("ETag")
+	}
+
+	var complete bytes.Buffer
+	complete.WriteString("<CompleteMultipartUpload>")
+	for i, etag := range etags {
+		fmt.Fprintf(&complete, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", i+1, etag)
+	}
+	complete.WriteString("</CompleteMultipartUpload>")
+	return c.do(http.MethodPost, "/bucket/"+key+"?uploadId="+initiated.UploadId, complete.String(), nil)
+}
+
+func TestS3MultipartUpload(t *testing.T) {
+	c := newS3TestClient(t, 4)
+
+	tests := []struct {
+		name   string
+		parts  []string
+		blocks int
+	}{
+		{"aligned", []string{"0123", "45678901", "23"}, 4},
+		{"unaligned", []string{"012", "3456789", "0123"}, 4},
+	}
+	for _, test := range tests {
+		key := test.name + ".txt"
+		response, body := c.multipartUpload(key, test.parts)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("complete %s upload: %s %s", test.name, response.Status, body)
+		}
+
+		fileMetaData := c.cluster.files[key]
+		want := strings.Join(test.parts, "")
+		if fileMetaData.GetSize() != int64(len(want)) || len(fileMetaData.GetBlockHashList()) != test.blocks {
+			t.Errorf("%s upload stored %v", test.name, fileMetaData)
+		}
+		if response, body := c.do(http.MethodGet, "/bucket/"+key, "", nil); body != want {
+			t.Errorf("GET %s after upload: %s %q, want %q", key, response.Status, body, want)
+		}
+		if response, body := c.do(http.MethodGet, "/bucket/"+key, "", map[string]string{"Range": "bytes=5-9"}); body != want[5:10] {
+			t.Errorf("GET %s bytes 5-9: %s %q, want %q", key, response.Status, body, want[5:10])
+		}
+	}
+}
+
+func TestS3MultipartUploadErrors(t *testing.T) {
+	c := newS3TestClient(t, 4)
+
+	_, body := c.do(http.MethodPost, "/bucket/a.txt?uploads", "", nil)
+	var initiated struct {
+		UploadId string
+	}
+	xml.Unmarshal([]byte(body), &initiated)
+	response, _ := c.do(http.MethodPut, "/bucket/a.txt?partNumber=1&uploadId="+initiated.UploadId, "part", nil)
+	etag := response.Header.Get("ETag")
+
+	tests := []struct {
+		complete string
+		code     string
+	}{
+		{"<CompleteMultipartUpload></CompleteMultipartUpload>", "MalformedXML"},
+		{`<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>"other"</ETag></Part></CompleteMultipartUpload>`, "InvalidPart"},
+		{"<CompleteMultipartUpload><Part><PartNumber>2</PartNumber><ETag>" + etag + "</ETag></Part></CompleteMultipartUpload>", "InvalidPart"},
+		{"<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>" + etag + "</ETag></Part>" +
+			"<Part><PartNumber>1</PartNumber><ETag>" + etag + "</ETag></Part></CompleteMultipartUpload>", "InvalidPartOrder"},
+	}
+	for _, test := range tests {
+		if response, body := c.do(http.MethodPost, "/bucket/a.txt?uploadId="+initiated.UploadId, test.complete, nil); errorCode(body) != test.code {
+			t.Errorf("complete with %s: %s %s, want %s", test.complete, response.Status, body, test.code)
+		}
+	}
+
+	if response, _ := c.do(http.MethodDelete, "/bucket/a.txt?uploadId="+initiated.UploadId, "", nil); response.StatusCode != http.StatusNoContent {
+		t.Errorf("abort upload: %s", response.Status)
+	}
+	if _, body := c.do(http.MethodPut, "/bucket/a.txt?partNumber=2&uploadId="+initiated.UploadId, "part", nil); errorCode(body) != "NoSuchUpload" {
+		t.Errorf("upload part after abort: %s", body)
+	}
+	if c.cluster.files["a.txt"] != nil {
+		t.Errorf("aborted upload stored %v", c.cluster.files["a.txt"])
+	}
+}
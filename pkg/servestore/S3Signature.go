@@ -0,0 +1,239 @@
+package servestore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWS Signature Version 4, which S3 clients sign their requests with
+const (
+	S3_SIGNING_ALGORITHM = "AWS4-HMAC-SHA256"
+	S3_SERVICE           = "s3"
+	S3_TIME_FORMAT       = "20060102T150405Z"
+	S3_DATE_FORMAT       = "20060102"
+	S3_UNSIGNED_PAYLOAD  = "UNSIGNED-PAYLOAD"
+	// Hash of an empty payload, which requests without a body are signed with
+	S3_EMPTY_PAYLOAD_HASH = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// How far the time a request was signed at may be from the time it is received
+const S3_MAX_CLOCK_SKEW = 15 * time.Minute
+
+// s3Authorization is the parsed Authorization header of a signed request
+type s3Authorization struct {
+	accessKey     string
+	date          string
+	region        string
+	signedHeaders []string
+	signature     string
+}
+
+// SignS3Request signs a request with AWS Signature Version 4 as an S3 client does. The host
+// and every header already set on the request are signed. payloadHash is the hex SHA-256
+// of the body, or S3_UNSIGNED_PAYLOAD.
+func SignS3Request(request *http.Request, payloadHash string, accessKey string, secretKey string, region string, now time.Time) {
+	amzDate := now.UTC().Format(S3_TIME_FORMAT)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"host"}
+	for name := range request.Header {
+		if name = strings.ToLower(name); name != "authorization" {
+			signedHeaders = append(signedHeaders, name)
+		}
+	}
+	sort.Strings(signedHeaders)
+
+	auth := &s3Authorization{accessKey: accessKey, date: amzDate[:len(S3_DATE_FORMAT)], region: region, signedHeaders: signedHeaders}
+	auth.signature = s3Signature(request, auth, amzDate, payloadHash, secretKey)
+	request.Header.Set("Authorization", auth.String())
+}
+
+func (auth *s3Authorization) scope() string {
+	return strings.Join([]string{auth.date, auth.region, S3_SERVICE, "aws4_request"}, "/")
+}
+
+func (auth *s3Authorization) String() string {
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		S3_SIGNING_ALGORITHM, auth.accessKey, auth.scope(), strings.Join(auth.signedHeaders, ";"), auth.signature)
+}
+
+// parseS3Authorization parses an Authorization header such as
+// "AWS4-HMAC-SHA256 Credential=key/20130524/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=hex"
+func parseS3Authorization(header string) (*s3Authorization, error) {
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || parts[0] != S3_SIGNING_ALGORITHM {
+		return nil, fmt.Errorf("unsupported authorization %q", parts[0])
+	}
+
+	auth := &s3Authorization{}
+	for _, field := range strings.Split(parts[1], ",") {
+		nameValue := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(nameValue) != 2 {
+			continue
+		}
+		name, value := nameValue[0], nameValue[1]
+		switch name {
+		case "Credential":
+			credential := strings.Split(value, "/")
+			if len(credential) != 5 || credential[3] != S3_SERVICE || credential[4] != "aws4_request" {
+				return nil, fmt.Errorf("malformed credential %q", value)
+			}
+			auth.accessKey, auth.date, auth.region = credential[0], credential[1], credential[2]
+		case "SignedHeaders":
+			auth.signedHeaders = strings.Split(value, ";")
+		case "Signature":
+			auth.signature = value
+		}
+	}
+	if auth.accessKey == "" || len(auth.signedHeaders) == 0 || auth.signature == "" {
+		return nil, fmt.Errorf("incomplete authorization %q", header)
+	}
+	return auth, nil
+}
+
+// s3Signature returns the hex signature of a request for the signed headers of auth
+func s3Signature(request *http.Request, auth *s3Authorization, amzDate string, payloadHash string, secretKey string) string {
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		s3URIEncode(request.URL.Path, false),
+		s3CanonicalQuery(request.URL.Query()),
+		s3CanonicalHeaders(request, auth.signedHeaders),
+		strings.Join(auth.signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{S3_SIGNING_ALGORITHM, amzDate, auth.scope(), hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{auth.date, auth.region, S3_SERVICE, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3URIEncode percent-encodes every byte but unreserved characters, and slashes unless
+// encodeSlash is set
+func s3URIEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	var params []string
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, s3URIEncode(name, true)+"="+s3URIEncode(value, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// s3CanonicalHeaders returns the signed headers as lowercase "name:value" lines. The host
+// and content length of a request are not kept among its headers.
+func s3CanonicalHeaders(request *http.Request, signedHeaders []string) string {
+	var canonical strings.Builder
+	for _, name := range signedHeaders {
+		var values []string
+		switch {
+		case name == "host" && request.Host != "":
+			values = []string{request.Host}
+		case name == "host":
+			values = []string{request.URL.Host}
+		case name == "content-length" && request.Header.Get("Content-Length") == "":
+			values = []string{fmt.Sprint(request.ContentLength)}
+		default:
+			values = request.Header.Values(name)
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		canonical.WriteString(name + ":" + strings.Join(trimmed, ",") + "\n")
+	}
+	return canonical.String()
+}
+
+// verifyS3Request checks the signature of a request against the secret key of the access
+// key it was signed with, and returns the payload hash it was signed with
+func verifyS3Request(request *http.Request, credentials map[string]string, region string, now time.Time) (string, error) {
+	header := request.Header.Get("Authorization")
+	if header == "" {
+		return "", s3ErrAccessDenied
+	}
+	auth, err := parseS3Authorization(header)
+	if err != nil {
+		return "", &s3Error{http.StatusBadRequest, "AuthorizationHeaderMalformed", err.Error()}
+	}
+	secretKey, ok := credentials[auth.accessKey]
+	if !ok {
+		return "", s3ErrInvalidAccessKeyId
+	}
+	if auth.region != region {
+		return "", &s3Error{http.StatusBadRequest, "AuthorizationHeaderMalformed", fmt.Sprintf("the region %q is wrong; expecting %q", auth.region, region)}
+	}
+	if !containsString(auth.signedHeaders, "host") {
+		return "", &s3Error{http.StatusBadRequest, "AuthorizationHeaderMalformed", "the host header is not signed"}
+	}
+
+	amzDate := request.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse(S3_TIME_FORMAT, amzDate)
+	if err != nil || !strings.HasPrefix(amzDate, auth.date) {
+		return "", &s3Error{http.StatusBadRequest, "AuthorizationHeaderMalformed", "missing or invalid X-Amz-Date"}
+	}
+	if skew := now.Sub(signedAt); skew > S3_MAX_CLOCK_SKEW || skew < -S3_MAX_CLOCK_SKEW {
+		return "", s3ErrRequestTimeTooSkewed
+	}
+
+	// Requests without a body are signed with the hash of an empty payload by clients that do
+	// not send it
+	payloadHash := request.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" && request.ContentLength == 0 {
+		payloadHash = S3_EMPTY_PAYLOAD_HASH
+	}
+	switch {
+	case payloadHash == S3_UNSIGNED_PAYLOAD:
+	case strings.HasPrefix(payloadHash, "STREAMING-"):
+		return "", &s3Error{http.StatusNotImplemented, "NotImplemented", "chunked payload signing is not supported"}
+	case len(payloadHash) != sha256.Size*2:
+		return "", &s3Error{http.StatusBadRequest, "InvalidArgument", "missing or invalid X-Amz-Content-Sha256"}
+	}
+
+	signature := s3Signature(request, auth, amzDate, payloadHash, secretKey)
+	if !hmac.Equal([]byte(signature), []byte(auth.signature)) {
+		return "", s3ErrSignatureDoesNotMatch
+	}
+	return payloadHash, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, element := range list {
+		if element == s {
+			return true
+		}
+	}
+	return false
+}
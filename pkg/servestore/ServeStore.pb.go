@@ -21,6 +21,55 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type MemberState int32
+
+const (
+	MemberState_LIVE    MemberState = 0
+	MemberState_SUSPECT MemberState = 1
+	MemberState_DEAD    MemberState = 2
+)
+
+// Enum value maps for MemberState.
+var (
+	MemberState_name = map[int32]string{
+		0: "LIVE",
+		1: "SUSPECT",
+		2: "DEAD",
+	}
+	MemberState_value = map[string]int32{
+		"LIVE":    0,
+		"SUSPECT": 1,
+		"DEAD":    2,
+	}
+)
+
+func (x MemberState) Enum() *MemberState {
+	p := new(MemberState)
+	*p = x
+	return p
+}
+
+func (x MemberState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MemberState) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_servestore_ServeStore_proto_enumTypes[0].Descriptor()
+}
+
+func (MemberState) Type() protoreflect.EnumType {
+	return &file_pkg_servestore_ServeStore_proto_enumTypes[0]
+}
+
+func (x MemberState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MemberState.Descriptor instead.
+func (MemberState) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{0}
+}
+
 type BlockHash struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -115,6 +164,108 @@ func (x *BlockHashes) GetHashes() []string {
 	return nil
 }
 
+type BlockHashRange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Start string `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End   string `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+}
+
+func (x *BlockHashRange) Reset() {
+	*x = BlockHashRange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockHashRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockHashRange) ProtoMessage() {}
+
+func (x *BlockHashRange) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockHashRange.ProtoReflect.Descriptor instead.
+func (*BlockHashRange) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BlockHashRange) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *BlockHashRange) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+type BlockSizes struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sizes map[string]int64 `protobuf:"bytes,1,rep,name=sizes,proto3" json:"sizes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *BlockSizes) Reset() {
+	*x = BlockSizes{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockSizes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockSizes) ProtoMessage() {}
+
+func (x *BlockSizes) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockSizes.ProtoReflect.Descriptor instead.
+func (*BlockSizes) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BlockSizes) GetSizes() map[string]int64 {
+	if x != nil {
+		return x.Sizes
+	}
+	return nil
+}
+
 type Block struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -127,7 +278,7 @@ type Block struct {
 func (x *Block) Reset() {
 	*x = Block{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[2]
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -140,7 +291,7 @@ func (x *Block) String() string {
 func (*Block) ProtoMessage() {}
 
 func (x *Block) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[2]
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -153,7 +304,7 @@ func (x *Block) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Block.ProtoReflect.Descriptor instead.
 func (*Block) Descriptor() ([]byte, []int) {
-	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{2}
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Block) GetBlockData() []byte {
@@ -181,7 +332,7 @@ type Success struct {
 func (x *Success) Reset() {
 	*x = Success{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[3]
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -194,7 +345,7 @@ func (x *Success) String() string {
 func (*Success) ProtoMessage() {}
 
 func (x *Success) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[3]
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -207,7 +358,7 @@ func (x *Success) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Success.ProtoReflect.Descriptor instead.
 func (*Success) Descriptor() ([]byte, []int) {
-	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{3}
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Success) GetFlag() bool {
@@ -222,15 +373,24 @@ type FileMetaData struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Filename      string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Version       int32    `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
-	BlockHashList []string `protobuf:"bytes,3,rep,name=blockHashList,proto3" json:"blockHashList,omitempty"`
+	Filename       string            `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version        int32             `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	BlockHashList  []string          `protobuf:"bytes,3,rep,name=blockHashList,proto3" json:"blockHashList,omitempty"`
+	Size           int64             `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	Mode           uint32            `protobuf:"varint,5,opt,name=mode,proto3" json:"mode,omitempty"`
+	Mtime          int64             `protobuf:"varint,6,opt,name=mtime,proto3" json:"mtime,omitempty"`
+	Xattrs         map[string][]byte `protobuf:"bytes,7,rep,name=xattrs,proto3" json:"xattrs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	SymlinkTarget  string            `protobuf:"bytes,8,opt,name=symlinkTarget,proto3" json:"symlinkTarget,omitempty"`
+	HardlinkTarget string            `protobuf:"bytes,9,opt,name=hardlinkTarget,proto3" json:"hardlinkTarget,omitempty"`
+	BlockSize      int32             `protobuf:"varint,10,opt,name=blockSize,proto3" json:"blockSize,omitempty"`
+	RenamedFrom    string            `protobuf:"bytes,11,opt,name=renamedFrom,proto3" json:"renamedFrom,omitempty"`
+	RenamedTo      string            `protobuf:"bytes,12,opt,name=renamedTo,proto3" json:"renamedTo,omitempty"`
 }
 
 func (x *FileMetaData) Reset() {
 	*x = FileMetaData{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[4]
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -243,7 +403,7 @@ func (x *FileMetaData) String() string {
 func (*FileMetaData) ProtoMessage() {}
 
 func (x *FileMetaData) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[4]
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -256,7 +416,7 @@ func (x *FileMetaData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileMetaData.ProtoReflect.Descriptor instead.
 func (*FileMetaData) Descriptor() ([]byte, []int) {
-	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{4}
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *FileMetaData) GetFilename() string {
@@ -280,6 +440,69 @@ func (x *FileMetaData) GetBlockHashList() []string {
 	return nil
 }
 
+func (x *FileMetaData) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileMetaData) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *FileMetaData) GetMtime() int64 {
+	if x != nil {
+		return x.Mtime
+	}
+	return 0
+}
+
+func (x *FileMetaData) GetXattrs() map[string][]byte {
+	if x != nil {
+		return x.Xattrs
+	}
+	return nil
+}
+
+func (x *FileMetaData) GetSymlinkTarget() string {
+	if x != nil {
+		return x.SymlinkTarget
+	}
+	return ""
+}
+
+func (x *FileMetaData) GetHardlinkTarget() string {
+	if x != nil {
+		return x.HardlinkTarget
+	}
+	return ""
+}
+
+func (x *FileMetaData) GetBlockSize() int32 {
+	if x != nil {
+		return x.BlockSize
+	}
+	return 0
+}
+
+func (x *FileMetaData) GetRenamedFrom() string {
+	if x != nil {
+		return x.RenamedFrom
+	}
+	return ""
+}
+
+func (x *FileMetaData) GetRenamedTo() string {
+	if x != nil {
+		return x.RenamedTo
+	}
+	return ""
+}
+
 type FileInfoMap struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -291,7 +514,7 @@ type FileInfoMap struct {
 func (x *FileInfoMap) Reset() {
 	*x = FileInfoMap{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[5]
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -304,7 +527,7 @@ func (x *FileInfoMap) String() string {
 func (*FileInfoMap) ProtoMessage() {}
 
 func (x *FileInfoMap) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[5]
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -317,7 +540,7 @@ func (x *FileInfoMap) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileInfoMap.ProtoReflect.Descriptor instead.
 func (*FileInfoMap) Descriptor() ([]byte, []int) {
-	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{5}
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *FileInfoMap) GetFileInfoMap() map[string]*FileMetaData {
@@ -338,7 +561,7 @@ type Version struct {
 func (x *Version) Reset() {
 	*x = Version{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[6]
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -351,7 +574,7 @@ func (x *Version) String() string {
 func (*Version) ProtoMessage() {}
 
 func (x *Version) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[6]
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -364,7 +587,7 @@ func (x *Version) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Version.ProtoReflect.Descriptor instead.
 func (*Version) Descriptor() ([]byte, []int) {
-	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{6}
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *Version) GetVersion() int32 {
@@ -374,31 +597,31 @@ func (x *Version) GetVersion() int32 {
 	return 0
 }
 
-type BlockStoreAddr struct {
+type FileName struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Addr string `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
 }
 
-func (x *BlockStoreAddr) Reset() {
-	*x = BlockStoreAddr{}
+func (x *FileName) Reset() {
+	*x = FileName{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[7]
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *BlockStoreAddr) String() string {
+func (x *FileName) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BlockStoreAddr) ProtoMessage() {}
+func (*FileName) ProtoMessage() {}
 
-func (x *BlockStoreAddr) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[7]
+func (x *FileName) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -409,170 +632,1040 @@ func (x *BlockStoreAddr) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BlockStoreAddr.ProtoReflect.Descriptor instead.
-func (*BlockStoreAddr) Descriptor() ([]byte, []int) {
-	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use FileName.ProtoReflect.Descriptor instead.
+func (*FileName) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *BlockStoreAddr) GetAddr() string {
+func (x *FileName) GetFilename() string {
 	if x != nil {
-		return x.Addr
+		return x.Filename
 	}
 	return ""
 }
 
-var File_pkg_servestore_ServeStore_proto protoreflect.FileDescriptor
+type FileRename struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_pkg_servestore_ServeStore_proto_rawDesc = []byte{
-	0x0a, 0x1d, 0x70, 0x6b, 0x67, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2f,
-	0x53, 0x75, 0x72, 0x66, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
-	0x09, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67,
-	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74,
-	0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x1f, 0x0a, 0x09, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
-	0x48, 0x61, 0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x22, 0x25, 0x0a, 0x0b, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22,
-	0x43, 0x0a, 0x05, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63,
-	0x6b, 0x44, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x62, 0x6c, 0x6f,
-	0x63, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x53,
-	0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
-	0x53, 0x69, 0x7a, 0x65, 0x22, 0x1d, 0x0a, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12,
-	0x12, 0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x66,
-	0x6c, 0x61, 0x67, 0x22, 0x6a, 0x0a, 0x0c, 0x46, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x44,
-	0x61, 0x74, 0x61, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12,
-	0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x62, 0x6c, 0x6f,
-	0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09,
-	0x52, 0x0d, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x22,
-	0xb1, 0x01, 0x0a, 0x0b, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x12,
-	0x49, 0x0a, 0x0b, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65,
-	0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x2e, 0x46, 0x69, 0x6c,
-	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0b, 0x66,
-	0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x1a, 0x57, 0x0a, 0x10, 0x46, 0x69,
-	0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
-	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
-	0x12, 0x2d, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x17, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65,
-	0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
-	0x02, 0x38, 0x01, 0x22, 0x23, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x18,
-	0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x24, 0x0a, 0x0e, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x41, 0x64, 0x64, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x64,
-	0x64, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x64, 0x72, 0x32, 0xb5,
-	0x01, 0x0a, 0x0a, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x12, 0x34, 0x0a,
-	0x08, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x14, 0x2e, 0x73, 0x75, 0x72, 0x66,
-	0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x1a,
-	0x10, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x22, 0x00, 0x12, 0x32, 0x0a, 0x08, 0x50, 0x75, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12,
-	0x10, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x1a, 0x12, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x53, 0x75,
-	0x63, 0x63, 0x65, 0x73, 0x73, 0x22, 0x00, 0x12, 0x3d, 0x0a, 0x09, 0x48, 0x61, 0x73, 0x42, 0x6c,
-	0x6f, 0x63, 0x6b, 0x73, 0x12, 0x16, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65,
-	0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x1a, 0x16, 0x2e, 0x73,
-	0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61,
-	0x73, 0x68, 0x65, 0x73, 0x22, 0x00, 0x32, 0xd6, 0x01, 0x0a, 0x09, 0x4d, 0x65, 0x74, 0x61, 0x53,
-	0x74, 0x6f, 0x72, 0x65, 0x12, 0x42, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x49,
-	0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x16,
-	0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49,
-	0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x22, 0x00, 0x12, 0x3b, 0x0a, 0x0a, 0x55, 0x70, 0x64, 0x61,
-	0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x17, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f,
-	0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74, 0x61, 0x1a,
-	0x12, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x73,
-	0x69, 0x6f, 0x6e, 0x22, 0x00, 0x12, 0x48, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x41, 0x64, 0x64, 0x72, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70,
-	0x74, 0x79, 0x1a, 0x19, 0x2e, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42,
-	0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x41, 0x64, 0x64, 0x72, 0x22, 0x00, 0x42,
-	0x1c, 0x5a, 0x1a, 0x63, 0x73, 0x65, 0x32, 0x32, 0x34, 0x2f, 0x70, 0x72, 0x6f, 0x6a, 0x34, 0x2f,
-	0x70, 0x6b, 0x67, 0x2f, 0x73, 0x75, 0x72, 0x66, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+	OldFilename  string        `protobuf:"bytes,1,opt,name=oldFilename,proto3" json:"oldFilename,omitempty"`
+	OldVersion   int32         `protobuf:"varint,2,opt,name=oldVersion,proto3" json:"oldVersion,omitempty"`
+	FileMetaData *FileMetaData `protobuf:"bytes,3,opt,name=fileMetaData,proto3" json:"fileMetaData,omitempty"`
 }
 
-var (
-	file_pkg_servestore_ServeStore_proto_rawDescOnce sync.Once
-	file_pkg_servestore_ServeStore_proto_rawDescData = file_pkg_servestore_ServeStore_proto_rawDesc
-)
+func (x *FileRename) Reset() {
+	*x = FileRename{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-func file_pkg_servestore_ServeStore_proto_rawDescGZIP() []byte {
-	file_pkg_servestore_ServeStore_proto_rawDescOnce.Do(func() {
-		file_pkg_servestore_ServeStore_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_servestore_ServeStore_proto_rawDescData)
-	})
-	return file_pkg_servestore_ServeStore_proto_rawDescData
+func (x *FileRename) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_pkg_servestore_ServeStore_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
-var file_pkg_servestore_ServeStore_proto_goTypes = []interface{}{
-	(*BlockHash)(nil),      // 0: servestore.BlockHash
-	(*BlockHashes)(nil),    // 1: servestore.BlockHashes
-	(*Block)(nil),          // 2: servestore.Block
-	(*Success)(nil),        // 3: servestore.Success
-	(*FileMetaData)(nil),   // 4: servestore.FileMetaData
-	(*FileInfoMap)(nil),    // 5: servestore.FileInfoMap
-	(*Version)(nil),        // 6: servestore.Version
-	(*BlockStoreAddr)(nil), // 7: servestore.BlockStoreAddr
-	nil,                    // 8: servestore.FileInfoMap.FileInfoMapEntry
-	(*empty.Empty)(nil),    // 9: google.protobuf.Empty
+func (*FileRename) ProtoMessage() {}
+
+func (x *FileRename) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_pkg_servestore_ServeStore_proto_depIdxs = []int32{
-	8, // 0: servestore.FileInfoMap.fileInfoMap:type_name -> servestore.FileInfoMap.FileInfoMapEntry
-	4, // 1: servestore.FileInfoMap.FileInfoMapEntry.value:type_name -> servestore.FileMetaData
-	0, // 2: servestore.BlockStore.GetBlock:input_type -> servestore.BlockHash
-	2, // 3: servestore.BlockStore.PutBlock:input_type -> servestore.Block
-	1, // 4: servestore.BlockStore.HasBlocks:input_type -> servestore.BlockHashes
-	9, // 5: servestore.MetaStore.GetFileInfoMap:input_type -> google.protobuf.Empty
-	4, // 6: servestore.MetaStore.UpdateFile:input_type -> servestore.FileMetaData
-	9, // 7: servestore.MetaStore.GetBlockStoreAddr:input_type -> google.protobuf.Empty
-	2, // 8: servestore.BlockStore.GetBlock:output_type -> servestore.Block
-	3, // 9: servestore.BlockStore.PutBlock:output_type -> servestore.Success
-	1, // 10: servestore.BlockStore.HasBlocks:output_type -> servestore.BlockHashes
-	5, // 11: servestore.MetaStore.GetFileInfoMap:output_type -> servestore.FileInfoMap
-	6, // 12: servestore.MetaStore.UpdateFile:output_type -> servestore.Version
-	7, // 13: servestore.MetaStore.GetBlockStoreAddr:output_type -> servestore.BlockStoreAddr
-	8, // [8:14] is the sub-list for method output_type
-	2, // [2:8] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+
+// Deprecated: Use FileRename.ProtoReflect.Descriptor instead.
+func (*FileRename) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{10}
 }
 
-func init() { file_pkg_servestore_ServeStore_proto_init() }
-func file_pkg_servestore_ServeStore_proto_init() {
-	if File_pkg_servestore_ServeStore_proto != nil {
-		return
+func (x *FileRename) GetOldFilename() string {
+	if x != nil {
+		return x.OldFilename
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_pkg_servestore_ServeStore_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BlockHash); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_pkg_servestore_ServeStore_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BlockHashes); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_pkg_servestore_ServeStore_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Block); i {
-			case 0:
-				return &v.state
+	return ""
+}
+
+func (x *FileRename) GetOldVersion() int32 {
+	if x != nil {
+		return x.OldVersion
+	}
+	return 0
+}
+
+func (x *FileRename) GetFileMetaData() *FileMetaData {
+	if x != nil {
+		return x.FileMetaData
+	}
+	return nil
+}
+
+type FileBatch struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Files []*FileMetaData `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+}
+
+func (x *FileBatch) Reset() {
+	*x = FileBatch{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileBatch) ProtoMessage() {}
+
+func (x *FileBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileBatch.ProtoReflect.Descriptor instead.
+func (*FileBatch) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *FileBatch) GetFiles() []*FileMetaData {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type FileConflict struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Filename      string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version       int32  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	LatestVersion int32  `protobuf:"varint,3,opt,name=latestVersion,proto3" json:"latestVersion,omitempty"`
+}
+
+func (x *FileConflict) Reset() {
+	*x = FileConflict{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileConflict) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileConflict) ProtoMessage() {}
+
+func (x *FileConflict) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileConflict.ProtoReflect.Descriptor instead.
+func (*FileConflict) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *FileConflict) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileConflict) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *FileConflict) GetLatestVersion() int32 {
+	if x != nil {
+		return x.LatestVersion
+	}
+	return 0
+}
+
+type FileConflicts struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Conflicts []*FileConflict `protobuf:"bytes,1,rep,name=conflicts,proto3" json:"conflicts,omitempty"`
+}
+
+func (x *FileConflicts) Reset() {
+	*x = FileConflicts{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileConflicts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileConflicts) ProtoMessage() {}
+
+func (x *FileConflicts) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileConflicts.ProtoReflect.Descriptor instead.
+func (*FileConflicts) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *FileConflicts) GetConflicts() []*FileConflict {
+	if x != nil {
+		return x.Conflicts
+	}
+	return nil
+}
+
+type FileVersions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Versions []*FileMetaData `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+}
+
+func (x *FileVersions) Reset() {
+	*x = FileVersions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileVersions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileVersions) ProtoMessage() {}
+
+func (x *FileVersions) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileVersions.ProtoReflect.Descriptor instead.
+func (*FileVersions) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *FileVersions) GetVersions() []*FileMetaData {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type BlockStoreAddr struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Addr string `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+}
+
+func (x *BlockStoreAddr) Reset() {
+	*x = BlockStoreAddr{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockStoreAddr) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockStoreAddr) ProtoMessage() {}
+
+func (x *BlockStoreAddr) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockStoreAddr.ProtoReflect.Descriptor instead.
+func (*BlockStoreAddr) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *BlockStoreAddr) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+type BlockStoreRing struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Addrs             []string         `protobuf:"bytes,1,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	VirtualNodes      int32            `protobuf:"varint,2,opt,name=virtualNodes,proto3" json:"virtualNodes,omitempty"`
+	Weights           map[string]int32 `protobuf:"bytes,3,rep,name=weights,proto3" json:"weights,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	ReplicationFactor int32            `protobuf:"varint,4,opt,name=replicationFactor,proto3" json:"replicationFactor,omitempty"`
+	WriteQuorum       int32            `protobuf:"varint,5,opt,name=writeQuorum,proto3" json:"writeQuorum,omitempty"`
+	ReadQuorum        int32            `protobuf:"varint,6,opt,name=readQuorum,proto3" json:"readQuorum,omitempty"`
+	Previous          *BlockStoreRing  `protobuf:"bytes,7,opt,name=previous,proto3" json:"previous,omitempty"`
+	Epoch             int64            `protobuf:"varint,8,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	SuspectAddrs      []string         `protobuf:"bytes,9,rep,name=suspectAddrs,proto3" json:"suspectAddrs,omitempty"`
+}
+
+func (x *BlockStoreRing) Reset() {
+	*x = BlockStoreRing{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockStoreRing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockStoreRing) ProtoMessage() {}
+
+func (x *BlockStoreRing) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockStoreRing.ProtoReflect.Descriptor instead.
+func (*BlockStoreRing) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *BlockStoreRing) GetAddrs() []string {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *BlockStoreRing) GetVirtualNodes() int32 {
+	if x != nil {
+		return x.VirtualNodes
+	}
+	return 0
+}
+
+func (x *BlockStoreRing) GetWeights() map[string]int32 {
+	if x != nil {
+		return x.Weights
+	}
+	return nil
+}
+
+func (x *BlockStoreRing) GetReplicationFactor() int32 {
+	if x != nil {
+		return x.ReplicationFactor
+	}
+	return 0
+}
+
+func (x *BlockStoreRing) GetWriteQuorum() int32 {
+	if x != nil {
+		return x.WriteQuorum
+	}
+	return 0
+}
+
+func (x *BlockStoreRing) GetReadQuorum() int32 {
+	if x != nil {
+		return x.ReadQuorum
+	}
+	return 0
+}
+
+func (x *BlockStoreRing) GetPrevious() *BlockStoreRing {
+	if x != nil {
+		return x.Previous
+	}
+	return nil
+}
+
+func (x *BlockStoreRing) GetEpoch() int64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+func (x *BlockStoreRing) GetSuspectAddrs() []string {
+	if x != nil {
+		return x.SuspectAddrs
+	}
+	return nil
+}
+
+type BlockStoreHeartbeat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Addr   string `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	Weight int32  `protobuf:"varint,2,opt,name=weight,proto3" json:"weight,omitempty"`
+}
+
+func (x *BlockStoreHeartbeat) Reset() {
+	*x = BlockStoreHeartbeat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockStoreHeartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockStoreHeartbeat) ProtoMessage() {}
+
+func (x *BlockStoreHeartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockStoreHeartbeat.ProtoReflect.Descriptor instead.
+func (*BlockStoreHeartbeat) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *BlockStoreHeartbeat) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *BlockStoreHeartbeat) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+type RingEpoch struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Epoch int64 `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+}
+
+func (x *RingEpoch) Reset() {
+	*x = RingEpoch{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RingEpoch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RingEpoch) ProtoMessage() {}
+
+func (x *RingEpoch) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RingEpoch.ProtoReflect.Descriptor instead.
+func (*RingEpoch) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RingEpoch) GetEpoch() int64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+type BlockStoreMember struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Addr          string      `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	State         MemberState `protobuf:"varint,2,opt,name=state,proto3,enum=servestore.MemberState" json:"state,omitempty"`
+	LastHeartbeat int64       `protobuf:"varint,3,opt,name=lastHeartbeat,proto3" json:"lastHeartbeat,omitempty"`
+	Weight        int32       `protobuf:"varint,4,opt,name=weight,proto3" json:"weight,omitempty"`
+	OnRing        bool        `protobuf:"varint,5,opt,name=onRing,proto3" json:"onRing,omitempty"`
+}
+
+func (x *BlockStoreMember) Reset() {
+	*x = BlockStoreMember{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockStoreMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockStoreMember) ProtoMessage() {}
+
+func (x *BlockStoreMember) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockStoreMember.ProtoReflect.Descriptor instead.
+func (*BlockStoreMember) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *BlockStoreMember) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *BlockStoreMember) GetState() MemberState {
+	if x != nil {
+		return x.State
+	}
+	return MemberState_LIVE
+}
+
+func (x *BlockStoreMember) GetLastHeartbeat() int64 {
+	if x != nil {
+		return x.LastHeartbeat
+	}
+	return 0
+}
+
+func (x *BlockStoreMember) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *BlockStoreMember) GetOnRing() bool {
+	if x != nil {
+		return x.OnRing
+	}
+	return false
+}
+
+type Membership struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Members []*BlockStoreMember `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	Epoch   int64               `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+}
+
+func (x *Membership) Reset() {
+	*x = Membership{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_servestore_ServeStore_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Membership) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Membership) ProtoMessage() {}
+
+func (x *Membership) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_servestore_ServeStore_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Membership.ProtoReflect.Descriptor instead.
+func (*Membership) Descriptor() ([]byte, []int) {
+	return file_pkg_servestore_ServeStore_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Membership) GetMembers() []*BlockStoreMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *Membership) GetEpoch() int64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+var File_pkg_servestore_ServeStore_proto protoreflect.FileDescriptor
+
+var file_pkg_servestore_ServeStore_proto_rawDesc = []byte{
+	0x0a, 0x1f, 0x70, 0x6b, 0x67, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65,
+	0x2f, 0x53, 0x65, 0x72, 0x76, 0x65, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x1a, 0x1b, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x65,
+	0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x1f, 0x0a, 0x09, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x22, 0x25, 0x0a, 0x0b, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x61,
+	0x73, 0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x68, 0x61, 0x73, 0x68,
+	0x65, 0x73, 0x22, 0x38, 0x0a, 0x0e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x52,
+	0x61, 0x6e, 0x67, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x22, 0x7f, 0x0a, 0x0a,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x73, 0x12, 0x37, 0x0a, 0x05, 0x73, 0x69,
+	0x7a, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x69, 0x7a, 0x65,
+	0x73, 0x2e, 0x53, 0x69, 0x7a, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x05, 0x73, 0x69,
+	0x7a, 0x65, 0x73, 0x1a, 0x38, 0x0a, 0x0a, 0x53, 0x69, 0x7a, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x43, 0x0a,
+	0x05, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x44,
+	0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x44, 0x61, 0x74, 0x61, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x69, 0x7a,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x69,
+	0x7a, 0x65, 0x22, 0x1d, 0x0a, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x12, 0x0a,
+	0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x66, 0x6c, 0x61,
+	0x67, 0x22, 0xcd, 0x03, 0x0a, 0x0c, 0x46, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61,
+	0x74, 0x61, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x62, 0x6c, 0x6f, 0x63,
+	0x6b, 0x48, 0x61, 0x73, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x0d, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69,
+	0x7a, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x74, 0x69, 0x6d, 0x65, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6d, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x3c, 0x0a, 0x06,
+	0x78, 0x61, 0x74, 0x74, 0x72, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4d, 0x65,
+	0x74, 0x61, 0x44, 0x61, 0x74, 0x61, 0x2e, 0x58, 0x61, 0x74, 0x74, 0x72, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x06, 0x78, 0x61, 0x74, 0x74, 0x72, 0x73, 0x12, 0x24, 0x0a, 0x0d, 0x73, 0x79,
+	0x6d, 0x6c, 0x69, 0x6e, 0x6b, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0d, 0x73, 0x79, 0x6d, 0x6c, 0x69, 0x6e, 0x6b, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x12, 0x26, 0x0a, 0x0e, 0x68, 0x61, 0x72, 0x64, 0x6c, 0x69, 0x6e, 0x6b, 0x54, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x68, 0x61, 0x72, 0x64, 0x6c, 0x69,
+	0x6e, 0x6b, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63,
+	0x6b, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x72, 0x65, 0x6e, 0x61, 0x6d, 0x65,
+	0x64, 0x46, 0x72, 0x6f, 0x6d, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x6e,
+	0x61, 0x6d, 0x65, 0x64, 0x46, 0x72, 0x6f, 0x6d, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x6e, 0x61,
+	0x6d, 0x65, 0x64, 0x54, 0x6f, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x6e,
+	0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x1a, 0x39, 0x0a, 0x0b, 0x58, 0x61, 0x74, 0x74, 0x72, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0xb3, 0x01, 0x0a, 0x0b, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61,
+	0x70, 0x12, 0x4a, 0x0a, 0x0b, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74,
+	0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x2e,
+	0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x0b, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x1a, 0x58, 0x0a,
+	0x10, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x2e, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e,
+	0x46, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x23, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x26, 0x0a, 0x08,
+	0x46, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65,
+	0x6e, 0x61, 0x6d, 0x65, 0x22, 0x8c, 0x01, 0x0a, 0x0a, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x6f, 0x6c, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x6c, 0x64, 0x46, 0x69, 0x6c,
+	0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x6f, 0x6c, 0x64, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x6f, 0x6c, 0x64, 0x56, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x3c, 0x0a, 0x0c, 0x66, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74,
+	0x61, 0x44, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74,
+	0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x0c, 0x66, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x44,
+	0x61, 0x74, 0x61, 0x22, 0x3b, 0x0a, 0x09, 0x46, 0x69, 0x6c, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68,
+	0x12, 0x2e, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x18, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c,
+	0x65, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73,
+	0x22, 0x6a, 0x0a, 0x0c, 0x46, 0x69, 0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x6c, 0x69, 0x63, 0x74,
+	0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x6c, 0x61, 0x74, 0x65, 0x73, 0x74,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x6c,
+	0x61, 0x74, 0x65, 0x73, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x47, 0x0a, 0x0d,
+	0x46, 0x69, 0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x6c, 0x69, 0x63, 0x74, 0x73, 0x12, 0x36, 0x0a,
+	0x09, 0x63, 0x6f, 0x6e, 0x66, 0x6c, 0x69, 0x63, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x18, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69,
+	0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x6c, 0x69, 0x63, 0x74, 0x52, 0x09, 0x63, 0x6f, 0x6e, 0x66,
+	0x6c, 0x69, 0x63, 0x74, 0x73, 0x22, 0x44, 0x0a, 0x0c, 0x46, 0x69, 0x6c, 0x65, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x34, 0x0a, 0x08, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73,
+	0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74,
+	0x61, 0x52, 0x08, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x24, 0x0a, 0x0e, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x41, 0x64, 0x64, 0x72, 0x12, 0x12, 0x0a,
+	0x04, 0x61, 0x64, 0x64, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x64,
+	0x72, 0x22, 0xab, 0x03, 0x0a, 0x0e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65,
+	0x52, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x12, 0x22, 0x0a, 0x0c, 0x76, 0x69,
+	0x72, 0x74, 0x75, 0x61, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x0c, 0x76, 0x69, 0x72, 0x74, 0x75, 0x61, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x41,
+	0x0a, 0x07, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x27, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x69, 0x6e, 0x67, 0x2e, 0x57, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74,
+	0x73, 0x12, 0x2c, 0x0a, 0x11, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x11, 0x72, 0x65,
+	0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x12,
+	0x20, 0x0a, 0x0b, 0x77, 0x72, 0x69, 0x74, 0x65, 0x51, 0x75, 0x6f, 0x72, 0x75, 0x6d, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x77, 0x72, 0x69, 0x74, 0x65, 0x51, 0x75, 0x6f, 0x72, 0x75,
+	0x6d, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x61, 0x64, 0x51, 0x75, 0x6f, 0x72, 0x75, 0x6d, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x72, 0x65, 0x61, 0x64, 0x51, 0x75, 0x6f, 0x72, 0x75,
+	0x6d, 0x12, 0x36, 0x0a, 0x08, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65,
+	0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x69, 0x6e, 0x67, 0x52,
+	0x08, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x70, 0x6f,
+	0x63, 0x68, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x65, 0x70, 0x6f, 0x63, 0x68, 0x12,
+	0x22, 0x0a, 0x0c, 0x73, 0x75, 0x73, 0x70, 0x65, 0x63, 0x74, 0x41, 0x64, 0x64, 0x72, 0x73, 0x18,
+	0x09, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x73, 0x75, 0x73, 0x70, 0x65, 0x63, 0x74, 0x41, 0x64,
+	0x64, 0x72, 0x73, 0x1a, 0x3a, 0x0a, 0x0c, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22,
+	0x41, 0x0a, 0x13, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x48, 0x65, 0x61,
+	0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x64, 0x64, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x64, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x65,
+	0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x22, 0x21, 0x0a, 0x09, 0x52, 0x69, 0x6e, 0x67, 0x45, 0x70, 0x6f, 0x63, 0x68, 0x12,
+	0x14, 0x0a, 0x05, 0x65, 0x70, 0x6f, 0x63, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05,
+	0x65, 0x70, 0x6f, 0x63, 0x68, 0x22, 0xab, 0x01, 0x0a, 0x10, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53,
+	0x74, 0x6f, 0x72, 0x65, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x64,
+	0x64, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x64, 0x72, 0x12, 0x2d,
+	0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65,
+	0x72, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x24, 0x0a,
+	0x0d, 0x6c, 0x61, 0x73, 0x74, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x6c, 0x61, 0x73, 0x74, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62,
+	0x65, 0x61, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6f,
+	0x6e, 0x52, 0x69, 0x6e, 0x67, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x6f, 0x6e, 0x52,
+	0x69, 0x6e, 0x67, 0x22, 0x5a, 0x0a, 0x0a, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x68, 0x69,
+	0x70, 0x12, 0x36, 0x0a, 0x07, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72,
+	0x52, 0x07, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x70, 0x6f,
+	0x63, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x65, 0x70, 0x6f, 0x63, 0x68, 0x2a,
+	0x2e, 0x0a, 0x0b, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x08,
+	0x0a, 0x04, 0x4c, 0x49, 0x56, 0x45, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x55, 0x53, 0x50,
+	0x45, 0x43, 0x54, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x44, 0x45, 0x41, 0x44, 0x10, 0x02, 0x32,
+	0xc4, 0x02, 0x0a, 0x0a, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x12, 0x36,
+	0x0a, 0x08, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x15, 0x2e, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73,
+	0x68, 0x1a, 0x11, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x00, 0x12, 0x34, 0x0a, 0x08, 0x50, 0x75, 0x74, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x12, 0x11, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x1a, 0x13, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f,
+	0x72, 0x65, 0x2e, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x22, 0x00, 0x12, 0x3f, 0x0a, 0x09,
+	0x48, 0x61, 0x73, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x17, 0x2e, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68,
+	0x65, 0x73, 0x1a, 0x17, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x00, 0x12, 0x43, 0x0a,
+	0x0a, 0x4c, 0x69, 0x73, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x1a, 0x2e, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61,
+	0x73, 0x68, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x1a, 0x17, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73,
+	0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73,
+	0x22, 0x00, 0x12, 0x42, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x69,
+	0x7a, 0x65, 0x73, 0x12, 0x17, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65,
+	0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x1a, 0x16, 0x2e, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53,
+	0x69, 0x7a, 0x65, 0x73, 0x22, 0x00, 0x32, 0x85, 0x06, 0x0a, 0x09, 0x4d, 0x65, 0x74, 0x61, 0x53,
+	0x74, 0x6f, 0x72, 0x65, 0x12, 0x43, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x49,
+	0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x17,
+	0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65,
+	0x49, 0x6e, 0x66, 0x6f, 0x4d, 0x61, 0x70, 0x22, 0x00, 0x12, 0x3d, 0x0a, 0x0a, 0x55, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x18, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73,
+	0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74,
+	0x61, 0x1a, 0x13, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x00, 0x12, 0x49, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x41, 0x64, 0x64, 0x72, 0x12, 0x16, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x1a, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f,
+	0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x41, 0x64, 0x64,
+	0x72, 0x22, 0x00, 0x12, 0x49, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53,
+	0x74, 0x6f, 0x72, 0x65, 0x52, 0x69, 0x6e, 0x67, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x1a, 0x1a, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x69, 0x6e, 0x67, 0x22, 0x00, 0x12, 0x4e,
+	0x0a, 0x12, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53,
+	0x74, 0x6f, 0x72, 0x65, 0x12, 0x1f, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72,
+	0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x48, 0x65, 0x61, 0x72,
+	0x74, 0x62, 0x65, 0x61, 0x74, 0x1a, 0x15, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f,
+	0x72, 0x65, 0x2e, 0x52, 0x69, 0x6e, 0x67, 0x45, 0x70, 0x6f, 0x63, 0x68, 0x22, 0x00, 0x12, 0x45,
+	0x0a, 0x09, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x12, 0x1f, 0x2e, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x74,
+	0x6f, 0x72, 0x65, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x1a, 0x15, 0x2e, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x52, 0x69, 0x6e, 0x67, 0x45, 0x70,
+	0x6f, 0x63, 0x68, 0x22, 0x00, 0x12, 0x41, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x4d, 0x65, 0x6d, 0x62,
+	0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x16,
+	0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x4d, 0x65, 0x6d, 0x62,
+	0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x22, 0x00, 0x12, 0x3f, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x52,
+	0x69, 0x6e, 0x67, 0x45, 0x70, 0x6f, 0x63, 0x68, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x1a, 0x15, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x52, 0x69,
+	0x6e, 0x67, 0x45, 0x70, 0x6f, 0x63, 0x68, 0x22, 0x00, 0x12, 0x43, 0x0a, 0x0f, 0x47, 0x65, 0x74,
+	0x46, 0x69, 0x6c, 0x65, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x14, 0x2e, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4e, 0x61,
+	0x6d, 0x65, 0x1a, 0x18, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e,
+	0x46, 0x69, 0x6c, 0x65, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x00, 0x12, 0x3b,
+	0x0a, 0x0a, 0x52, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x16, 0x2e, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65,
+	0x6e, 0x61, 0x6d, 0x65, 0x1a, 0x13, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72,
+	0x65, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x00, 0x12, 0x41, 0x0a, 0x0b, 0x55,
+	0x70, 0x64, 0x61, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x15, 0x2e, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x1a, 0x19, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x2e, 0x46,
+	0x69, 0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x6c, 0x69, 0x63, 0x74, 0x73, 0x22, 0x00, 0x42, 0x16,
+	0x5a, 0x14, 0x72, 0x63, 0x6a, 0x6e, 0x67, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_pkg_servestore_ServeStore_proto_rawDescOnce sync.Once
+	file_pkg_servestore_ServeStore_proto_rawDescData = file_pkg_servestore_ServeStore_proto_rawDesc
+)
+
+func file_pkg_servestore_ServeStore_proto_rawDescGZIP() []byte {
+	file_pkg_servestore_ServeStore_proto_rawDescOnce.Do(func() {
+		file_pkg_servestore_ServeStore_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_servestore_ServeStore_proto_rawDescData)
+	})
+	return file_pkg_servestore_ServeStore_proto_rawDescData
+}
+
+var file_pkg_servestore_ServeStore_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_pkg_servestore_ServeStore_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_pkg_servestore_ServeStore_proto_goTypes = []interface{}{
+	(MemberState)(0),            // 0: servestore.MemberState
+	(*BlockHash)(nil),           // 1: servestore.BlockHash
+	(*BlockHashes)(nil),         // 2: servestore.BlockHashes
+	(*BlockHashRange)(nil),      // 3: servestore.BlockHashRange
+	(*BlockSizes)(nil),          // 4: servestore.BlockSizes
+	(*Block)(nil),               // 5: servestore.Block
+	(*Success)(nil),             // 6: servestore.Success
+	(*FileMetaData)(nil),        // 7: servestore.FileMetaData
+	(*FileInfoMap)(nil),         // 8: servestore.FileInfoMap
+	(*Version)(nil),             // 9: servestore.Version
+	(*FileName)(nil),            // 10: servestore.FileName
+	(*FileRename)(nil),          // 11: servestore.FileRename
+	(*FileBatch)(nil),           // 12: servestore.FileBatch
+	(*FileConflict)(nil),        // 13: servestore.FileConflict
+	(*FileConflicts)(nil),       // 14: servestore.FileConflicts
+	(*FileVersions)(nil),        // 15: servestore.FileVersions
+	(*BlockStoreAddr)(nil),      // 16: servestore.BlockStoreAddr
+	(*BlockStoreRing)(nil),      // 17: servestore.BlockStoreRing
+	(*BlockStoreHeartbeat)(nil), // 18: servestore.BlockStoreHeartbeat
+	(*RingEpoch)(nil),           // 19: servestore.RingEpoch
+	(*BlockStoreMember)(nil),    // 20: servestore.BlockStoreMember
+	(*Membership)(nil),          // 21: servestore.Membership
+	nil,                         // 22: servestore.BlockSizes.SizesEntry
+	nil,                         // 23: servestore.FileMetaData.XattrsEntry
+	nil,                         // 24: servestore.FileInfoMap.FileInfoMapEntry
+	nil,                         // 25: servestore.BlockStoreRing.WeightsEntry
+	(*empty.Empty)(nil),         // 26: google.protobuf.Empty
+}
+var file_pkg_servestore_ServeStore_proto_depIdxs = []int32{
+	22, // 0: servestore.BlockSizes.sizes:type_name -> servestore.BlockSizes.SizesEntry
+	23, // 1: servestore.FileMetaData.xattrs:type_name -> servestore.FileMetaData.XattrsEntry
+	24, // 2: servestore.FileInfoMap.fileInfoMap:type_name -> servestore.FileInfoMap.FileInfoMapEntry
+	7,  // 3: servestore.FileRename.fileMetaData:type_name -> servestore.FileMetaData
+	7,  // 4: servestore.FileBatch.files:type_name -> servestore.FileMetaData
+	13, // 5: servestore.FileConflicts.conflicts:type_name -> servestore.FileConflict
+	7,  // 6: servestore.FileVersions.versions:type_name -> servestore.FileMetaData
+	25, // 7: servestore.BlockStoreRing.weights:type_name -> servestore.BlockStoreRing.WeightsEntry
+	17, // 8: servestore.BlockStoreRing.previous:type_name -> servestore.BlockStoreRing
+	0,  // 9: servestore.BlockStoreMember.state:type_name -> servestore.MemberState
+	20, // 10: servestore.Membership.members:type_name -> servestore.BlockStoreMember
+	7,  // 11: servestore.FileInfoMap.FileInfoMapEntry.value:type_name -> servestore.FileMetaData
+	1,  // 12: servestore.BlockStore.GetBlock:input_type -> servestore.BlockHash
+	5,  // 13: servestore.BlockStore.PutBlock:input_type -> servestore.Block
+	2,  // 14: servestore.BlockStore.HasBlocks:input_type -> servestore.BlockHashes
+	3,  // 15: servestore.BlockStore.ListBlocks:input_type -> servestore.BlockHashRange
+	2,  // 16: servestore.BlockStore.GetBlockSizes:input_type -> servestore.BlockHashes
+	26, // 17: servestore.MetaStore.GetFileInfoMap:input_type -> google.protobuf.Empty
+	7,  // 18: servestore.MetaStore.UpdateFile:input_type -> servestore.FileMetaData
+	26, // 19: servestore.MetaStore.GetBlockStoreAddr:input_type -> google.protobuf.Empty
+	26, // 20: servestore.MetaStore.GetBlockStoreRing:input_type -> google.protobuf.Empty
+	18, // 21: servestore.MetaStore.RegisterBlockStore:input_type -> servestore.BlockStoreHeartbeat
+	18, // 22: servestore.MetaStore.Heartbeat:input_type -> servestore.BlockStoreHeartbeat
+	26, // 23: servestore.MetaStore.GetMembership:input_type -> google.protobuf.Empty
+	26, // 24: servestore.MetaStore.GetRingEpoch:input_type -> google.protobuf.Empty
+	10, // 25: servestore.MetaStore.GetFileVersions:input_type -> servestore.FileName
+	11, // 26: servestore.MetaStore.RenameFile:input_type -> servestore.FileRename
+	12, // 27: servestore.MetaStore.UpdateFiles:input_type -> servestore.FileBatch
+	5,  // 28: servestore.BlockStore.GetBlock:output_type -> servestore.Block
+	6,  // 29: servestore.BlockStore.PutBlock:output_type -> servestore.Success
+	2,  // 30: servestore.BlockStore.HasBlocks:output_type -> servestore.BlockHashes
+	2,  // 31: servestore.BlockStore.ListBlocks:output_type -> servestore.BlockHashes
+	4,  // 32: servestore.BlockStore.GetBlockSizes:output_type -> servestore.BlockSizes
+	8,  // 33: servestore.MetaStore.GetFileInfoMap:output_type -> servestore.FileInfoMap
+	9,  // 34: servestore.MetaStore.UpdateFile:output_type -> servestore.Version
+	16, // 35: servestore.MetaStore.GetBlockStoreAddr:output_type -> servestore.BlockStoreAddr
+	17, // 36: servestore.MetaStore.GetBlockStoreRing:output_type -> servestore.BlockStoreRing
+	19, // 37: servestore.MetaStore.RegisterBlockStore:output_type -> servestore.RingEpoch
+	19, // 38: servestore.MetaStore.Heartbeat:output_type -> servestore.RingEpoch
+	21, // 39: servestore.MetaStore.GetMembership:output_type -> servestore.Membership
+	19, // 40: servestore.MetaStore.GetRingEpoch:output_type -> servestore.RingEpoch
+	15, // 41: servestore.MetaStore.GetFileVersions:output_type -> servestore.FileVersions
+	9,  // 42: servestore.MetaStore.RenameFile:output_type -> servestore.Version
+	14, // 43: servestore.MetaStore.UpdateFiles:output_type -> servestore.FileConflicts
+	28, // [28:44] is the sub-list for method output_type
+	12, // [12:28] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_pkg_servestore_ServeStore_proto_init() }
+func file_pkg_servestore_ServeStore_proto_init() {
+	if File_pkg_servestore_ServeStore_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_pkg_servestore_ServeStore_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockHash); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockHashes); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockHashRange); i {
+			case 0:
+				return &v.state
 			case 1:
 				return &v.sizeCache
 			case 2:
@@ -582,7 +1675,7 @@ func file_pkg_servestore_ServeStore_proto_init() {
 			}
 		}
 		file_pkg_servestore_ServeStore_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Success); i {
+			switch v := v.(*BlockSizes); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -594,7 +1687,7 @@ func file_pkg_servestore_ServeStore_proto_init() {
 			}
 		}
 		file_pkg_servestore_ServeStore_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FileMetaData); i {
+			switch v := v.(*Block); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -606,7 +1699,7 @@ func file_pkg_servestore_ServeStore_proto_init() {
 			}
 		}
 		file_pkg_servestore_ServeStore_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FileInfoMap); i {
+			switch v := v.(*Success); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -618,7 +1711,7 @@ func file_pkg_servestore_ServeStore_proto_init() {
 			}
 		}
 		file_pkg_servestore_ServeStore_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Version); i {
+			switch v := v.(*FileMetaData); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -630,6 +1723,102 @@ func file_pkg_servestore_ServeStore_proto_init() {
 			}
 		}
 		file_pkg_servestore_ServeStore_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileInfoMap); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Version); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileName); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileRename); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileBatch); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileConflict); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileConflicts); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileVersions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*BlockStoreAddr); i {
 			case 0:
 				return &v.state
@@ -641,19 +1830,80 @@ func file_pkg_servestore_ServeStore_proto_init() {
 				return nil
 			}
 		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockStoreRing); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockStoreHeartbeat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RingEpoch); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockStoreMember); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_servestore_ServeStore_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Membership); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pkg_servestore_ServeStore_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   9,
+			NumEnums:      1,
+			NumMessages:   25,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
 		GoTypes:           file_pkg_servestore_ServeStore_proto_goTypes,
 		DependencyIndexes: file_pkg_servestore_ServeStore_proto_depIdxs,
+		EnumInfos:         file_pkg_servestore_ServeStore_proto_enumTypes,
 		MessageInfos:      file_pkg_servestore_ServeStore_proto_msgTypes,
 	}.Build()
 	File_pkg_servestore_ServeStore_proto = out.File
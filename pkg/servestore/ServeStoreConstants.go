@@ -5,7 +5,31 @@ const DEFAULT_META_FILENAME string = "index.txt"
 const FILENAME_INDEX int = 0
 const VERSION_INDEX int = 1
 const HASH_LIST_INDEX int = 2
+const SIZE_INDEX int = 3
+const MODE_INDEX int = 4
+const MTIME_INDEX int = 5
+const XATTRS_INDEX int = 6
+const SYMLINK_TARGET_INDEX int = 7
+const HARDLINK_TARGET_INDEX int = 8
+const STAT_SIZE_INDEX int = 9
+const STAT_MTIME_INDEX int = 10
+const STAT_CTIME_INDEX int = 11
+const STAT_INO_INDEX int = 12
 
 const CONFIG_DELIMITER string = ","
 const HASH_DELIMITER string = " "
+const XATTR_DELIMITER string = " "
+const XATTR_VALUE_DELIMITER string = ":"
 const TOMBSTONE_HASH string = "0"
+
+const IGNORE_FILENAME string = ".servestoreignore"
+
+const DEFAULT_BLOCK_SIZE int = 4096
+const DEFAULT_CONCURRENCY int = 8
+const DEFAULT_BUFFER_SIZE int64 = 64 * 1024 * 1024
+
+const JOURNAL_FILENAME string = ".servestore-journal"
+const TEMP_FILE_PREFIX string = ".servestore-tmp-"
+
+const BLOCK_SERVER_PREFIX string = "blockserver"
+const VIRTUAL_NODE_DELIMITER string = "#"
@@ -5,9 +5,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"io"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -31,72 +31,73 @@ func ConcatPath(baseDir, fileDir string) string {
 	return baseDir + "/" + fileDir
 }
 
+// validFilename reports whether filename is a clean slash-separated path inside the base
+// directory: not absolute, and without "." or ".." components
+func validFilename(filename string) bool {
+	return filename == path.Clean(filename) && !strings.HasPrefix(filename, "/") &&
+		filename != "." && filename != ".." && !strings.HasPrefix(filename, "../")
+}
+
 /*
 	Reading and Writing Local Metadata File Related
 */
 
-// NewFileMetaDataFromConfig returns a FileMetaData struct
-// associated with one line in the local metadata file.
-func NewFileMetaDataFromConfig(configString string) *FileMetaData {
-	configItems := strings.Split(configString, CONFIG_DELIMITER)
-
-	filename := configItems[FILENAME_INDEX]
-	version, _ := strconv.Atoi(configItems[VERSION_INDEX])
-	blockHashList := strings.Split(configItems[HASH_LIST_INDEX], HASH_DELIMITER)
+// xattrsToConfig encodes extended attributes as space-separated
+// hex-encoded name:value pairs, sorted by name
+func xattrsToConfig(xattrs map[string][]byte) string {
+	names := make([]string, 0, len(xattrs))
+	for name := range xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	return &FileMetaData{
-		Filename:      filename,
-		Version:       int32(version),
-		BlockHashList: blockHashList[:len(blockHashList)-1],
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, hex.EncodeToString([]byte(name))+XATTR_VALUE_DELIMITER+hex.EncodeToString(xattrs[name]))
 	}
+	return strings.Join(pairs, XATTR_DELIMITER)
 }
 
 // LoadMetaFromMetaFiles loads the local metadata file into a file meta map.
 // The key is the file's name and the value is the file's metadata.
 // You can use this function to load the index.txt file in this project.
 func LoadMetaFromMetaFile(baseDir string) (fileMetaMap map[string]*FileMetaData, e error) {
+	localIndex, e := LoadLocalIndex(baseDir)
+	return localIndex.Files, e
+}
+
+// LoadLocalIndex loads the local metadata file, including the stat cache of each file.
+func LoadLocalIndex(baseDir string) (localIndex *LocalIndex, e error) {
 	metaFilePath, _ := filepath.Abs(ConcatPath(baseDir, DEFAULT_META_FILENAME))
 
-	fileMetaMap = make(map[string]*FileMetaData)
+	localIndex = &LocalIndex{
+		Files: make(map[string]*FileMetaData),
+		Stats: make(map[string]*FileStat),
+	}
 
 	metaFileStats, e := os.Stat(metaFilePath)
 	if e != nil || metaFileStats.IsDir() {
-		return fileMetaMap, nil
+		return localIndex, nil
 	}
+	localIndex.WriteTime = metaFileStats.ModTime()
+
 	metaFD, e := os.Open(metaFilePath)
 	if e != nil {
-		log.Fatal("Error When Opening Meta")
+		return localIndex, fmt.Errorf("error when opening meta: %w", e)
 	}
 	defer metaFD.Close()
 
-	leftOverContent := ""
-	metaReader := bufio.NewReader(metaFD)
-	for {
-		lineContent, isPrefix, e := metaReader.ReadLine()
-		if e != nil && e != io.EOF {
-			log.Fatal("Error During Reading Meta")
-		}
-
-		leftOverContent += string(lineContent)
-		if isPrefix {
-			continue
-		}
-
-		if len(leftOverContent) == 0 {
-			break
-		}
-
-		currFileMeta := NewFileMetaDataFromConfig(leftOverContent)
-
-		leftOverContent = ""
-		fileMetaMap[currFileMeta.Filename] = currFileMeta
+	parsedIndex, e := ParseLocalIndex(metaFD)
+	if e != nil {
+		return localIndex, fmt.Errorf("error during reading meta: %w", e)
 	}
+	parsedIndex.WriteTime = localIndex.WriteTime
 
-	return fileMetaMap, nil
+	return parsedIndex, nil
 }
 
 // FileMetaDataToString converts a FileMetaData struct
-// to a string for writing back to local metadata file
+// to a line of a legacy local metadata file, for logging
 func FileMetaDataToString(fm *FileMetaData) (result string) {
 	result += fm.Filename + ","
 	result += strconv.Itoa(int(fm.Version)) + ","
@@ -105,6 +106,13 @@ func FileMetaDataToString(fm *FileMetaData) (result string) {
 		result += blockHash + " "
 	}
 
+	result += "," + strconv.FormatInt(fm.Size, 10)
+	result += "," + strconv.FormatUint(uint64(fm.Mode), 8)
+	result += "," + strconv.FormatInt(fm.Mtime, 10)
+	result += "," + xattrsToConfig(fm.Xattrs)
+	result += "," + hex.EncodeToString([]byte(fm.SymlinkTarget))
+	result += "," + hex.EncodeToString([]byte(fm.HardlinkTarget))
+
 	result += "\n"
 
 	return
@@ -112,30 +120,45 @@ func FileMetaDataToString(fm *FileMetaData) (result string) {
 
 // WriteMetaFile writes the file meta map back to local metadata file
 func WriteMetaFile(fileMetas map[string]*FileMetaData, baseDir string) error {
+	return WriteLocalIndex(&LocalIndex{Files: fileMetas}, baseDir)
+}
+
+// WriteLocalIndex writes the local index back to local metadata file. The index is written
+// to a temporary file that then replaces the metadata file, so that an interrupted write
+// leaves the previous index intact.
+func WriteLocalIndex(localIndex *LocalIndex, baseDir string) error {
 	log.Println("Updating local index...")
 
 	outputMetaPath := ConcatPath(baseDir, DEFAULT_META_FILENAME)
 
-	outFD, err := os.Create(outputMetaPath)
+	outFD, err := os.CreateTemp(baseDir, TEMP_FILE_PREFIX+DEFAULT_META_FILENAME+"-*")
 	if err != nil {
-		log.Fatal("Error During Meta Write Back")
+		return fmt.Errorf("error during meta write back: %w", err)
 	}
+	tempMetaPath := outFD.Name()
+	defer os.Remove(tempMetaPath)
+	defer outFD.Close()
 
-	// Sort meta file entries by filename
-	files := make([]string, 0)
-	for filename := range fileMetas {
-		files = append(files, filename)
+	outWriter := bufio.NewWriter(outFD)
+	if err := EncodeLocalIndex(outWriter, localIndex); err != nil {
+		return fmt.Errorf("error during meta write back: %w", err)
 	}
-	sort.Strings(files)
-
-	// Write entries to local metadata file
-	for _, filename := range files {
-		_, err := outFD.WriteString(FileMetaDataToString(fileMetas[filename]))
-		if err != nil {
-			log.Fatal("Error During Meta Write Back")
-		}
+	if err := outWriter.Flush(); err != nil {
+		return fmt.Errorf("error during meta write back: %w", err)
+	}
+	if err := outFD.Chmod(0644); err != nil {
+		return fmt.Errorf("error during meta write back: %w", err)
+	}
+	if err := outFD.Sync(); err != nil {
+		return fmt.Errorf("error during meta write back: %w", err)
+	}
+	if err := outFD.Close(); err != nil {
+		return fmt.Errorf("error during meta write back: %w", err)
 	}
 
+	if err := os.Rename(tempMetaPath, outputMetaPath); err != nil {
+		return fmt.Errorf("error during meta write back: %w", err)
+	}
 	return nil
 }
 
@@ -15,6 +15,30 @@ type MetaStoreInterface interface {
 
 	// Get the the BlockStore address
 	GetBlockStoreAddr(ctx context.Context, _ *emptypb.Empty) (*BlockStoreAddr, error)
+
+	// Get the BlockStores and how blocks are placed and replicated on them
+	GetBlockStoreRing(ctx context.Context, _ *emptypb.Empty) (*BlockStoreRing, error)
+
+	// Register a BlockStore with the cluster
+	RegisterBlockStore(ctx context.Context, heartbeat *BlockStoreHeartbeat) (*RingEpoch, error)
+
+	// Mark a registered BlockStore live
+	Heartbeat(ctx context.Context, heartbeat *BlockStoreHeartbeat) (*RingEpoch, error)
+
+	// Get the state of every BlockStore of the cluster
+	GetMembership(ctx context.Context, _ *emptypb.Empty) (*Membership, error)
+
+	// Get the ring epoch, which changes whenever the BlockStores on the ring do
+	GetRingEpoch(ctx context.Context, _ *emptypb.Empty) (*RingEpoch, error)
+
+	// Get the versions of a file the MetaStore accepted, oldest first
+	GetFileVersions(ctx context.Context, fileName *FileName) (*FileVersions, error)
+
+	// Rename a file, tombstoning its old name, in a single update
+	RenameFile(ctx context.Context, fileRename *FileRename) (*Version, error)
+
+	// Update several files' fileinfo entries, all or none of them
+	UpdateFiles(ctx context.Context, fileBatch *FileBatch) (*FileConflicts, error)
 }
 
 type BlockStoreInterface interface {
@@ -28,6 +52,12 @@ type BlockStoreInterface interface {
 	// Given a list of hashes “in”, returns a list containing the
 	// subset of in that are stored in the key-value store
 	HasBlocks(ctx context.Context, blockHashesIn *BlockHashes) (*BlockHashes, error)
+
+	// Returns the hashes of the stored blocks in a range of the hash ring
+	ListBlocks(ctx context.Context, blockHashRange *BlockHashRange) (*BlockHashes, error)
+
+	// Returns the sizes of the stored blocks among the given hashes
+	GetBlockSizes(ctx context.Context, blockHashesIn *BlockHashes) (*BlockSizes, error)
 }
 
 type ClientInterface interface {
@@ -35,9 +65,19 @@ type ClientInterface interface {
 	GetFileInfoMap(serverFileInfoMap *map[string]*FileMetaData) error
 	UpdateFile(fileMetaData *FileMetaData, latestVersion *int32) error
 	GetBlockStoreAddr(blockStoreAddr *string) error
+	GetBlockStoreRing(blockStoreRing *BlockStoreRing) error
+	RegisterBlockStore(blockStoreAddr string, weight int, epoch *int64) error
+	Heartbeat(blockStoreAddr string, weight int, epoch *int64) error
+	GetMembership(membership *Membership) error
+	GetRingEpoch(epoch *int64) error
+	GetFileVersions(filename string, versions *[]*FileMetaData) error
+	RenameFile(fileRename *FileRename, latestVersion *int32) error
+	UpdateFiles(files []*FileMetaData, conflicts *[]*FileConflict) error
 
 	// BlockStore
 	GetBlock(blockHash string, blockStoreAddr string, block *Block) error
 	PutBlock(block *Block, blockStoreAddr string, succ *bool) error
 	HasBlocks(blockHashesIn []string, blockStoreAddr string, blockHashesOut *[]string) error
+	ListBlocks(start string, end string, blockStoreAddr string, blockHashesOut *[]string) error
+	GetBlockSizes(blockHashesIn []string, blockStoreAddr string, blockSizesOut *map[string]int64) error
 }
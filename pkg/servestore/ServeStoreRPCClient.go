@@ -13,6 +13,16 @@ type RPCClient struct {
 	MetaStoreAddr string
 	BaseDir       string
 	BlockSize     int
+
+	// Policies of the syncs of BaseDir. Files are cut into blocks of BlockSize bytes unless
+	// SyncOptions.Chunker is set.
+	SyncOptions
+
+	// Limit the bytes per second of blocks uploaded and downloaded. Copies of the client share
+	// them, so they apply across all its concurrent block transfers. Unlimited if nil. See
+	// SetTransferLimits.
+	UploadLimiter   *RateLimiter
+	DownloadLimiter *RateLimiter
 }
 
 func (surfClient *RPCClient) GetBlock(blockHash string, blockStoreAddr string, block *Block) error {
@@ -33,6 +43,7 @@ func (surfClient *RPCClient) GetBlock(blockHash string, blockStoreAddr string, b
 		conn.Close()
 		return err
 	}
+	surfClient.DownloadLimiter.wait(len(b.GetBlockData()))
 	block.BlockData = b.GetBlockData()
 	block.BlockSize = b.GetBlockSize()
 
@@ -41,6 +52,8 @@ func (surfClient *RPCClient) GetBlock(blockHash string, blockStoreAddr string, b
 }
 
 func (surfClient *RPCClient) PutBlock(block *Block, blockStoreAddr string, succ *bool) error {
+	surfClient.UploadLimiter.wait(len(block.GetBlockData()))
+
 	// connect to the server
 	conn, err := grpc.Dial(blockStoreAddr, grpc.WithInsecure())
 	if err != nil {
@@ -88,6 +101,54 @@ func (surfClient *RPCClient) HasBlocks(blockHashesIn []string, blockStoreAddr st
 	return conn.Close()
 }
 
+func (surfClient *RPCClient) ListBlocks(start string, end string, blockStoreAddr string, blockHashesOut *[]string) error {
+	// connect to the server
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewBlockStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	blockHashes, err := c.ListBlocks(ctx, &BlockHashRange{Start: start, End: end})
+	if err != nil {
+		log.Printf("grpc ListBlocks error: %v", err)
+		conn.Close()
+		return err
+	}
+	*blockHashesOut = blockHashes.GetHashes()
+
+	// close the connection
+	return conn.Close()
+}
+
+func (surfClient *RPCClient) GetBlockSizes(blockHashesIn []string, blockStoreAddr string, blockSizesOut *map[string]int64) error {
+	// connect to the server
+	conn, err := grpc.Dial(blockStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewBlockStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	blockSizes, err := c.GetBlockSizes(ctx, &BlockHashes{Hashes: blockHashesIn})
+	if err != nil {
+		log.Printf("grpc GetBlockSizes error: %v", err)
+		conn.Close()
+		return err
+	}
+	*blockSizesOut = blockSizes.GetSizes()
+
+	// close the connection
+	return conn.Close()
+}
+
 func (surfClient *RPCClient) GetFileInfoMap(serverFileInfoMap *map[string]*FileMetaData) error {
 	// connect to the server
 	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
@@ -163,6 +224,218 @@ func (surfClient *RPCClient) GetBlockStoreAddr(blockStoreAddr *string) error {
 	return conn.Close()
 }
 
+func (surfClient *RPCClient) GetBlockStoreRing(blockStoreRing *BlockStoreRing) error {
+	// connect to the server
+	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewMetaStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ring, err := c.GetBlockStoreRing(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Printf("grpc GetBlockStoreRing error: %v", err)
+		conn.Close()
+		return err
+	}
+
+	*blockStoreRing = BlockStoreRing{
+		Addrs:             ring.GetAddrs(),
+		VirtualNodes:      ring.GetVirtualNodes(),
+		Weights:           ring.GetWeights(),
+		ReplicationFactor: ring.GetReplicationFactor(),
+		WriteQuorum:       ring.GetWriteQuorum(),
+		ReadQuorum:        ring.GetReadQuorum(),
+		Previous:          ring.GetPrevious(),
+		Epoch:             ring.GetEpoch(),
+		SuspectAddrs:      ring.GetSuspectAddrs(),
+	}
+
+	// close the connection
+	return conn.Close()
+}
+
+func (surfClient *RPCClient) RegisterBlockStore(blockStoreAddr string, weight int, epoch *int64) error {
+	// connect to the server
+	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewMetaStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ringEpoch, err := c.RegisterBlockStore(ctx, &BlockStoreHeartbeat{Addr: blockStoreAddr, Weight: int32(weight)})
+	if err != nil {
+		log.Printf("grpc RegisterBlockStore error: %v", err)
+		conn.Close()
+		return err
+	}
+
+	*epoch = ringEpoch.GetEpoch()
+
+	// close the connection
+	return conn.Close()
+}
+
+func (surfClient *RPCClient) Heartbeat(blockStoreAddr string, weight int, epoch *int64) error {
+	// connect to the server
+	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewMetaStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ringEpoch, err := c.Heartbeat(ctx, &BlockStoreHeartbeat{Addr: blockStoreAddr, Weight: int32(weight)})
+	if err != nil {
+		log.Printf("grpc Heartbeat error: %v", err)
+		conn.Close()
+		return err
+	}
+
+	*epoch = ringEpoch.GetEpoch()
+
+	// close the connection
+	return conn.Close()
+}
+
+func (surfClient *RPCClient) GetMembership(membership *Membership) error {
+	// connect to the server
+	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewMetaStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	members, err := c.GetMembership(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Printf("grpc GetMembership error: %v", err)
+		conn.Close()
+		return err
+	}
+
+	*membership = Membership{
+		Members: members.GetMembers(),
+		Epoch:   members.GetEpoch(),
+	}
+
+	// close the connection
+	return conn.Close()
+}
+
+func (surfClient *RPCClient) GetRingEpoch(epoch *int64) error {
+	// connect to the server
+	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewMetaStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ringEpoch, err := c.GetRingEpoch(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Printf("grpc GetRingEpoch error: %v", err)
+		conn.Close()
+		return err
+	}
+
+	*epoch = ringEpoch.GetEpoch()
+
+	// close the connection
+	return conn.Close()
+}
+
+func (surfClient *RPCClient) GetFileVersions(filename string, versions *[]*FileMetaData) error {
+	// connect to the server
+	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewMetaStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	fileVersions, err := c.GetFileVersions(ctx, &FileName{Filename: filename})
+	if err != nil {
+		log.Printf("grpc GetFileVersions error: %v", err)
+		conn.Close()
+		return err
+	}
+	*versions = fileVersions.GetVersions()
+
+	// close the connection
+	return conn.Close()
+}
+
+func (surfClient *RPCClient) RenameFile(fileRename *FileRename, latestVersion *int32) error {
+	// connect to the server
+	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewMetaStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	version, err := c.RenameFile(ctx, fileRename)
+	if err != nil {
+		log.Printf("grpc RenameFile error: %v", err)
+		conn.Close()
+		return err
+	}
+
+	*latestVersion = version.GetVersion()
+
+	// close the connection
+	return conn.Close()
+}
+
+func (surfClient *RPCClient) UpdateFiles(files []*FileMetaData, conflicts *[]*FileConflict) error {
+	// connect to the server
+	conn, err := grpc.Dial(surfClient.MetaStoreAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Printf("grpc Dial error: %v", err)
+		return err
+	}
+	c := NewMetaStoreClient(conn)
+
+	// perform the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	fileConflicts, err := c.UpdateFiles(ctx, &FileBatch{Files: files})
+	if err != nil {
+		log.Printf("grpc UpdateFiles error: %v", err)
+		conn.Close()
+		return err
+	}
+
+	*conflicts = fileConflicts.GetConflicts()
+
+	// close the connection
+	return conn.Close()
+}
+
 // This line guarantees all method for RPCClient are implemented
 var _ ClientInterface = new(RPCClient)
 
@@ -173,5 +446,9 @@ func NewServeStoreRPCClient(hostPort, baseDir string, blockSize int) RPCClient {
 		MetaStoreAddr: hostPort,
 		BaseDir:       baseDir,
 		BlockSize:     blockSize,
+		SyncOptions:   SyncOptions{LinkPolicy: LINK_PRESERVE},
+
+		UploadLimiter:   NewRateLimiter(0),
+		DownloadLimiter: NewRateLimiter(0),
 	}
 }
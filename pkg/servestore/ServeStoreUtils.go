@@ -1,79 +1,124 @@
 package servestore
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
-var rpcClient RPCClient
-var files map[string][]*Block
+// scanJob is a local file that has to be read and hashed
+type scanJob struct {
+	path         string
+	fileMetaData *FileMetaData
+	err          error
+}
 
-var localIndex map[string]*FileMetaData
-var remoteIndex map[string]*FileMetaData
-var remoteBlockStoreAddr string
-var syncedLocalIndex map[string]*FileMetaData
+// ClientSync syncs the base directory of client with its MetaStore, with the policies set
+// on client. See Syncer.Sync.
+func ClientSync(client RPCClient) (*SyncReport, error) {
+	return client.syncer().Sync()
+}
 
-// Implement the logic for a client syncing with the server here.
-func ClientSync(client RPCClient) {
-	rpcClient = client
+// ClientPlan computes what ClientSync would do without uploading, downloading or
+// changing any file, local or remote.
+func ClientPlan(client RPCClient) (*SyncPlan, error) {
+	return client.syncer().Plan()
+}
 
-	// Clear global file maps
-	files = make(map[string][]*Block)
-	syncedLocalIndex = make(map[string]*FileMetaData) // Store synced local index file metadata
+// resolvePendingFiles resumes the files an interrupted sync was syncing when it was
+// interrupted. A file whose local and remote versions both match the version it was
+// being synced to was synced completely, only its journal record is missing.
+func (s *Syncer) resolvePendingFiles() {
+	for filename, fileMetaData := range s.interrupted.pending {
+		remoteFileMetaData, exists := s.remoteIndex[filename]
+		if !exists || remoteFileMetaData.GetVersion() != fileMetaData.GetVersion() || !equalHashLists(remoteFileMetaData.GetBlockHashList(), fileMetaData.GetBlockHashList()) {
+			continue
+		}
 
-	localIndex = getLocalIndex(rpcClient.BaseDir)    // Get local FileMetaInfo map from local index file (index.txt)
-	remoteIndex = getRemoteIndex()                   // Get remote FileMetaInfo map from server
-	remoteBlockStoreAddr = getRemoteBlockStoreAddr() // Get remote BlockStore address
+		scannedFileMetaData, scanned := s.scannedIndex[filename]
+		if isTombstone(fileMetaData) {
+			if scanned {
+				continue
+			}
+		} else if !scanned || !equalHashLists(scannedFileMetaData.GetBlockHashList(), fileMetaData.GetBlockHashList()) {
+			continue
+		}
+
+		log.Println("Resuming", filename, "at version", fileMetaData.GetVersion())
+		s.localIndex[filename] = fileMetaData
+		delete(s.localStats, filename)
+	}
+}
 
-	handleFiles(rpcClient.BaseDir) // Scan all files in client's base directory
+// recoverInterruptedSync removes the temporary files of downloads that were interrupted,
+// and records the files the interrupted sync did sync in the local index before a new
+// sync starts
+func (s *Syncer) recoverInterruptedSync() error {
+	for _, tempFilename := range s.interrupted.temps {
+		tempPath := ConcatPath(s.baseDir, tempFilename)
+		log.Println("Removing interrupted download:", tempPath)
+		if err := os.Remove(tempPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove interrupted download: %w", err)
+		}
+	}
 
-	// Update local index with synced local index
-	WriteMetaFile(syncedLocalIndex, rpcClient.BaseDir)
+	recoveredIndex := &LocalIndex{Files: make(map[string]*FileMetaData), Stats: s.localStats}
+	for filename, fileMetaData := range s.localIndex {
+		recoveredIndex.Files[filename] = fileMetaData
+	}
+	for filename, fileMetaData := range s.ignoredIndex {
+		recoveredIndex.Files[filename] = fileMetaData
+	}
+	return WriteLocalIndex(recoveredIndex, s.baseDir)
 }
 
-func getLocalIndex(directory string) map[string]*FileMetaData {
+func getLocalIndex(directory string) (*LocalIndex, error) {
 	log.Println("Retrieving local index...")
 
-	// Create local index file if it does not exist.
-	_, err := os.Stat(ConcatPath(directory, DEFAULT_META_FILENAME))
+	// A missing local index file is loaded as an empty index and created when the sync completes
+	localIndex, err := LoadLocalIndex(directory)
 	if err != nil {
-		if os.IsNotExist(err) {
-			log.Println("Local index does not exist, creating local index...")
-			// Create local index file
-			index, err := os.Create(ConcatPath(directory, DEFAULT_META_FILENAME))
-			if err != nil {
-				log.Fatalf("File open error: %v", err)
-			}
-			index.Close()
-		} else {
-			log.Fatalf("Stat error: %v", err)
-		}
+		return nil, fmt.Errorf("load local index: %w", err)
 	}
-
-	localIndex, err := LoadMetaFromMetaFile(directory)
-	if err != nil {
-		log.Fatalf("LoadMetaFromMetaFile error: %v", err)
+	if localIndex.FormatVersion != INDEX_FORMAT_VERSION {
+		log.Println("Migrating local index from format version", localIndex.FormatVersion, "to", INDEX_FORMAT_VERSION)
 	}
 
 	// Log localIndex FileMetaData map
 	log.Println("Local index file metadata:")
-	for _, fileMetaData := range localIndex {
+	for _, fileMetaData := range localIndex.Files {
 		log.Println(FileMetaDataToString(fileMetaData))
 	}
 
-	return localIndex
+	return localIndex, nil
 }
 
-func getRemoteIndex() map[string]*FileMetaData {
+func (s *Syncer) getRemoteIndex() (map[string]*FileMetaData, error) {
 	log.Println("Retrieving remote index...")
 
 	remoteIndex := make(map[string]*FileMetaData)
-	err := rpcClient.GetFileInfoMap(&remoteIndex)
+	err := s.client.GetFileInfoMap(&remoteIndex)
 	if err != nil {
-		log.Fatalf("GetFileInfoMap error: %v", err)
+		return nil, fmt.Errorf("get remote index: %w", err)
+	}
+
+	// Files are only ever written inside the base directory
+	for filename, fileMetaData := range remoteIndex {
+		target := fileMetaData.GetHardlinkTarget()
+		if !validFilename(filename) || target != "" && !validFilename(target) {
+			log.Println("Ignoring remote file with an invalid name:", filename)
+			delete(remoteIndex, filename)
+		}
 	}
 
 	// Log remoteIndex FileMetaData map
@@ -82,302 +127,944 @@ func getRemoteIndex() map[string]*FileMetaData {
 		log.Println(FileMetaDataToString(fileMetaData))
 	}
 
-	return remoteIndex
+	return remoteIndex, nil
 }
 
-func getRemoteBlockStoreAddr() string {
-	log.Println("Retrieving remote BlockStore address...")
+// FetchBlockPlacement retrieves the BlockStores blocks are placed on from the MetaStore of
+// client. A MetaStore that does not know about the hash ring has a single BlockStore.
+func FetchBlockPlacement(client ClientInterface) (*BlockPlacement, error) {
+	log.Println("Retrieving remote BlockStore ring...")
+
+	blockStoreRing := &BlockStoreRing{}
+	err := client.GetBlockStoreRing(blockStoreRing)
+	if status.Code(err) == codes.Unimplemented {
+		log.Println("Retrieving remote BlockStore address...")
+
+		var remoteBlockStoreAddr string
+		if err := client.GetBlockStoreAddr(&remoteBlockStoreAddr); err != nil {
+			return nil, fmt.Errorf("get BlockStore address: %w", err)
+		}
+		return NewBlockPlacement(NewClusterConfig([]string{remoteBlockStoreAddr})), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get BlockStore ring: %w", err)
+	}
+
+	cluster, err := NewClusterConfigFromRing(blockStoreRing)
+	if err != nil {
+		return nil, fmt.Errorf("get BlockStore ring: %w", err)
+	}
+	placement := NewBlockPlacement(cluster)
+	log.Println("Ring epoch", blockStoreRing.GetEpoch())
+
+	// Read blocks from BlockStores that may be down last
+	placement.Suspect = make(map[string]bool)
+	for _, addr := range blockStoreRing.GetSuspectAddrs() {
+		placement.Suspect[addr] = true
+	}
+
+	// Blocks are still being moved from the previous BlockStores
+	if blockStoreRing.GetPrevious() != nil {
+		previousCluster, err := NewClusterConfigFromRing(blockStoreRing.GetPrevious())
+		if err != nil {
+			return nil, fmt.Errorf("get BlockStore ring: %w", err)
+		}
+		placement.Previous = NewBlockPlacement(previousCluster)
+	}
+	return placement, nil
+}
 
-	var remoteBlockStoreAddr string
-	err := rpcClient.GetBlockStoreAddr(&remoteBlockStoreAddr)
+// refreshRemoteFile reloads the remote metadata of filename after a version conflict,
+// since the remote index fetched at the start of the sync is stale by then.
+func (s *Syncer) refreshRemoteFile(filename string) error {
+	latestRemoteIndex := make(map[string]*FileMetaData)
+	err := s.client.GetFileInfoMap(&latestRemoteIndex)
 	if err != nil {
-		log.Fatalf("GetBlockStoreAddr error: %v", err)
+		return fmt.Errorf("get remote index: %w", err)
 	}
 
-	return remoteBlockStoreAddr
+	if remoteFileMetaData, exists := latestRemoteIndex[filename]; exists {
+		s.syncMutex.Lock()
+		s.remoteIndex[filename] = remoteFileMetaData
+		s.syncMutex.Unlock()
+	}
+	return nil
 }
 
-func handleFiles(directory string) {
+func (s *Syncer) scanFiles(directory string) error {
 	log.Println("Scanning files in directory:", directory)
 
-	err := filepath.WalkDir(directory, syncFile)
+	err := filepath.WalkDir(directory, s.scanFile)
 	if err != nil {
-		log.Fatalf("WalkDir error: %v", err)
-	}
-
-	// Handle files in local index that were not found locally
-	for filename := range localIndex {
-		if remoteFileMetaData, exists := remoteIndex[filename]; exists { // File in remoteIndex, check if already deleted
-			// If file has not been deleted in remoteIndex
-			if len(remoteFileMetaData.GetBlockHashList()) == 0 ||
-				remoteFileMetaData.GetBlockHashList()[0] != TOMBSTONE_HASH ||
-				len(remoteFileMetaData.GetBlockHashList()) != 1 {
-				// Attempt to update remote file with deletion
-				fileMetaData := &FileMetaData{Filename: filename, Version: localIndex[filename].GetVersion() + 1, BlockHashList: []string{TOMBSTONE_HASH}}
-				var latestVersion int32
-				err := rpcClient.UpdateFile(fileMetaData, &latestVersion)
-				if err != nil {
-					log.Fatalf("UpdateFile error: %v", err)
-				}
+		return fmt.Errorf("scan %s: %w", directory, err)
+	}
+
+	s.hashFiles()
 
-				if latestVersion != -1 { // If successful, add file to synced local index
-					log.Println(filename, "successfully deleted!")
+	return nil
+}
 
-					syncedLocalIndex[filename] = fileMetaData
-				} else { // If unsuccessful, download remote file blocks, overwrite local file, and add file to synced local index
-					log.Println(filename, "unsuccessfully deleted, downloading updates!")
+// hashFiles reads and hashes the files queued by scanFile, up to SyncOptions.Concurrency files
+// at once, then completes the scanned metadata of the files and of the hard links to them
+func (s *Syncer) hashFiles() {
+	runConcurrently(len(s.scanJobs), s.options.concurrency(), func(i int) error {
+		job := s.scanJobs[i]
+		log.Println("Hashing file:", job.path)
+
+		hashes, size, err := s.hashFile(job.path)
+		job.fileMetaData.BlockHashList, job.fileMetaData.Size, job.err = hashes, size, err
+		if err == nil {
+			s.emitEvent(SyncEvent{Type: EVENT_FILE_SCANNED, Filename: job.fileMetaData.GetFilename(), Bytes: size})
+		}
+		return nil
+	})
+
+	for _, job := range s.scanJobs {
+		filename := job.fileMetaData.GetFilename()
+		if job.err != nil {
+			s.scanErrors[filename] = job.err
+			delete(s.scannedIndex, filename)
+			delete(s.scannedStats, filename)
+		}
+	}
 
-					blocks := downloadBlocks(filename)
+	// Hard links to a file share its content
+	for _, fileMetaData := range s.scannedHardlinks {
+		filename, primary := fileMetaData.GetFilename(), fileMetaData.GetHardlinkTarget()
+		if err, failed := s.scanErrors[primary]; failed {
+			s.scanErrors[filename] = err
+			delete(s.scannedIndex, filename)
+			continue
+		}
 
-					updateLocalFile(rpcClient.BaseDir, filename, blocks)
+		fileMetaData.BlockHashList = s.scannedIndex[primary].GetBlockHashList()
+		fileMetaData.Size = s.scannedIndex[primary].GetSize()
+	}
 
-					syncedLocalIndex[filename] = remoteIndex[filename]
+	s.scanJobs = nil
+	s.scannedHardlinks = nil
+}
+
+func (s *Syncer) scanFile(path string, d fs.DirEntry, err error) error {
+	filename := s.relativeFilename(path)
+
+	// If there is an error, record it against the entry and keep scanning the rest of the directory
+	if err != nil {
+		log.Printf("scanFile error: %v", err)
+		if d == nil || filename == "" {
+			return err
+		}
+		if s.ignoreRules.Ignored(filename, d.IsDir()) {
+			return nil
+		}
+		s.scanErrors[filename] = err
+		if d.IsDir() {
+			// Files under an unreadable directory must not be mistaken for local deletions
+			for indexedFilename := range s.localIndex {
+				if strings.HasPrefix(indexedFilename, filename+"/") {
+					s.scanErrors[indexedFilename] = err
 				}
+			}
+			return fs.SkipDir
+		}
+		return nil
+	}
 
-				// If file has been deleted in remoteIndex
-			} else {
-				log.Println("Downloading potential updates for", filename)
+	// Skip the client's own files and ignored paths, without descending into ignored directories
+	if isClientFile(filename) && !d.IsDir() {
+		return nil
+	}
+	if filename != "" && s.ignoreRules.Ignored(filename, d.IsDir()) {
+		log.Println("Ignoring:", path)
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	}
 
-				blocks := downloadBlocks(filename)
+	// Load the directory's ignore file before scanning its contents
+	if d.IsDir() {
+		if err := s.ignoreRules.LoadIgnoreFile(s.baseDir, filename); err != nil {
+			return fmt.Errorf("load %s: %w", ConcatPath(path, IGNORE_FILENAME), err)
+		}
+		return nil
+	}
+
+	// Begin algorithm to scan each file and compute hashes.
+	log.Println("Scanning file:", path)
 
-				updateLocalFile(rpcClient.BaseDir, filename, blocks)
+	info, err := d.Info()
+	if err != nil {
+		s.scanErrors[filename] = err
+		return nil
+	}
+
+	// Symbolic links are synced according to the client's link policy
+	followed := false
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			s.scanErrors[filename] = err
+			return nil
+		}
 
-				syncedLocalIndex[filename] = remoteIndex[filename]
+		switch {
+		case s.options.LinkPolicy == LINK_SKIP:
+			log.Println("Skipping symbolic link:", path)
+			s.skippedLinks[filename] = true
+			return nil
+		// A link downloaded from a client preserving links is kept as a link
+		case s.options.LinkPolicy == LINK_FOLLOW && s.localIndex[filename].GetSymlinkTarget() != target:
+			info, err = os.Stat(path)
+			if err != nil {
+				s.scanErrors[filename] = err
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				log.Println("Skipping symbolic link to a directory or special file:", path)
+				s.skippedLinks[filename] = true
+				return nil
 			}
+			followed = true
+		default:
+			_, hashes := readSymlinkBlocks(target)
+			s.scannedIndex[filename] = &FileMetaData{Filename: filename, BlockHashList: hashes, Size: int64(len(target)), SymlinkTarget: target}
+			s.emitEvent(SyncEvent{Type: EVENT_FILE_SCANNED, Filename: filename, Bytes: int64(len(target))})
+			return nil
+		}
+	} else if !info.Mode().IsRegular() {
+		// Sockets, devices and named pipes cannot be synced
+		log.Println("Skipping special file:", path)
+		return nil
+	}
+
+	fileMetaData := &FileMetaData{Filename: filename}
+	hashing := false
+
+	// Hard links to a file that was already scanned share its content, which is filled in once it is hashed
+	id, hardlinked := getFileID(info)
+	if primary, seen := s.hardlinks[id]; hardlinked && seen && !followed {
+		log.Println(path, "is a hard link to", primary)
+
+		fileMetaData.HardlinkTarget = primary
+		s.scannedHardlinks = append(s.scannedHardlinks, fileMetaData)
+	} else {
+		// The file is stat'ed before it is read, so that a change made while reading it is
+		// detected by the next sync
+		fileStat := newFileStat(info)
+		if base := s.localIndex[filename]; !s.options.Rehash && !isSymlink(base) && fileStat.unchangedSince(s.localStats[filename], s.localIndexWriteTime) {
+			// The file is unchanged since the last sync, its blocks are only read if they need to be uploaded
+			log.Println(path, "is unchanged since the last sync")
+
+			fileMetaData.BlockHashList = base.GetBlockHashList()
+			fileMetaData.Size = base.GetSize()
 		} else {
-			// File in localIndex but not in remoteIndex, which is never possible in intended operation: case is explicitly "handled" for clarity and thoroughness
+			// The file is hashed once the whole directory has been scanned
+			s.scanJobs = append(s.scanJobs, &scanJob{path: path, fileMetaData: fileMetaData})
+			hashing = true
 		}
+		s.scannedStats[filename] = fileStat
 
-		delete(localIndex, filename)
-		delete(remoteIndex, filename)
+		if hardlinked && !followed {
+			s.hardlinks[id] = filename
+		}
 	}
 
-	// Download files from remoteIndex that are neither scanned nor in localIndex
-	for filename, remoteFileMetaData := range remoteIndex {
-		log.Println("Downloading updates for", filename)
-
-		blocks := downloadBlocks(filename)
+	err = readFileAttributes(path, info, s.options.Xattrs, fileMetaData)
+	if err != nil {
+		s.scanErrors[filename] = err
+		return nil
+	}
 
-		updateLocalFile(rpcClient.BaseDir, filename, blocks)
+	// Extended attributes that are not being read are carried over unchanged
+	if !s.options.Xattrs {
+		fileMetaData.Xattrs = s.localIndex[filename].GetXattrs()
+	}
 
-		syncedLocalIndex[filename] = remoteFileMetaData
+	s.scannedIndex[filename] = fileMetaData
+	if !hashing { // Hashed files are reported once hashed
+		s.emitEvent(SyncEvent{Type: EVENT_FILE_SCANNED, Filename: filename, Bytes: fileMetaData.GetSize()})
 	}
+
+	return nil
 }
 
-func syncFile(path string, d fs.DirEntry, err error) error {
-	// If dir entry is a directory, skip (`baseDir` should only have files, not directories)
-	if d.IsDir() || d.Name() == DEFAULT_META_FILENAME {
-		log.Println("Skipping:", path)
-		return nil
+// syncPlannedFile carries out the planned action for a single file
+func (s *Syncer) syncPlannedFile(plannedFile *PlannedFile) {
+	filename := plannedFile.Filename
+
+	if err, failed := s.scanErrors[filename]; failed {
+		s.failFile(filename, plannedFile.Action, err)
+		return
 	}
 
-	// If there is an error, return the error
-	if err != nil {
-		log.Printf("syncFile error: %v", err)
-		return err
+	switch {
+	case plannedFile.Action == ACTION_UPLOADED:
+		s.uploadFile(filename, plannedFile.uploadVersion(), plannedFile.local, plannedFile.MetadataOnly)
+	case plannedFile.Action == ACTION_DELETED && plannedFile.Direction == DIRECTION_UP:
+		s.deleteRemoteFile(filename, plannedFile.uploadVersion())
+	case plannedFile.Action == ACTION_RENAMED && plannedFile.Direction == DIRECTION_UP:
+		s.renameRemoteFile(plannedFile)
+	case plannedFile.Action == ACTION_RENAMED:
+		s.renameLocalFile(plannedFile)
+	case plannedFile.Action == ACTION_SKIPPED:
+		// Keep the local index entry when it is still current, since it records the attributes as stored locally
+		fileMetaData := plannedFile.remote
+		if fileMetaData == nil || plannedFile.base != nil && plannedFile.base.GetVersion() == fileMetaData.GetVersion() {
+			fileMetaData = plannedFile.base
+		}
+		s.recordSyncedFile(filename, fileMetaData, s.scannedStat(filename, fileMetaData), &FileReport{Filename: filename, Action: ACTION_SKIPPED, Version: fileMetaData.GetVersion()})
+	default:
+		log.Println("Downloading updates for", filename)
+
+		s.downloadFile(filename, plannedFile.Action, plannedFile.MetadataOnly)
 	}
+}
 
-	// Begin algorithm to scan each file and compute hashes.
-	log.Println("Syncing file:", path)
+// relativeFilename returns the slash-separated path of a file relative to the client's base
+// directory, which is the file's name in the local and remote index
+func (s *Syncer) relativeFilename(path string) string {
+	filename, err := filepath.Rel(s.baseDir, path)
+	if err != nil || filename == "." {
+		return ""
+	}
+	return filepath.ToSlash(filename)
+}
 
+// hashFile computes the hash of each block of the file at path and the file's size, reading
+// the file one block at a time
+func (s *Syncer) hashFile(path string) ([]string, int64, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		log.Printf("File open error: %v", err)
-		return err
+		return nil, 0, fmt.Errorf("open %s: %w", path, err)
 	}
 	defer file.Close()
 
-	filename := d.Name()
-	buf := make([]byte, rpcClient.BlockSize)
+	s.bufferLimiter.acquire(int64(s.blockSize()))
+	defer s.bufferLimiter.release(int64(s.blockSize()))
+	buf := make([]byte, s.blockSize())
 
-	blocks := make([]*Block, 0)
 	hashes := make([]string, 0)
-	// For each block in the file, calculate the hash and block size and add to files list
+	var size int64
 	for {
-		blockSize, err := file.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				log.Printf("File read error: %v", err)
-				return err
-			}
+		blockData, err := s.options.Chunker.ReadBlock(file, buf)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, 0, fmt.Errorf("read %s: %w", path, err)
 		}
 
-		blockData := make([]byte, blockSize)
-		copy(blockData, buf[:blockSize])
+		hashes = append(hashes, GetBlockHashString(blockData))
+		size += int64(len(blockData))
+	}
 
-		block := &Block{BlockData: blockData, BlockSize: int32(blockSize)}
-		hash := GetBlockHashString(blockData)
+	return hashes, size, nil
+}
 
-		blocks = append(blocks, block)
-		hashes = append(hashes, hash)
+// readBlock reads the next block of the file into buf, returning io.EOF at the end of the file
+func readBlock(file io.Reader, buf []byte) ([]byte, error) {
+	blockSize, err := io.ReadFull(file, buf)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
 	}
+	return buf[:blockSize], err
+}
 
-	files[filename] = blocks
+// uploadFile uploads the blocks of a local file and attempts to update its remote metadata
+// to `version`. If another client updated the file first, the remote version is downloaded
+// instead and the file is reported as a conflict.
+func (s *Syncer) uploadFile(filename string, version int32, localFileMetaData *FileMetaData, metadataOnly bool) {
+	// Upload blocks before updating remoteIndex, unless only the attributes of the file changed
+	var bytesUploaded int64
+	if !metadataOnly {
+		var err error
+		bytesUploaded, err = s.uploadBlocks(filename, localFileMetaData)
+		if err != nil {
+			s.failFile(filename, ACTION_UPLOADED, err)
+			return
+		}
+	}
+
+	fileMetaData := proto.Clone(localFileMetaData).(*FileMetaData)
+	fileMetaData.Version = version
+	if !isSymlink(fileMetaData) { // Lets readers find the block holding an offset
+		fileMetaData.BlockSize = int32(s.blockSize())
+	}
+	s.commitRemoteFile(fileMetaData, ACTION_UPLOADED, func(latestVersion int32) {
+		if latestVersion != -1 { // If successful, add file to synced local index
+			log.Println(filename, "successfully uploaded!")
 
-	// File is in localIndex
-	if localFileMetaData, exists := localIndex[filename]; exists {
-		localHashes := localFileMetaData.GetBlockHashList()
+			s.recordSyncedFile(filename, fileMetaData, s.scannedStat(filename, fileMetaData), &FileReport{Filename: filename, Action: ACTION_UPLOADED, Direction: DIRECTION_UP, Version: latestVersion, BytesUploaded: bytesUploaded})
+		} else { // If unsuccessful, download remote file blocks, overwrite local file, and add file to synced local index
+			log.Println(filename, "unsuccessfully uploaded, downloading updates!")
 
-		isModified := false
-		if len(hashes) != len(localHashes) {
-			isModified = true
-		} else {
-			for i := 0; i < len(hashes); i++ {
-				if hashes[i] != localHashes[i] {
-					isModified = true
-					break
-				}
-			}
+			s.downloadFile(filename, ACTION_CONFLICT, false)
 		}
+	})
+}
 
-		if isModified {
-			// Attempt to update remote file with modifications
-			uploadBlocks(filename, hashes) // Upload blocks before updating remoteIndex
+// deleteRemoteFile attempts to update the remote metadata of a locally deleted file with a
+// tombstone at `version`. If another client updated the file first, the remote version is
+// downloaded instead and the file is reported as a conflict.
+func (s *Syncer) deleteRemoteFile(filename string, version int32) {
+	fileMetaData := &FileMetaData{Filename: filename, Version: version, BlockHashList: []string{TOMBSTONE_HASH}}
+	s.commitRemoteFile(fileMetaData, ACTION_DELETED, func(latestVersion int32) {
+		if latestVersion != -1 { // If successful, add file to synced local index
+			log.Println(filename, "successfully deleted!")
 
-			fileMetaData := &FileMetaData{Filename: filename, Version: localIndex[filename].GetVersion() + 1, BlockHashList: hashes}
-			var latestVersion int32
-			err := rpcClient.UpdateFile(fileMetaData, &latestVersion)
-			if err != nil {
-				log.Fatalf("UpdateFile error: %v", err)
-			}
+			s.recordSyncedFile(filename, fileMetaData, nil, &FileReport{Filename: filename, Action: ACTION_DELETED, Direction: DIRECTION_UP, Version: latestVersion})
+		} else { // If unsuccessful, download remote file blocks, overwrite local file, and add file to synced local index
+			log.Println(filename, "unsuccessfully deleted, downloading updates!")
 
-			if latestVersion != -1 { // If successful, upload new file blocks and add file to synced local index
-				log.Println(filename, "successfully modified!")
+			s.downloadFile(filename, ACTION_CONFLICT, false)
+		}
+	})
+}
 
-				syncedLocalIndex[filename] = fileMetaData
-			} else { // If unsuccessful, download remote file blocks, overwrite local file, and add file to synced local index
-				log.Println(filename, "unsuccessfully modified, downloading updates!")
+// commitRemoteFile updates the remote metadata of a file with `action` and passes the new
+// version, or -1 if another client updated the file first, to committed. With
+// SyncOptions.Atomic the update is held back, to be committed with the other changes to the
+// file's directory in a single transaction once every file is synced.
+func (s *Syncer) commitRemoteFile(fileMetaData *FileMetaData, action SyncAction, committed func(latestVersion int32)) {
+	if s.options.Atomic {
+		s.holdCommit(&pendingCommit{fileMetaData: fileMetaData, action: action, committed: committed})
+		return
+	}
 
-				blocks := downloadBlocks(filename)
+	latestVersion, err := s.updateRemoteFile(fileMetaData)
+	if err != nil {
+		s.failFile(fileMetaData.GetFilename(), action, err)
+		return
+	}
+	committed(latestVersion)
+}
 
-				updateLocalFile(rpcClient.BaseDir, filename, blocks)
+// renameRemoteFile renames a file that was renamed locally with a single update of the
+// MetaStore, without uploading its blocks again. If the MetaStore cannot rename files or
+// another client updated either name first, the file is uploaded under its new name and
+// deleted under its old name instead. With SyncOptions.Atomic it always is, so that both
+// updates are committed in the transactions of their directories, which cannot record
+// renames.
+func (s *Syncer) renameRemoteFile(plannedFile *PlannedFile) {
+	filename, oldFilename := plannedFile.Filename, plannedFile.RenamedFrom
+	if s.options.Atomic {
+		s.uploadFile(filename, plannedFile.uploadVersion(), plannedFile.local, false)
+		s.deleteRemoteFile(oldFilename, plannedFile.renamed.uploadVersion())
+		return
+	}
 
-				syncedLocalIndex[filename] = remoteIndex[filename]
-			}
-		} else {
-			log.Println("Downloading potential updates for", filename)
+	fileMetaData := proto.Clone(plannedFile.local).(*FileMetaData)
+	fileMetaData.Version = plannedFile.uploadVersion()
+	fileMetaData.BlockSize = int32(s.blockSize())
+	tombstone := &FileMetaData{Filename: oldFilename, Version: plannedFile.renamed.uploadVersion(), BlockHashList: []string{TOMBSTONE_HASH}}
+	for _, pending := range []*FileMetaData{fileMetaData, tombstone} {
+		if err := s.journal.recordPending(pending); err != nil {
+			s.failRename(plannedFile, err)
+			return
+		}
+	}
 
-			blocks := downloadBlocks(filename)
+	var latestVersion int32
+	err := s.client.RenameFile(&FileRename{OldFilename: oldFilename, OldVersion: plannedFile.renamed.base.GetVersion(), FileMetaData: fileMetaData}, &latestVersion)
+	if status.Code(err) == codes.Unimplemented || err == nil && latestVersion == -1 {
+		log.Println(oldFilename, "could not be renamed to", filename, "remotely, uploading it instead")
 
-			updateLocalFile(rpcClient.BaseDir, filename, blocks)
+		s.uploadFile(filename, plannedFile.uploadVersion(), plannedFile.local, false)
+		s.deleteRemoteFile(oldFilename, plannedFile.renamed.uploadVersion())
+		return
+	}
+	if err != nil {
+		s.failRename(plannedFile, fmt.Errorf("rename remote file: %w", err))
+		return
+	}
 
-			syncedLocalIndex[filename] = remoteIndex[filename]
+	log.Println(oldFilename, "successfully renamed to", filename)
+
+	s.recordSyncedFile(oldFilename, tombstone, nil, nil)
+	s.recordSyncedFile(filename, fileMetaData, s.scannedStat(filename, fileMetaData), &FileReport{Filename: filename, Action: ACTION_RENAMED, Direction: DIRECTION_UP, Version: latestVersion, RenamedFrom: oldFilename})
+}
+
+// renameLocalFile renames a local file that was renamed remotely, and applies the attributes
+// of its remote version. If the local file cannot be renamed, the file is downloaded under its
+// new name and removed under its old name instead.
+func (s *Syncer) renameLocalFile(plannedFile *PlannedFile) {
+	filename, oldFilename := plannedFile.Filename, plannedFile.RenamedFrom
+	for _, pending := range []*FileMetaData{plannedFile.remote, plannedFile.renamed.remote} {
+		if err := s.journal.recordPending(pending); err != nil {
+			s.failRename(plannedFile, err)
+			return
 		}
-		// File is not in local index
-	} else {
-		// Attempt to update remote file with addition
-		uploadBlocks(filename, hashes) // Upload blocks before updating remoteIndex
+	}
 
-		fileMetaData := &FileMetaData{Filename: filename, Version: 1, BlockHashList: hashes}
-		var latestVersion int32
-		err := rpcClient.UpdateFile(fileMetaData, &latestVersion)
-		if err != nil {
-			log.Fatalf("UpdateFile error: %v", err)
+	path := ConcatPath(s.baseDir, filename)
+	err := checkLocalParents(s.baseDir, filename)
+	if err == nil {
+		err = checkLocalParents(s.baseDir, oldFilename)
+	}
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.Rename(ConcatPath(s.baseDir, oldFilename), path)
+	}
+	if err != nil {
+		log.Println(oldFilename, "could not be renamed to", filename, "locally, downloading it instead:", err)
+
+		s.downloadFile(filename, ACTION_DOWNLOADED, false)
+		s.downloadFile(oldFilename, ACTION_DOWNLOADED, false)
+		return
+	}
+	s.recordSyncedFile(oldFilename, plannedFile.renamed.remote, nil, nil)
+
+	syncedFileMetaData, fileStat, err := s.updateLocalFileAttributes(s.baseDir, plannedFile.remote)
+	if err != nil {
+		s.failFile(filename, ACTION_RENAMED, err)
+		return
+	}
+
+	s.recordSyncedFile(filename, syncedFileMetaData, fileStat, &FileReport{Filename: filename, Action: ACTION_RENAMED, Direction: DIRECTION_DOWN, Version: plannedFile.remote.GetVersion(), RenamedFrom: oldFilename})
+}
+
+// failRename records a renamed file that could not be synced under both of its names
+func (s *Syncer) failRename(plannedFile *PlannedFile, err error) {
+	oldFilename := plannedFile.RenamedFrom
+	s.recordSyncedFile(oldFilename, s.localIndex[oldFilename], s.localStats[oldFilename], nil)
+	s.failFile(plannedFile.Filename, ACTION_RENAMED, err)
+}
+
+// updateRemoteFile updates the remote metadata of a file, returning the new version or -1 on a version conflict
+func (s *Syncer) updateRemoteFile(fileMetaData *FileMetaData) (int32, error) {
+	if err := s.journal.recordPending(fileMetaData); err != nil {
+		return 0, err
+	}
+
+	var latestVersion int32
+	err := s.client.UpdateFile(fileMetaData, &latestVersion)
+	if err != nil {
+		return 0, fmt.Errorf("update remote metadata: %w", err)
+	}
+
+	if latestVersion == -1 {
+		if err := s.refreshRemoteFile(fileMetaData.GetFilename()); err != nil {
+			return 0, err
 		}
+	}
 
-		if latestVersion != -1 { // If successful, upload new file blocks and add file to synced local index
-			log.Println(filename, "successfully added!")
+	return latestVersion, nil
+}
 
-			syncedLocalIndex[filename] = fileMetaData
-		} else { // If unsuccesful, download remote file blocks, overwrite local file, and add file to synced local index
-			log.Println(filename, "unsuccessfully added, downloading updates!")
+// downloadFile overwrites (or removes) the local file with its remote version and
+// records the outcome as `action`
+func (s *Syncer) downloadFile(filename string, action SyncAction, metadataOnly bool) {
+	s.syncMutex.Lock()
+	remoteFileMetaData := s.remoteIndex[filename]
+	s.syncMutex.Unlock()
 
-			blocks := downloadBlocks(filename)
+	if err := s.journal.recordPending(remoteFileMetaData); err != nil {
+		s.failFile(filename, action, err)
+		return
+	}
 
-			updateLocalFile(rpcClient.BaseDir, filename, blocks)
+	// Links are recreated locally instead of being downloaded
+	if isSymlink(remoteFileMetaData) {
+		err := updateLocalSymlink(s.baseDir, filename, remoteFileMetaData.GetSymlinkTarget())
+		if err != nil {
+			s.failFile(filename, action, err)
+			return
+		}
 
-			syncedLocalIndex[filename] = remoteIndex[filename]
+		s.recordSyncedFile(filename, remoteFileMetaData, nil, &FileReport{Filename: filename, Action: action, Direction: DIRECTION_DOWN, Version: remoteFileMetaData.GetVersion()})
+		return
+	}
+	if target := remoteFileMetaData.GetHardlinkTarget(); target != "" && !isTombstone(remoteFileMetaData) {
+		targetFileMetaData, _ := s.syncedFile(target)
+		linked, err := updateLocalHardlink(s.baseDir, filename, target, targetFileMetaData, remoteFileMetaData.GetBlockHashList())
+		if err != nil {
+			s.failFile(filename, action, err)
+			return
 		}
+		metadataOnly = metadataOnly || linked
 	}
 
-	delete(localIndex, filename)
-	delete(remoteIndex, filename)
+	// Only the attributes of the file changed, its content is already up to date
+	var bytesDownloaded int64
+	if !metadataOnly {
+		var err error
+		bytesDownloaded, err = s.updateLocalFile(s.baseDir, filename, remoteFileMetaData)
+		if err != nil {
+			s.failFile(filename, action, err)
+			return
+		}
+	}
 
-	return nil
+	if action == ACTION_DOWNLOADED && isTombstone(remoteFileMetaData) {
+		action = ACTION_DELETED
+	}
+
+	syncedFileMetaData := remoteFileMetaData
+	var fileStat *FileStat
+	if !isTombstone(remoteFileMetaData) {
+		var err error
+		syncedFileMetaData, fileStat, err = s.updateLocalFileAttributes(s.baseDir, remoteFileMetaData)
+		if err != nil {
+			s.failFile(filename, action, err)
+			return
+		}
+	}
+
+	s.recordSyncedFile(filename, syncedFileMetaData, fileStat, &FileReport{Filename: filename, Action: action, Direction: DIRECTION_DOWN, Version: remoteFileMetaData.GetVersion(), BytesDownloaded: bytesDownloaded})
 }
 
-func uploadBlocks(filename string, blockHashes []string) {
-	log.Println("Uploading blocks for", filename, "with block hashes:", blockHashes)
+// updateLocalFileAttributes applies the attributes of the remote file to the local file and
+// returns the metadata and stat cache to record in the local index. The attributes are read
+// back from the local file, since the local file system may store them with less precision.
+func (s *Syncer) updateLocalFileAttributes(directory string, remoteFileMetaData *FileMetaData) (*FileMetaData, *FileStat, error) {
+	path := ConcatPath(directory, remoteFileMetaData.GetFilename())
+	if err := checkLocalParents(directory, remoteFileMetaData.GetFilename()); err != nil {
+		return nil, nil, err
+	}
 
-	// Check BlockStore server for already uploaded blocks
-	commonBlocks := []string{}
-	err := rpcClient.HasBlocks(blockHashes, remoteBlockStoreAddr, &commonBlocks)
+	err := applyFileAttributes(path, remoteFileMetaData, s.options.Xattrs)
 	if err != nil {
-		log.Fatalf("HasBlocks error: %v", err)
+		return nil, nil, err
 	}
 
-	log.Println("Common blocks:", commonBlocks)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
 
-	// Create a map of blocks already present in the BlockStore server
-	presentBlocks := make(map[string]bool)
-	for _, blockHash := range commonBlocks {
-		presentBlocks[blockHash] = true
+	syncedFileMetaData := proto.Clone(remoteFileMetaData).(*FileMetaData)
+	err = readFileAttributes(path, info, false, syncedFileMetaData)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Upload blocks not already present in the BlockStore server
-	for _, block := range files[filename] {
-		if _, exists := presentBlocks[GetBlockHashString(block.GetBlockData())]; !exists {
-			log.Println("Block", block, "not already present in BlockStore, uploading...")
+	return syncedFileMetaData, newFileStat(info), nil
+}
+
+// scannedStat returns the stat cache of the scanned local file, if the file's content is
+// the content recorded for it in `fileMetaData`
+func (s *Syncer) scannedStat(filename string, fileMetaData *FileMetaData) *FileStat {
+	fileStat, exists := s.scannedStats[filename]
+	if exists && !isSymlink(fileMetaData) && equalHashLists(s.scannedIndex[filename].GetBlockHashList(), fileMetaData.GetBlockHashList()) {
+		return fileStat
+	}
+	return nil
+}
 
-			var success bool
-			err := rpcClient.PutBlock(block, remoteBlockStoreAddr, &success)
-			if err != nil || !success {
-				log.Fatalf("PutBlock error: %v", err)
+// recordSyncedFile records the outcome of syncing a file: its synced local index entry and
+// stat cache, if not nil, and its report. Only entries that changed are journaled: an
+// interrupted sync is resumed from the local index, which already holds the others.
+func (s *Syncer) recordSyncedFile(filename string, fileMetaData *FileMetaData, fileStat *FileStat, fileReport *FileReport) {
+	defer s.emitReportEvent(fileReport) // Once syncMutex is released
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	if fileMetaData != nil {
+		s.syncedLocalIndex[filename] = fileMetaData
+		if !proto.Equal(fileMetaData, s.localIndex[filename]) {
+			if err := s.journal.recordDone(fileMetaData, fileStat); err != nil {
+				log.Println(err)
 			}
 		}
 	}
+	if fileStat != nil {
+		s.syncedStats[filename] = fileStat
+	}
+	if fileReport != nil {
+		s.report.add(fileReport)
+	}
 }
 
-func downloadBlocks(filename string) []*Block {
-	log.Println("Downloading blocks for", filename)
+// syncedFile returns the synced local index entry of a file that has already been synced
+func (s *Syncer) syncedFile(filename string) (*FileMetaData, bool) {
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
 
-	// If file has been deleted remotely, return special Block list
-	if len(remoteIndex[filename].GetBlockHashList()) == 1 && remoteIndex[filename].GetBlockHashList()[0] == TOMBSTONE_HASH {
-		log.Println(filename, "has been deleted, no download necessary!")
+	fileMetaData, synced := s.syncedLocalIndex[filename]
+	return fileMetaData, synced
+}
 
-		block := &Block{BlockData: nil, BlockSize: int32(rpcClient.BlockSize)}
-		return []*Block{block}
-	}
+// failFile records a file that could not be synced. Its previous local index entry is kept
+// so the next sync compares against the last state that was known to be in sync.
+func (s *Syncer) failFile(filename string, action SyncAction, err error) {
+	log.Printf("Failed to sync %s: %v", filename, err)
+
+	s.recordSyncedFile(filename, s.localIndex[filename], s.localStats[filename], &FileReport{Filename: filename, Action: action, Error: err.Error()})
+}
+
+// uploadBlocks streams the blocks of a local file to the BlockStores. The file is read one
+// block at a time, and each block is released once it is uploaded, or immediately if all
+// of its replicas already have it.
+func (s *Syncer) uploadBlocks(filename string, localFileMetaData *FileMetaData) (int64, error) {
+	blockHashes := localFileMetaData.GetBlockHashList()
+	log.Println("Uploading blocks for", filename, "with block hashes:", blockHashes)
 
-	blocks := make([]*Block, 0)
-	for _, hash := range remoteIndex[filename].GetBlockHashList() {
-		block := &Block{}
-		err := rpcClient.GetBlock(hash, remoteBlockStoreAddr, block)
+	// Check the BlockStore servers for already uploaded blocks
+	holders := s.blockPlacement.HasBlocks(s.client, blockHashes)
+
+	log.Println("Common blocks:", len(holders))
+
+	// The content of a symbolic link is a single block holding its target
+	if isSymlink(localFileMetaData) {
+		blocks, hashes := readSymlinkBlocks(localFileMetaData.GetSymlinkTarget())
+		if s.fullyReplicated(hashes[0], holders) {
+			return 0, nil
+		}
+
+		written, err := s.blockPlacement.PutBlock(s.client, blocks[0], hashes[0], holders[hashes[0]])
 		if err != nil {
-			log.Fatalf("GetBlock error: %v", err)
+			return 0, fmt.Errorf("put block %s: %w", hashes[0], err)
 		}
+		s.emitEvent(SyncEvent{Type: EVENT_BLOCK_UPLOADED, Filename: filename, Bytes: int64(blocks[0].GetBlockSize())})
+		return int64(written) * int64(blocks[0].GetBlockSize()), nil
+	}
 
-		blocks = append(blocks, block)
+	path := ConcatPath(s.baseDir, filename)
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", path, err)
 	}
+	defer file.Close()
 
-	return blocks
+	return s.putBlocks(file, filename, blockHashes, holders)
 }
 
-func updateLocalFile(directory string, filename string, blocks []*Block) {
-	log.Println("Updating", filename, "in", directory, "with new blocks:", blocks)
-	// If file has been deleted remotely, delete local file
-	if len(blocks) == 1 && blocks[0].GetBlockData() == nil && blocks[0].BlockSize == int32(rpcClient.BlockSize) {
-		log.Println(filename, "has been deleted, removing local file if present!")
+// fullyReplicated reports whether every replica of the block holds it
+func (s *Syncer) fullyReplicated(blockHash string, holders map[string]map[string]bool) bool {
+	return len(holders[blockHash]) >= len(s.blockPlacement.Replicas(blockHash))
+}
 
-		err := os.Remove(ConcatPath(directory, filename))
-		if err != nil && !os.IsNotExist(err) {
-			log.Fatalf("File remove error: %v", err)
+// putBlocks reads `file`, the local file `filename`, one block at a time and uploads the
+// blocks that are not present on all of their replicas yet, up to SyncOptions.Concurrency
+// blocks at once. Each block is checked against `blockHashes`, the hashes the file had when
+// it was scanned. `holders` are the replicas already holding each block.
+func (s *Syncer) putBlocks(file io.Reader, filename string, blockHashes []string, holders map[string]map[string]bool) (int64, error) {
+	path := ConcatPath(s.baseDir, filename)
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, s.options.concurrency())
+
+	var mu sync.Mutex
+	var bytesUploaded int64
+	var uploadErr error
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return uploadErr != nil
+	}
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if uploadErr == nil {
+			uploadErr = err
 		}
-		// Otherwise, overwrite local file
-	} else {
-		file, err := os.Create(ConcatPath(directory, filename))
+	}
+
+	blockSize := int64(s.blockSize())
+	numBlocks := 0
+	uploaded := make(map[string]bool)
+	for !failed() {
+		s.bufferLimiter.acquire(blockSize)
+		blockData, err := s.options.Chunker.ReadBlock(file, make([]byte, blockSize))
 		if err != nil {
-			log.Fatalf("Create error: %v", err)
+			s.bufferLimiter.release(blockSize)
+			if err != io.EOF {
+				fail(fmt.Errorf("read %s: %w", path, err))
+			}
+			break
+		}
+
+		hash := GetBlockHashString(blockData)
+		if numBlocks >= len(blockHashes) || hash != blockHashes[numBlocks] {
+			s.bufferLimiter.release(blockSize)
+			fail(fmt.Errorf("%s changed during sync", path))
+			break
 		}
-		defer file.Close()
+		numBlocks++
+
+		if uploaded[hash] || s.fullyReplicated(hash, holders) {
+			s.bufferLimiter.release(blockSize)
+			continue
+		}
+		uploaded[hash] = true
+
+		log.Println("Block", hash, "not already present on all replicas, uploading...")
 
-		for _, block := range blocks {
-			_, err = file.Write(block.GetBlockData())
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(block *Block, hash string) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			defer s.bufferLimiter.release(blockSize)
+
+			written, err := s.blockPlacement.PutBlock(s.client, block, hash, holders[hash])
 			if err != nil {
-				log.Fatalf("Write blocks error: %v", err)
+				fail(fmt.Errorf("put block %s: %w", hash, err))
+				return
 			}
+
+			atomic.AddInt64(&bytesUploaded, int64(written)*int64(block.GetBlockSize()))
+			s.emitEvent(SyncEvent{Type: EVENT_BLOCK_UPLOADED, Filename: filename, Bytes: int64(block.GetBlockSize())})
+		}(&Block{BlockData: blockData, BlockSize: int32(len(blockData))}, hash)
+	}
+	wg.Wait()
+
+	if uploadErr == nil && numBlocks != len(blockHashes) {
+		uploadErr = fmt.Errorf("%s changed during sync", path)
+	}
+	return bytesUploaded, uploadErr
+}
+
+// blockDownload is the result of downloading a single block
+type blockDownload struct {
+	hash  string
+	block *Block
+	err   error
+}
+
+// downloadBlocks streams the blocks of the remote file to `file` in order. Up to
+// SyncOptions.Concurrency blocks are downloaded at once, and each block is released once it
+// is written. A block's size is not known before it is downloaded, so it is counted as a
+// full block of the Chunker's BlockSize bytes against the buffer size.
+func (s *Syncer) downloadBlocks(filename string, remoteFileMetaData *FileMetaData, file io.Writer) (int64, error) {
+	log.Println("Downloading blocks for", filename)
+
+	// The blocks are those of the client that uploaded the file
+	blockSize := int64(remoteFileMetaData.GetBlockSize())
+	if blockSize == 0 { // Not recorded by older clients
+		blockSize = int64(s.blockSize())
+	}
+	var stopped int32
+
+	// Downloads are started in order, so a block always holds its share of the buffer
+	// before any block after it
+	pending := make(chan chan *blockDownload, s.options.concurrency())
+	go func() {
+		defer close(pending)
+		for _, hash := range remoteFileMetaData.GetBlockHashList() {
+			if atomic.LoadInt32(&stopped) != 0 {
+				return
+			}
+
+			s.bufferLimiter.acquire(blockSize)
+			result := make(chan *blockDownload, 1)
+			go func(hash string) {
+				block := &Block{}
+				err := s.blockPlacement.GetBlock(s.client, hash, block)
+				result <- &blockDownload{hash: hash, block: block, err: err}
+			}(hash)
+			pending <- result
+		}
+	}()
+
+	var bytesDownloaded int64
+	var downloadErr error
+	for result := range pending {
+		download := <-result
+		if downloadErr == nil {
+			if download.err != nil {
+				downloadErr = fmt.Errorf("get block %s: %w", download.hash, download.err)
+			} else if GetBlockHashString(download.block.GetBlockData()) != download.hash {
+				downloadErr = fmt.Errorf("get block %s: content does not match hash", download.hash)
+			} else if _, err := file.Write(download.block.GetBlockData()); err != nil {
+				downloadErr = fmt.Errorf("write %s: %w", filename, err)
+			} else {
+				bytesDownloaded += int64(len(download.block.GetBlockData()))
+				s.emitEvent(SyncEvent{Type: EVENT_BLOCK_DOWNLOADED, Filename: filename, Bytes: int64(len(download.block.GetBlockData()))})
+			}
+
+			if downloadErr != nil {
+				atomic.StoreInt32(&stopped, 1)
+			}
+		}
+		s.bufferLimiter.release(blockSize)
+	}
+
+	return bytesDownloaded, downloadErr
+}
+
+// updateLocalFile overwrites the local file with the remote file's blocks, or removes it
+// if the remote file is deleted. It returns the bytes downloaded.
+//
+// The blocks are written to a temporary file next to the local file as they are
+// downloaded and checked against their hashes, and the temporary file only replaces the
+// local file once it is complete, so an interrupted download never leaves a partial file.
+func (s *Syncer) updateLocalFile(directory string, filename string, remoteFileMetaData *FileMetaData) (int64, error) {
+	log.Println("Updating", filename, "in", directory, "with", len(remoteFileMetaData.GetBlockHashList()), "new blocks")
+	path := ConcatPath(directory, filename)
+	if err := checkLocalParents(directory, filename); err != nil {
+		return 0, err
+	}
+
+	// If file has been deleted remotely, delete local file
+	if isTombstone(remoteFileMetaData) {
+		log.Println(filename, "has been deleted, removing local file if present!")
+
+		return 0, removeLocalFile(path)
+	}
+
+	// Otherwise, overwrite local file
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return 0, fmt.Errorf("create directory for %s: %w", filename, err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), TEMP_FILE_PREFIX+filepath.Base(path)+"-*")
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", filename, err)
+	}
+	tempPath := file.Name()
+	defer os.Remove(tempPath)
+	defer file.Close()
+
+	if err := s.journal.recordTemp(s.relativeFilename(tempPath)); err != nil {
+		return 0, err
+	}
+
+	bytesDownloaded, err := s.downloadBlocks(filename, remoteFileMetaData, file)
+	if err != nil {
+		return bytesDownloaded, err
+	}
+
+	// Files from clients that do not sync the mode get the mode os.Create would give them
+	mode := posixToFileMode(remoteFileMetaData.GetMode())
+	if remoteFileMetaData.GetMode() == 0 {
+		mode = 0644
+	}
+	if err := file.Chmod(mode); err != nil {
+		return bytesDownloaded, fmt.Errorf("chmod %s: %w", filename, err)
+	}
+	if err := file.Sync(); err != nil {
+		return bytesDownloaded, fmt.Errorf("write %s: %w", filename, err)
+	}
+	if err := file.Close(); err != nil {
+		return bytesDownloaded, fmt.Errorf("write %s: %w", filename, err)
+	}
+
+	// Renaming replaces a symbolic link or hard link at path itself, and never writes through it
+	if err := os.Rename(tempPath, path); err != nil {
+		return bytesDownloaded, fmt.Errorf("rename %s: %w", filename, err)
+	}
+
+	return bytesDownloaded, nil
+}
+
+// isTombstone reports whether the file metadata marks a deleted file
+func isTombstone(fileMetaData *FileMetaData) bool {
+	blockHashList := fileMetaData.GetBlockHashList()
+	return len(blockHashList) == 1 && blockHashList[0] == TOMBSTONE_HASH
+}
+
+func equalHashLists(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			return false
 		}
 	}
+	return true
 }
@@ -26,6 +26,8 @@ type BlockStoreClient interface {
 	GetBlock(ctx context.Context, in *BlockHash, opts ...grpc.CallOption) (*Block, error)
 	PutBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Success, error)
 	HasBlocks(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (*BlockHashes, error)
+	ListBlocks(ctx context.Context, in *BlockHashRange, opts ...grpc.CallOption) (*BlockHashes, error)
+	GetBlockSizes(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (*BlockSizes, error)
 }
 
 type blockStoreClient struct {
@@ -38,7 +40,7 @@ func NewBlockStoreClient(cc grpc.ClientConnInterface) BlockStoreClient {
 
 func (c *blockStoreClient) GetBlock(ctx context.Context, in *BlockHash, opts ...grpc.CallOption) (*Block, error) {
 	out := new(Block)
-	err := c.cc.Invoke(ctx, "/surfstore.BlockStore/GetBlock", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/servestore.BlockStore/GetBlock", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +49,7 @@ func (c *blockStoreClient) GetBlock(ctx context.Context, in *BlockHash, opts ...
 
 func (c *blockStoreClient) PutBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Success, error) {
 	out := new(Success)
-	err := c.cc.Invoke(ctx, "/surfstore.BlockStore/PutBlock", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/servestore.BlockStore/PutBlock", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +58,25 @@ func (c *blockStoreClient) PutBlock(ctx context.Context, in *Block, opts ...grpc
 
 func (c *blockStoreClient) HasBlocks(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (*BlockHashes, error) {
 	out := new(BlockHashes)
-	err := c.cc.Invoke(ctx, "/surfstore.BlockStore/HasBlocks", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/servestore.BlockStore/HasBlocks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockStoreClient) ListBlocks(ctx context.Context, in *BlockHashRange, opts ...grpc.CallOption) (*BlockHashes, error) {
+	out := new(BlockHashes)
+	err := c.cc.Invoke(ctx, "/servestore.BlockStore/ListBlocks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockStoreClient) GetBlockSizes(ctx context.Context, in *BlockHashes, opts ...grpc.CallOption) (*BlockSizes, error) {
+	out := new(BlockSizes)
+	err := c.cc.Invoke(ctx, "/servestore.BlockStore/GetBlockSizes", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -70,6 +90,8 @@ type BlockStoreServer interface {
 	GetBlock(context.Context, *BlockHash) (*Block, error)
 	PutBlock(context.Context, *Block) (*Success, error)
 	HasBlocks(context.Context, *BlockHashes) (*BlockHashes, error)
+	ListBlocks(context.Context, *BlockHashRange) (*BlockHashes, error)
+	GetBlockSizes(context.Context, *BlockHashes) (*BlockSizes, error)
 	mustEmbedUnimplementedBlockStoreServer()
 }
 
@@ -86,6 +108,12 @@ func (UnimplementedBlockStoreServer) PutBlock(context.Context, *Block) (*Success
 func (UnimplementedBlockStoreServer) HasBlocks(context.Context, *BlockHashes) (*BlockHashes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HasBlocks not implemented")
 }
+func (UnimplementedBlockStoreServer) ListBlocks(context.Context, *BlockHashRange) (*BlockHashes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBlocks not implemented")
+}
+func (UnimplementedBlockStoreServer) GetBlockSizes(context.Context, *BlockHashes) (*BlockSizes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockSizes not implemented")
+}
 func (UnimplementedBlockStoreServer) mustEmbedUnimplementedBlockStoreServer() {}
 
 // UnsafeBlockStoreServer may be embedded to opt out of forward compatibility for this service.
@@ -109,7 +137,7 @@ func _BlockStore_GetBlock_Handler(srv interface{}, ctx context.Context, dec func
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/surfstore.BlockStore/GetBlock",
+		FullMethod: "/servestore.BlockStore/GetBlock",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(BlockStoreServer).GetBlock(ctx, req.(*BlockHash))
@@ -127,7 +155,7 @@ func _BlockStore_PutBlock_Handler(srv interface{}, ctx context.Context, dec func
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/surfstore.BlockStore/PutBlock",
+		FullMethod: "/servestore.BlockStore/PutBlock",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(BlockStoreServer).PutBlock(ctx, req.(*Block))
@@ -145,7 +173,7 @@ func _BlockStore_HasBlocks_Handler(srv interface{}, ctx context.Context, dec fun
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/surfstore.BlockStore/HasBlocks",
+		FullMethod: "/servestore.BlockStore/HasBlocks",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(BlockStoreServer).HasBlocks(ctx, req.(*BlockHashes))
@@ -153,11 +181,47 @@ func _BlockStore_HasBlocks_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BlockStore_ListBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockHashRange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStoreServer).ListBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.BlockStore/ListBlocks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockStoreServer).ListBlocks(ctx, req.(*BlockHashRange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockStore_GetBlockSizes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockHashes)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStoreServer).GetBlockSizes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.BlockStore/GetBlockSizes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockStoreServer).GetBlockSizes(ctx, req.(*BlockHashes))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BlockStore_ServiceDesc is the grpc.ServiceDesc for BlockStore service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var BlockStore_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "surfstore.BlockStore",
+	ServiceName: "servestore.BlockStore",
 	HandlerType: (*BlockStoreServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
@@ -172,9 +236,17 @@ var BlockStore_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "HasBlocks",
 			Handler:    _BlockStore_HasBlocks_Handler,
 		},
+		{
+			MethodName: "ListBlocks",
+			Handler:    _BlockStore_ListBlocks_Handler,
+		},
+		{
+			MethodName: "GetBlockSizes",
+			Handler:    _BlockStore_GetBlockSizes_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
-	Metadata: "pkg/surfstore/SurfStore.proto",
+	Metadata: "pkg/servestore/ServeStore.proto",
 }
 
 // MetaStoreClient is the client API for MetaStore service.
@@ -184,6 +256,14 @@ type MetaStoreClient interface {
 	GetFileInfoMap(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*FileInfoMap, error)
 	UpdateFile(ctx context.Context, in *FileMetaData, opts ...grpc.CallOption) (*Version, error)
 	GetBlockStoreAddr(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*BlockStoreAddr, error)
+	GetBlockStoreRing(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*BlockStoreRing, error)
+	RegisterBlockStore(ctx context.Context, in *BlockStoreHeartbeat, opts ...grpc.CallOption) (*RingEpoch, error)
+	Heartbeat(ctx context.Context, in *BlockStoreHeartbeat, opts ...grpc.CallOption) (*RingEpoch, error)
+	GetMembership(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Membership, error)
+	GetRingEpoch(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RingEpoch, error)
+	GetFileVersions(ctx context.Context, in *FileName, opts ...grpc.CallOption) (*FileVersions, error)
+	RenameFile(ctx context.Context, in *FileRename, opts ...grpc.CallOption) (*Version, error)
+	UpdateFiles(ctx context.Context, in *FileBatch, opts ...grpc.CallOption) (*FileConflicts, error)
 }
 
 type metaStoreClient struct {
@@ -196,7 +276,7 @@ func NewMetaStoreClient(cc grpc.ClientConnInterface) MetaStoreClient {
 
 func (c *metaStoreClient) GetFileInfoMap(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*FileInfoMap, error) {
 	out := new(FileInfoMap)
-	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/GetFileInfoMap", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/GetFileInfoMap", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -205,7 +285,7 @@ func (c *metaStoreClient) GetFileInfoMap(ctx context.Context, in *empty.Empty, o
 
 func (c *metaStoreClient) UpdateFile(ctx context.Context, in *FileMetaData, opts ...grpc.CallOption) (*Version, error) {
 	out := new(Version)
-	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/UpdateFile", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/UpdateFile", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -214,7 +294,79 @@ func (c *metaStoreClient) UpdateFile(ctx context.Context, in *FileMetaData, opts
 
 func (c *metaStoreClient) GetBlockStoreAddr(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*BlockStoreAddr, error) {
 	out := new(BlockStoreAddr)
-	err := c.cc.Invoke(ctx, "/surfstore.MetaStore/GetBlockStoreAddr", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/GetBlockStoreAddr", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) GetBlockStoreRing(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*BlockStoreRing, error) {
+	out := new(BlockStoreRing)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/GetBlockStoreRing", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) RegisterBlockStore(ctx context.Context, in *BlockStoreHeartbeat, opts ...grpc.CallOption) (*RingEpoch, error) {
+	out := new(RingEpoch)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/RegisterBlockStore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) Heartbeat(ctx context.Context, in *BlockStoreHeartbeat, opts ...grpc.CallOption) (*RingEpoch, error) {
+	out := new(RingEpoch)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) GetMembership(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Membership, error) {
+	out := new(Membership)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/GetMembership", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) GetRingEpoch(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RingEpoch, error) {
+	out := new(RingEpoch)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/GetRingEpoch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) GetFileVersions(ctx context.Context, in *FileName, opts ...grpc.CallOption) (*FileVersions, error) {
+	out := new(FileVersions)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/GetFileVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) RenameFile(ctx context.Context, in *FileRename, opts ...grpc.CallOption) (*Version, error) {
+	out := new(Version)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/RenameFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metaStoreClient) UpdateFiles(ctx context.Context, in *FileBatch, opts ...grpc.CallOption) (*FileConflicts, error) {
+	out := new(FileConflicts)
+	err := c.cc.Invoke(ctx, "/servestore.MetaStore/UpdateFiles", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -228,6 +380,14 @@ type MetaStoreServer interface {
 	GetFileInfoMap(context.Context, *empty.Empty) (*FileInfoMap, error)
 	UpdateFile(context.Context, *FileMetaData) (*Version, error)
 	GetBlockStoreAddr(context.Context, *empty.Empty) (*BlockStoreAddr, error)
+	GetBlockStoreRing(context.Context, *empty.Empty) (*BlockStoreRing, error)
+	RegisterBlockStore(context.Context, *BlockStoreHeartbeat) (*RingEpoch, error)
+	Heartbeat(context.Context, *BlockStoreHeartbeat) (*RingEpoch, error)
+	GetMembership(context.Context, *empty.Empty) (*Membership, error)
+	GetRingEpoch(context.Context, *empty.Empty) (*RingEpoch, error)
+	GetFileVersions(context.Context, *FileName) (*FileVersions, error)
+	RenameFile(context.Context, *FileRename) (*Version, error)
+	UpdateFiles(context.Context, *FileBatch) (*FileConflicts, error)
 	mustEmbedUnimplementedMetaStoreServer()
 }
 
@@ -244,6 +404,30 @@ func (UnimplementedMetaStoreServer) UpdateFile(context.Context, *FileMetaData) (
 func (UnimplementedMetaStoreServer) GetBlockStoreAddr(context.Context, *empty.Empty) (*BlockStoreAddr, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBlockStoreAddr not implemented")
 }
+func (UnimplementedMetaStoreServer) GetBlockStoreRing(context.Context, *empty.Empty) (*BlockStoreRing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockStoreRing not implemented")
+}
+func (UnimplementedMetaStoreServer) RegisterBlockStore(context.Context, *BlockStoreHeartbeat) (*RingEpoch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterBlockStore not implemented")
+}
+func (UnimplementedMetaStoreServer) Heartbeat(context.Context, *BlockStoreHeartbeat) (*RingEpoch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedMetaStoreServer) GetMembership(context.Context, *empty.Empty) (*Membership, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMembership not implemented")
+}
+func (UnimplementedMetaStoreServer) GetRingEpoch(context.Context, *empty.Empty) (*RingEpoch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRingEpoch not implemented")
+}
+func (UnimplementedMetaStoreServer) GetFileVersions(context.Context, *FileName) (*FileVersions, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFileVersions not implemented")
+}
+func (UnimplementedMetaStoreServer) RenameFile(context.Context, *FileRename) (*Version, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameFile not implemented")
+}
+func (UnimplementedMetaStoreServer) UpdateFiles(context.Context, *FileBatch) (*FileConflicts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateFiles not implemented")
+}
 func (UnimplementedMetaStoreServer) mustEmbedUnimplementedMetaStoreServer() {}
 
 // UnsafeMetaStoreServer may be embedded to opt out of forward compatibility for this service.
@@ -267,7 +451,7 @@ func _MetaStore_GetFileInfoMap_Handler(srv interface{}, ctx context.Context, dec
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/surfstore.MetaStore/GetFileInfoMap",
+		FullMethod: "/servestore.MetaStore/GetFileInfoMap",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(MetaStoreServer).GetFileInfoMap(ctx, req.(*empty.Empty))
@@ -285,7 +469,7 @@ func _MetaStore_UpdateFile_Handler(srv interface{}, ctx context.Context, dec fun
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/surfstore.MetaStore/UpdateFile",
+		FullMethod: "/servestore.MetaStore/UpdateFile",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(MetaStoreServer).UpdateFile(ctx, req.(*FileMetaData))
@@ -303,7 +487,7 @@ func _MetaStore_GetBlockStoreAddr_Handler(srv interface{}, ctx context.Context,
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/surfstore.MetaStore/GetBlockStoreAddr",
+		FullMethod: "/servestore.MetaStore/GetBlockStoreAddr",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(MetaStoreServer).GetBlockStoreAddr(ctx, req.(*empty.Empty))
@@ -311,11 +495,155 @@ func _MetaStore_GetBlockStoreAddr_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetaStore_GetBlockStoreRing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).GetBlockStoreRing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.MetaStore/GetBlockStoreRing",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).GetBlockStoreRing(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_RegisterBlockStore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockStoreHeartbeat)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).RegisterBlockStore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.MetaStore/RegisterBlockStore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).RegisterBlockStore(ctx, req.(*BlockStoreHeartbeat))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockStoreHeartbeat)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.MetaStore/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).Heartbeat(ctx, req.(*BlockStoreHeartbeat))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_GetMembership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).GetMembership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.MetaStore/GetMembership",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).GetMembership(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_GetRingEpoch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).GetRingEpoch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.MetaStore/GetRingEpoch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).GetRingEpoch(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_GetFileVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).GetFileVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.MetaStore/GetFileVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).GetFileVersions(ctx, req.(*FileName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_RenameFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileRename)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).RenameFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.MetaStore/RenameFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).RenameFile(ctx, req.(*FileRename))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetaStore_UpdateFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileBatch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaStoreServer).UpdateFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/servestore.MetaStore/UpdateFiles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaStoreServer).UpdateFiles(ctx, req.(*FileBatch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetaStore_ServiceDesc is the grpc.ServiceDesc for MetaStore service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var MetaStore_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "surfstore.MetaStore",
+	ServiceName: "servestore.MetaStore",
 	HandlerType: (*MetaStoreServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
@@ -330,7 +658,39 @@ var MetaStore_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetBlockStoreAddr",
 			Handler:    _MetaStore_GetBlockStoreAddr_Handler,
 		},
+		{
+			MethodName: "GetBlockStoreRing",
+			Handler:    _MetaStore_GetBlockStoreRing_Handler,
+		},
+		{
+			MethodName: "RegisterBlockStore",
+			Handler:    _MetaStore_RegisterBlockStore_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _MetaStore_Heartbeat_Handler,
+		},
+		{
+			MethodName: "GetMembership",
+			Handler:    _MetaStore_GetMembership_Handler,
+		},
+		{
+			MethodName: "GetRingEpoch",
+			Handler:    _MetaStore_GetRingEpoch_Handler,
+		},
+		{
+			MethodName: "GetFileVersions",
+			Handler:    _MetaStore_GetFileVersions_Handler,
+		},
+		{
+			MethodName: "RenameFile",
+			Handler:    _MetaStore_RenameFile_Handler,
+		},
+		{
+			MethodName: "UpdateFiles",
+			Handler:    _MetaStore_UpdateFiles_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
-	Metadata: "pkg/surfstore/SurfStore.proto",
+	Metadata: "pkg/servestore/ServeStore.proto",
 }
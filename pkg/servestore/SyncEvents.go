@@ -0,0 +1,85 @@
+package servestore
+
+import (
+	"time"
+)
+
+// SyncEventType describes what happened during a sync
+type SyncEventType string
+
+const (
+	EVENT_FILE_SCANNED     SyncEventType = "fileScanned"     // A local file was scanned, and hashed if it changed
+	EVENT_PLANNED          SyncEventType = "planned"         // The plan was built, with the totals to transfer
+	EVENT_BLOCK_UPLOADED   SyncEventType = "blockUploaded"   // A block was uploaded to its replicas
+	EVENT_BLOCK_DOWNLOADED SyncEventType = "blockDownloaded" // A block was downloaded and written
+	EVENT_FILE_COMMITTED   SyncEventType = "fileCommitted"   // A file was synced in either direction
+	EVENT_CONFLICT         SyncEventType = "conflict"        // A file was updated on both sides, the remote version was kept
+	EVENT_FILE_FAILED      SyncEventType = "fileFailed"      // A file could not be synced
+)
+
+// SyncEvent reports the progress of a sync to SyncOptions.Events
+type SyncEvent struct {
+	Type      SyncEventType `json:"type"`
+	Time      time.Time     `json:"time"`
+	Filename  string        `json:"filename,omitempty"`
+	Action    SyncAction    `json:"action,omitempty"`
+	Direction SyncDirection `json:"direction,omitempty"`
+	Version   int32         `json:"version,omitempty"`
+	Error     string        `json:"error,omitempty"`
+
+	// Bytes of the scanned file, or of the block transferred (counted once however many
+	// replicas it was uploaded to)
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// Files to sync and bytes to transfer, set on EVENT_PLANNED. Blocks already held by
+	// their replicas are not uploaded, so fewer bytes may be transferred.
+	TotalFiles int   `json:"totalFiles,omitempty"`
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+}
+
+// emitEvent passes the event to SyncOptions.Events, if set
+func (s *Syncer) emitEvent(event SyncEvent) {
+	if s.options.Events == nil {
+		return
+	}
+	event.Time = time.Now()
+
+	s.eventMutex.Lock()
+	defer s.eventMutex.Unlock()
+	s.options.Events(event)
+}
+
+// emitPlannedEvent reports the totals of the plan
+func (s *Syncer) emitPlannedEvent(plan *SyncPlan) {
+	event := SyncEvent{Type: EVENT_PLANNED}
+	for _, plannedFile := range plan.Files {
+		if plannedFile.Action != ACTION_SKIPPED && plannedFile.Error == "" {
+			event.TotalFiles++
+			event.TotalBytes += plannedFile.Size
+		}
+	}
+	s.emitEvent(event)
+}
+
+// emitReportEvent reports the outcome of a file, unless it was left unchanged
+func (s *Syncer) emitReportEvent(fileReport *FileReport) {
+	if fileReport == nil || fileReport.Action == ACTION_SKIPPED && fileReport.Error == "" {
+		return
+	}
+
+	event := SyncEvent{
+		Type:      EVENT_FILE_COMMITTED,
+		Filename:  fileReport.Filename,
+		Action:    fileReport.Action,
+		Direction: fileReport.Direction,
+		Version:   fileReport.Version,
+		Error:     fileReport.Error,
+	}
+	switch {
+	case fileReport.Error != "":
+		event.Type = EVENT_FILE_FAILED
+	case fileReport.Action == ACTION_CONFLICT:
+		event.Type = EVENT_CONFLICT
+	}
+	s.emitEvent(event)
+}
@@ -0,0 +1,79 @@
+package servestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// syncWithEvents syncs baseDir and returns the events of the sync by type
+func syncWithEvents(t *testing.T, addr string, baseDir string) map[SyncEventType][]SyncEvent {
+	events := make(map[SyncEventType][]SyncEvent)
+	client := NewServeStoreRPCClient(addr, baseDir, 4)
+	client.Events = func(event SyncEvent) {
+		events[event.Type] = append(events[event.Type], event)
+	}
+	if _, err := ClientSync(client); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func totalBytes(events []SyncEvent) int64 {
+	var total int64
+	for _, event := range events {
+		total += event.Bytes
+	}
+	return total
+}
+
+func TestSyncEvents(t *testing.T) {
+	addr := startSyncCluster(t)
+	dirA, dirB := t.TempDir(), t.TempDir()
+	for filename, content := range map[string]string{"a.txt": "aaaabbbbcc", "b.txt": "dddd"} {
+		if err := os.WriteFile(filepath.Join(dirA, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := syncWithEvents(t, addr, dirA)
+	if len(events[EVENT_FILE_SCANNED]) != 2 || totalBytes(events[EVENT_FILE_SCANNED]) != 14 {
+		t.Errorf("scanned events: %+v", events[EVENT_FILE_SCANNED])
+	}
+	if planned := events[EVENT_PLANNED]; len(planned) != 1 || planned[0].TotalFiles != 2 || planned[0].TotalBytes != 14 {
+		t.Errorf("planned events: %+v", planned)
+	}
+	if len(events[EVENT_BLOCK_UPLOADED]) != 4 || totalBytes(events[EVENT_BLOCK_UPLOADED]) != 14 {
+		t.Errorf("block uploaded events: %+v", events[EVENT_BLOCK_UPLOADED])
+	}
+	if committed := events[EVENT_FILE_COMMITTED]; len(committed) != 2 || committed[0].Action != ACTION_UPLOADED || committed[0].Version != 1 {
+		t.Errorf("committed events: %+v", committed)
+	}
+
+	events = syncWithEvents(t, addr, dirB)
+	if len(events[EVENT_BLOCK_DOWNLOADED]) != 4 || totalBytes(events[EVENT_BLOCK_DOWNLOADED]) != 14 {
+		t.Errorf("block downloaded events: %+v", events[EVENT_BLOCK_DOWNLOADED])
+	}
+	if committed := events[EVENT_FILE_COMMITTED]; len(committed) != 2 || committed[0].Direction != DIRECTION_DOWN {
+		t.Errorf("committed events: %+v", committed)
+	}
+
+	// Unchanged files are scanned from the stat cache, and nothing else happens
+	events = syncWithEvents(t, addr, dirA)
+	if len(events[EVENT_FILE_SCANNED]) != 2 || len(events[EVENT_FILE_COMMITTED]) != 0 || events[EVENT_PLANNED][0].TotalFiles != 0 {
+		t.Errorf("events of an unchanged sync: %+v", events)
+	}
+
+	// Both clients change a.txt, the second to sync loses its change
+	if err := os.WriteFile(filepath.Join(dirA, "a.txt"), []byte("from A"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	syncWithEvents(t, addr, dirA)
+	if err := os.WriteFile(filepath.Join(dirB, "a.txt"), []byte("from B"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events = syncWithEvents(t, addr, dirB)
+	if conflicts := events[EVENT_CONFLICT]; len(conflicts) != 1 || conflicts[0].Filename != "a.txt" || conflicts[0].Version != 2 {
+		t.Errorf("conflict events: %+v", conflicts)
+	}
+}
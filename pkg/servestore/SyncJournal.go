@@ -0,0 +1,164 @@
+package servestore
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Kinds of sync journal records
+const JOURNAL_PENDING string = "pending" // A file is about to be synced to the given entry
+const JOURNAL_TEMP string = "temp"       // A temporary file, relative to the base directory, is being downloaded to
+const JOURNAL_DONE string = "done"       // A file was synced, with the given local index entry
+
+// SyncJournal is a write-ahead log of a sync in progress, kept in JOURNAL_FILENAME in the
+// client's base directory. The local index is only written once a sync completes, so if
+// the sync is interrupted the journal records what it had done: the next sync resumes
+// from the files that were synced and rolls back partially downloaded files.
+//
+// Every record is a line `kind,...` flushed to disk before the journal returns. Local index
+// entries are written as records of the local metadata file (see ParseLocalIndex).
+type SyncJournal struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+// interruptedSync is the content of the journal of an interrupted sync
+type interruptedSync struct {
+	done      map[string]*FileMetaData
+	doneStats map[string]*FileStat
+	pending   map[string]*FileMetaData
+	temps     []string // Temporary files, as slash-separated paths relative to the base directory
+}
+
+// createSyncJournal starts a new, empty journal, replacing any previous one
+func createSyncJournal(baseDir string) (*SyncJournal, error) {
+	journalPath := ConcatPath(baseDir, JOURNAL_FILENAME)
+	file, err := os.Create(journalPath)
+	if err != nil {
+		return nil, fmt.Errorf("create sync journal: %w", err)
+	}
+	return &SyncJournal{file: file, path: journalPath}, nil
+}
+
+func (j *SyncJournal) recordPending(fileMetaData *FileMetaData) error {
+	return j.record(JOURNAL_PENDING + CONFIG_DELIMITER + encodeIndexRecord(fileMetaData, nil) + "\n")
+}
+
+func (j *SyncJournal) recordTemp(tempFilename string) error {
+	return j.record(JOURNAL_TEMP + CONFIG_DELIMITER + hex.EncodeToString([]byte(tempFilename)) + "\n")
+}
+
+func (j *SyncJournal) recordDone(fileMetaData *FileMetaData, fileStat *FileStat) error {
+	return j.record(JOURNAL_DONE + CONFIG_DELIMITER + encodeIndexRecord(fileMetaData, fileStat) + "\n")
+}
+
+func (j *SyncJournal) record(line string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.WriteString(line); err != nil {
+		return fmt.Errorf("write sync journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("write sync journal: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the journal once the local index records the completed sync
+func (j *SyncJournal) remove() error {
+	j.file.Close()
+	if err := os.Remove(j.path); err != nil {
+		return fmt.Errorf("remove sync journal: %w", err)
+	}
+	return nil
+}
+
+// loadSyncJournal reads the journal left by an interrupted sync, returning nil if the
+// last sync completed. A record cut short by the interruption, and any record after a
+// malformed one, is ignored.
+func loadSyncJournal(baseDir string) (*interruptedSync, error) {
+	journalFD, err := os.Open(ConcatPath(baseDir, JOURNAL_FILENAME))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open sync journal: %w", err)
+	}
+	defer journalFD.Close()
+
+	interrupted := &interruptedSync{
+		done:      make(map[string]*FileMetaData),
+		doneStats: make(map[string]*FileStat),
+		pending:   make(map[string]*FileMetaData),
+		temps:     make([]string, 0),
+	}
+
+	journalReader := bufio.NewReader(journalFD)
+	for {
+		line, err := journalReader.ReadString('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read sync journal: %w", err)
+		}
+
+		kind, record, _ := cut(strings.TrimSuffix(line, "\n"), CONFIG_DELIMITER)
+		switch kind {
+		case JOURNAL_PENDING:
+			fileMetaData, _, err := parseIndexRecord(record, false)
+			if err != nil {
+				return interrupted, nil
+			}
+			interrupted.pending[fileMetaData.GetFilename()] = fileMetaData
+		case JOURNAL_TEMP:
+			tempFilename, err := hex.DecodeString(record)
+			if err != nil {
+				return interrupted, nil
+			}
+			if isClientFile(string(tempFilename)) {
+				interrupted.temps = append(interrupted.temps, string(tempFilename))
+			}
+		case JOURNAL_DONE:
+			fileMetaData, fileStat, err := parseIndexRecord(record, false)
+			if err != nil {
+				return interrupted, nil
+			}
+			filename := fileMetaData.GetFilename()
+			delete(interrupted.pending, filename)
+			interrupted.done[filename] = fileMetaData
+			if fileStat != nil {
+				interrupted.doneStats[filename] = fileStat
+			} else {
+				delete(interrupted.doneStats, filename)
+			}
+		default:
+			return interrupted, nil
+		}
+	}
+
+	return interrupted, nil
+}
+
+// cut slices s around the first instance of sep
+func cut(s string, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// isClientFile reports whether the slash-separated path relative to the base directory is
+// one of the client's own files, which are never synced: the local index, the sync
+// journal and temporary files
+func isClientFile(filename string) bool {
+	return filename == DEFAULT_META_FILENAME || filename == JOURNAL_FILENAME ||
+		strings.HasPrefix(path.Base(filename), TEMP_FILE_PREFIX)
+}
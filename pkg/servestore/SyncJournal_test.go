@@ -0,0 +1,98 @@
+package servestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncResumesInterruptedSync(t *testing.T) {
+	cluster := newFakeCluster()
+	cluster.putFile("a.txt", "aaaa", 4, true)
+	baseDir := t.TempDir()
+	syncer := NewSyncer(cluster, baseDir, SyncOptions{Chunker: NewFixedSizeChunker(4)})
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another client updates a.txt and adds b.txt. A sync downloading them is killed after
+	// a.txt is done, while b.txt is being written to a temporary file.
+	cluster.putFile("a.txt", "AAAA", 4, true).Version = 2
+	b := cluster.putFile("b.txt", "bbbbbbbb", 4, true)
+	writeTestFiles(t, baseDir, map[string]string{"a.txt": "AAAA", TEMP_FILE_PREFIX + "b.txt-1": "bbbb"})
+	synced, fileStat, err := syncer.updateLocalFileAttributes(baseDir, cluster.files["a.txt"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal, err := createSyncJournal(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		journal.recordPending(cluster.files["a.txt"]),
+		journal.recordDone(synced, fileStat),
+		journal.recordPending(b),
+		journal.recordTemp(TEMP_FILE_PREFIX + "b.txt-1"),
+		journal.record(JOURNAL_DONE + CONFIG_DELIMITER + "b.t"), // Cut short by the kill
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	journal.file.Close()
+
+	interrupted, err := loadSyncJournal(baseDir)
+	if err != nil || len(interrupted.done) != 1 || interrupted.done["a.txt"].GetVersion() != 2 ||
+		len(interrupted.pending) != 1 || interrupted.pending["b.txt"] == nil || len(interrupted.temps) != 1 {
+		t.Fatalf("journal loaded as %+v, %v", interrupted, err)
+	}
+
+	// The next sync keeps a.txt, downloads b.txt and removes the partial download
+	report, err := syncer.Sync()
+	if err != nil || report.Count(ACTION_SKIPPED) != 1 || report.Count(ACTION_DOWNLOADED) != 1 {
+		t.Fatalf("resumed sync: %v, %+v", err, report)
+	}
+	for _, fileReport := range report.Files {
+		if fileReport.Filename == "a.txt" && fileReport.Action != ACTION_SKIPPED {
+			t.Errorf("a.txt synced again: %+v", fileReport)
+		}
+	}
+	assertTestFiles(t, baseDir, map[string]string{"a.txt": "AAAA", "b.txt": "bbbbbbbb"})
+	for _, filename := range []string{TEMP_FILE_PREFIX + "b.txt-1", JOURNAL_FILENAME} {
+		if _, err := os.Stat(filepath.Join(baseDir, filename)); !os.IsNotExist(err) {
+			t.Errorf("%s left behind: %v", filename, err)
+		}
+	}
+	localIndex, err := getLocalIndex(baseDir)
+	if err != nil || localIndex.Files["a.txt"].GetVersion() != 2 || localIndex.Files["b.txt"].GetVersion() != 1 {
+		t.Errorf("local index %v, %v", localIndex, err)
+	}
+}
+
+func TestUnchangedFilesAreNotJournaled(t *testing.T) {
+	cluster := newFakeCluster()
+	baseDir := t.TempDir()
+	writeTestFiles(t, baseDir, map[string]string{"a.txt": "aaaa", "b.txt": "bbbb", "c.txt": "cccc"})
+	syncer := NewSyncer(cluster, baseDir, SyncOptions{Chunker: NewFixedSizeChunker(4)})
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The journal is truncated and written in place, so a hard link to it keeps its records
+	// once the sync removes it
+	writeTestFiles(t, baseDir, map[string]string{"b.txt": "BBBB", JOURNAL_FILENAME: ""})
+	journalCopy := filepath.Join(t.TempDir(), "journal")
+	if err := os.Link(filepath.Join(baseDir, JOURNAL_FILENAME), journalCopy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(journalCopy, filepath.Join(baseDir, JOURNAL_FILENAME)); err != nil {
+		t.Fatal(err)
+	}
+	journaled, err := loadSyncJournal(baseDir)
+	if err != nil || len(journaled.done) != 1 || journaled.done["b.txt"].GetVersion() != 2 || len(journaled.pending) != 0 {
+		t.Errorf("journaled %+v, %v", journaled, err)
+	}
+}
@@ -0,0 +1,265 @@
+package servestore
+
+import (
+	"sort"
+	"strings"
+)
+
+// PlannedFile describes what a sync will do with a single file, based on the three-way
+// comparison of the scanned local file, its local index entry and its remote metadata
+type PlannedFile struct {
+	Filename  string        `json:"filename"`
+	Action    SyncAction    `json:"action"`
+	Direction SyncDirection `json:"direction,omitempty"`
+	Size      int64         `json:"size"`      // Size of the file version that will be transferred
+	NumBlocks int           `json:"numBlocks"` // Number of blocks of the file version that will be transferred
+	Error     string        `json:"error,omitempty"`
+
+	// Only the mode, modification time or extended attributes of the file will be synced,
+	// its content is already the same on both sides
+	MetadataOnly bool `json:"metadataOnly,omitempty"`
+
+	// Previous name of a file renamed on one side, which will be renamed on the other side
+	// instead of being transferred again
+	RenamedFrom string `json:"renamedFrom,omitempty"`
+
+	local   *FileMetaData // Scanned local file, nil if the file does not exist locally
+	base    *FileMetaData // Local index entry, nil if the file has never been synced
+	remote  *FileMetaData // Remote metadata, nil if the file is unknown to the MetaStore
+	renamed *PlannedFile  // The file under its previous name, if RenamedFrom is set
+}
+
+// SyncPlan lists the planned action for every file, ordered by filename
+type SyncPlan struct {
+	Files []*PlannedFile `json:"files"`
+}
+
+// Count returns the number of files planned with the given action
+func (p *SyncPlan) Count(action SyncAction) int {
+	count := 0
+	for _, plannedFile := range p.Files {
+		if plannedFile.Action == action && plannedFile.Error == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// buildPlan compares every file of the scanned local files, local index and remote index
+func buildPlan(scannedIndex map[string]*FileMetaData, scanErrors map[string]error, localIndex map[string]*FileMetaData, remoteIndex map[string]*FileMetaData) *SyncPlan {
+	filenames := make(map[string]bool)
+	for filename := range scannedIndex {
+		filenames[filename] = true
+	}
+	for filename := range scanErrors {
+		filenames[filename] = true
+	}
+	for filename := range localIndex {
+		filenames[filename] = true
+	}
+	for filename := range remoteIndex {
+		filenames[filename] = true
+	}
+
+	sortedFilenames := make([]string, 0, len(filenames))
+	for filename := range filenames {
+		sortedFilenames = append(sortedFilenames, filename)
+	}
+	sort.Strings(sortedFilenames)
+
+	plan := &SyncPlan{Files: make([]*PlannedFile, 0, len(sortedFilenames))}
+	for _, filename := range sortedFilenames {
+		plannedFile := &PlannedFile{
+			Filename: filename,
+			local:    scannedIndex[filename],
+			base:     localIndex[filename],
+			remote:   remoteIndex[filename],
+		}
+
+		if err, failed := scanErrors[filename]; failed {
+			plannedFile.Action = ACTION_SKIPPED
+			plannedFile.Error = err.Error()
+		} else {
+			planFile(plannedFile)
+		}
+
+		// A file only known to the local index has nothing left to sync
+		if plannedFile.local == nil && plannedFile.remote == nil && plannedFile.Error == "" {
+			continue
+		}
+
+		plan.Files = append(plan.Files, plannedFile)
+	}
+
+	planRenames(plan)
+	return plan
+}
+
+// planRenames pairs the files that were renamed on one side, so they are renamed on the
+// other side instead of being transferred again under their new name and deleted under
+// their old name. A file deleted locally is paired with a new local file with the same
+// blocks, and a local file deleted remotely with the file the MetaStore recorded it was
+// renamed to, if the local file is unchanged. Files with the same blocks are paired in
+// filename order.
+func planRenames(plan *SyncPlan) {
+	vanished := make(map[string][]*PlannedFile)
+	for _, plannedFile := range plan.Files {
+		if plannedFile.Action == ACTION_DELETED && plannedFile.Direction == DIRECTION_UP && renameable(plannedFile.base) {
+			key := strings.Join(plannedFile.base.GetBlockHashList(), HASH_DELIMITER)
+			vanished[key] = append(vanished[key], plannedFile)
+		}
+	}
+
+	paired := make(map[*PlannedFile]bool)
+	for _, plannedFile := range plan.Files {
+		switch {
+		// Renamed locally
+		case plannedFile.Action == ACTION_UPLOADED && plannedFile.base == nil && renameable(plannedFile.local):
+			key := strings.Join(plannedFile.local.GetBlockHashList(), HASH_DELIMITER)
+			if candidates := vanished[key]; len(candidates) > 0 {
+				vanished[key] = candidates[1:]
+				plannedFile.setRename(DIRECTION_UP, candidates[0])
+				paired[candidates[0]] = true
+			}
+
+		// Renamed remotely
+		case plannedFile.local == nil && (plannedFile.base == nil || isTombstone(plannedFile.base)) &&
+			plannedFile.remote.GetRenamedFrom() != "" && plannedFile.Direction == DIRECTION_DOWN:
+			oldFile := plan.find(plannedFile.remote.GetRenamedFrom())
+			if oldFile == nil || paired[oldFile] || oldFile.Action != ACTION_DELETED || oldFile.Direction != DIRECTION_DOWN {
+				continue
+			}
+			if oldFile.remote.GetRenamedTo() != plannedFile.Filename || oldFile.remote.GetVersion() != oldFile.base.GetVersion()+1 ||
+				!renameable(oldFile.local) || !equalHashLists(oldFile.local.GetBlockHashList(), plannedFile.remote.GetBlockHashList()) {
+				continue
+			}
+			plannedFile.setRename(DIRECTION_DOWN, oldFile)
+			paired[oldFile] = true
+		}
+	}
+
+	if len(paired) == 0 {
+		return
+	}
+	files := make([]*PlannedFile, 0, len(plan.Files)-len(paired))
+	for _, plannedFile := range plan.Files {
+		if !paired[plannedFile] {
+			files = append(files, plannedFile)
+		}
+	}
+	plan.Files = files
+}
+
+// renameable reports whether a file can be paired as renamed: links are cheap to recreate,
+// and empty files are indistinguishable from each other
+func renameable(fileMetaData *FileMetaData) bool {
+	return len(fileMetaData.GetBlockHashList()) > 0 && !isTombstone(fileMetaData) && !isSymlink(fileMetaData) && fileMetaData.GetHardlinkTarget() == ""
+}
+
+// find returns the planned file with the given name, or nil if there is none
+func (p *SyncPlan) find(filename string) *PlannedFile {
+	i := sort.Search(len(p.Files), func(i int) bool { return p.Files[i].Filename >= filename })
+	if i < len(p.Files) && p.Files[i].Filename == filename {
+		return p.Files[i]
+	}
+	return nil
+}
+
+// planFile decides the action for a single file
+func planFile(p *PlannedFile) {
+	local, base, remote := p.local, p.base, p.remote
+
+	switch {
+	// File exists locally
+	case local != nil:
+		switch {
+		case remote == nil: // New file, or the MetaStore lost the file (e.g. it was restarted)
+			p.setUpload()
+		case base == nil && isTombstone(remote): // New local file reusing the name of a deleted file
+			p.setUpload()
+		case base == nil && equalHashLists(local.GetBlockHashList(), remote.GetBlockHashList()): // Same file created on both sides
+			if attributesChanged(local, remote) {
+				p.setDownload(ACTION_DOWNLOADED)
+			} else {
+				p.setSkip()
+			}
+		case base == nil: // Different file created on both sides, the remote file wins
+			p.setDownload(ACTION_CONFLICT)
+		case !equalHashLists(local.GetBlockHashList(), base.GetBlockHashList()) || attributesChanged(local, base): // Modified locally
+			if remote.GetVersion() == base.GetVersion() {
+				p.setUpload()
+			} else { // Also modified remotely, the remote file wins
+				p.setDownload(ACTION_CONFLICT)
+			}
+		case remote.GetVersion() != base.GetVersion(): // Modified (or deleted) remotely only
+			p.setDownload(ACTION_DOWNLOADED)
+		default:
+			p.setSkip()
+		}
+
+	// File was deleted locally
+	case base != nil && remote != nil:
+		switch {
+		case isTombstone(remote): // Also deleted remotely
+			p.setSkip()
+		case remote.GetVersion() == base.GetVersion():
+			p.Action, p.Direction = ACTION_DELETED, DIRECTION_UP
+		default: // Modified remotely, the remote file wins
+			p.setDownload(ACTION_CONFLICT)
+		}
+
+	// File only exists remotely
+	case remote != nil:
+		if isTombstone(remote) {
+			p.setSkip()
+		} else {
+			p.setDownload(ACTION_DOWNLOADED)
+		}
+
+	default:
+		p.setSkip()
+	}
+}
+
+func (p *PlannedFile) setUpload() {
+	p.Action, p.Direction = ACTION_UPLOADED, DIRECTION_UP
+	if p.remote != nil && equalHashLists(p.local.GetBlockHashList(), p.remote.GetBlockHashList()) {
+		p.MetadataOnly = true
+		return
+	}
+	p.Size, p.NumBlocks = p.local.GetSize(), len(p.local.GetBlockHashList())
+}
+
+func (p *PlannedFile) setDownload(action SyncAction) {
+	p.Action, p.Direction = action, DIRECTION_DOWN
+	if isTombstone(p.remote) {
+		if action == ACTION_DOWNLOADED {
+			p.Action = ACTION_DELETED
+		}
+		return
+	}
+	if p.local != nil && equalHashLists(p.local.GetBlockHashList(), p.remote.GetBlockHashList()) {
+		p.MetadataOnly = true
+		return
+	}
+	p.Size, p.NumBlocks = p.remote.GetSize(), len(p.remote.GetBlockHashList())
+}
+
+func (p *PlannedFile) setRename(direction SyncDirection, renamed *PlannedFile) {
+	p.Action, p.Direction = ACTION_RENAMED, direction
+	p.RenamedFrom, p.renamed = renamed.Filename, renamed
+	p.Size, p.NumBlocks, p.MetadataOnly = 0, 0, false
+}
+
+func (p *PlannedFile) setSkip() {
+	p.Action, p.Direction = ACTION_SKIPPED, DIRECTION_NONE
+}
+
+// uploadVersion returns the version an upload or remote deletion of the file must be committed as
+func (p *PlannedFile) uploadVersion() int32 {
+	version := p.base.GetVersion()
+	if p.remote.GetVersion() > version && isTombstone(p.remote) {
+		version = p.remote.GetVersion()
+	}
+	return version + 1
+}
@@ -0,0 +1,242 @@
+package servestore
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// startSyncCluster serves a MetaStore and a BlockStore on a localhost port
+func startSyncCluster(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+
+	server := grpc.NewServer()
+	RegisterMetaStoreServer(server, NewMetaStore(addr))
+	RegisterBlockStoreServer(server, NewBlockStore())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return addr
+}
+
+// writeTestFiles writes files, by slash-separated name, under dir
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for filename, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(filename))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertTestFiles checks that the regular files under dir, apart from the client's own, are
+// exactly files
+func assertTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	found := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		filename, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		filename = filepath.ToSlash(filename)
+		if isClientFile(filename) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		found[filename] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, files) {
+		t.Errorf("files of %s are %v, want %v", dir, found, files)
+	}
+}
+
+func TestPlanDoesNotSync(t *testing.T) {
+	addr := startSyncCluster(t)
+	dirA, dirB := t.TempDir(), t.TempDir()
+	sync := func(dir string) {
+		if _, err := ClientSync(NewServeStoreRPCClient(addr, dir, 4)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFiles(t, dirA, map[string]string{"same.txt": "same", "remote.txt": "remote", "local.txt": "local", "both.txt": "both", "deleted.txt": "deleted"})
+	sync(dirA)
+	sync(dirB)
+
+	// A changes remote.txt and both.txt and adds new-remote.txt. B changes local.txt and
+	// both.txt, deletes deleted.txt and adds new-local.txt.
+	writeTestFiles(t, dirA, map[string]string{"remote.txt": "remote 2", "both.txt": "both from A", "new-remote.txt": "new remote"})
+	sync(dirA)
+	writeTestFiles(t, dirB, map[string]string{"local.txt": "local 2", "both.txt": "both from B", "new-local.txt": "fresh content"})
+	if err := os.Remove(filepath.Join(dirB, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewServeStoreRPCClient(addr, dirB, 4)
+	remoteIndex := make(map[string]*FileMetaData)
+	if err := client.GetFileInfoMap(&remoteIndex); err != nil {
+		t.Fatal(err)
+	}
+	remoteVersions := make(map[string]int32)
+	for filename, fileMetaData := range remoteIndex {
+		remoteVersions[filename] = fileMetaData.GetVersion()
+	}
+	indexPath := filepath.Join(dirB, DEFAULT_META_FILENAME)
+	index, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := ClientPlan(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct {
+		action    SyncAction
+		direction SyncDirection
+	}{
+		"same.txt":       {ACTION_SKIPPED, DIRECTION_NONE},
+		"remote.txt":     {ACTION_DOWNLOADED, DIRECTION_DOWN},
+		"new-remote.txt": {ACTION_DOWNLOADED, DIRECTION_DOWN},
+		"local.txt":      {ACTION_UPLOADED, DIRECTION_UP},
+		"new-local.txt":  {ACTION_UPLOADED, DIRECTION_UP},
+		"both.txt":       {ACTION_CONFLICT, DIRECTION_DOWN},
+		"deleted.txt":    {ACTION_DELETED, DIRECTION_UP},
+	}
+	if len(plan.Files) != len(want) {
+		t.Errorf("planned %d files, want %d", len(plan.Files), len(want))
+	}
+	var newLocal *PlannedFile
+	for _, plannedFile := range plan.Files {
+		if w := want[plannedFile.Filename]; plannedFile.Action != w.action || plannedFile.Direction != w.direction || plannedFile.Error != "" {
+			t.Errorf("%s planned %+v, want %s %s", plannedFile.Filename, plannedFile, w.action, w.direction)
+		}
+		if plannedFile.Filename == "new-local.txt" {
+			newLocal = plannedFile
+		}
+	}
+	if newLocal == nil || newLocal.Size != 13 || newLocal.NumBlocks != 4 {
+		t.Errorf("new-local.txt planned %+v, want 13 bytes in 4 blocks", newLocal)
+	}
+
+	// Neither the local files and index, nor the remote files and blocks changed
+	for filename, content := range map[string]string{"same.txt": "same", "remote.txt": "remote", "local.txt": "local 2", "both.txt": "both from B", "new-local.txt": "fresh content"} {
+		if read, err := os.ReadFile(filepath.Join(dirB, filename)); err != nil || string(read) != content {
+			t.Errorf("%s changed to %q, %v", filename, read, err)
+		}
+	}
+	for _, filename := range []string{"deleted.txt", "new-remote.txt", JOURNAL_FILENAME} {
+		if _, err := os.Stat(filepath.Join(dirB, filename)); !os.IsNotExist(err) {
+			t.Errorf("%s exists: %v", filename, err)
+		}
+	}
+	if planned, err := os.ReadFile(indexPath); err != nil || string(planned) != string(index) {
+		t.Errorf("local index changed: %v", err)
+	}
+	remoteIndex = make(map[string]*FileMetaData)
+	if err := client.GetFileInfoMap(&remoteIndex); err != nil {
+		t.Fatal(err)
+	}
+	for filename, fileMetaData := range remoteIndex {
+		if fileMetaData.GetVersion() != remoteVersions[filename] {
+			t.Errorf("remote %s changed to version %d", filename, fileMetaData.GetVersion())
+		}
+	}
+	var uploaded []string
+	if err := client.HasBlocks(newLocal.local.GetBlockHashList(), addr, &uploaded); err != nil || len(uploaded) != 0 {
+		t.Errorf("blocks of new-local.txt uploaded: %v, %v", uploaded, err)
+	}
+}
+
+func TestPlanRenames(t *testing.T) {
+	file := func(filename string, version int32, hashes ...string) *FileMetaData {
+		return &FileMetaData{Filename: filename, Version: version, BlockHashList: hashes}
+	}
+	tombstone := func(filename string, version int32, renamedTo string) *FileMetaData {
+		return &FileMetaData{Filename: filename, Version: version, BlockHashList: []string{TOMBSTONE_HASH}, RenamedTo: renamedTo}
+	}
+	renamedFrom := func(fileMetaData *FileMetaData, oldFilename string) *FileMetaData {
+		fileMetaData.RenamedFrom = oldFilename
+		return fileMetaData
+	}
+
+	scanned := map[string]*FileMetaData{
+		"new.txt":       file("new.txt", 0, "h1", "h2"),
+		"copy.txt":      file("copy.txt", 0, "h1", "h2"),
+		"unrelated.txt": file("unrelated.txt", 0, "h3"),
+		"b.txt":         file("b.txt", 1, "h4"),
+		"edited.txt":    file("edited.txt", 1, "h6"),
+	}
+	local := map[string]*FileMetaData{
+		"old.txt":    file("old.txt", 1, "h1", "h2"),
+		"b.txt":      file("b.txt", 1, "h4"),
+		"edited.txt": file("edited.txt", 1, "h5"),
+	}
+	remote := map[string]*FileMetaData{
+		"old.txt":       file("old.txt", 1, "h1", "h2"),
+		"b.txt":         tombstone("b.txt", 2, "c.txt"),
+		"c.txt":         renamedFrom(file("c.txt", 1, "h4"), "b.txt"),
+		"edited.txt":    tombstone("edited.txt", 2, "moved.txt"),
+		"moved.txt":     renamedFrom(file("moved.txt", 1, "h5"), "edited.txt"),
+		"other.txt":     renamedFrom(file("other.txt", 1, "h7"), "gone.txt"),
+		"unrelated.txt": tombstone("unrelated.txt", 3, ""),
+	}
+
+	plan := buildPlan(scanned, nil, local, remote)
+	planned := make(map[string]*PlannedFile)
+	for _, plannedFile := range plan.Files {
+		planned[plannedFile.Filename] = plannedFile
+	}
+
+	// Renamed locally: the first new file with the same blocks takes the rename, the other is uploaded
+	if p := planned["copy.txt"]; p.Action != ACTION_RENAMED || p.Direction != DIRECTION_UP || p.RenamedFrom != "old.txt" {
+		t.Errorf("copy.txt planned %+v, want renamed up from old.txt", p)
+	}
+	if p := planned["new.txt"]; p.Action != ACTION_UPLOADED {
+		t.Errorf("new.txt planned %+v, want uploaded", p)
+	}
+	if _, ok := planned["old.txt"]; ok {
+		t.Errorf("old.txt planned separately from its rename")
+	}
+
+	// Renamed remotely
+	if p := planned["c.txt"]; p.Action != ACTION_RENAMED || p.Direction != DIRECTION_DOWN || p.RenamedFrom != "b.txt" {
+		t.Errorf("c.txt planned %+v, want renamed down from b.txt", p)
+	}
+	if _, ok := planned["b.txt"]; ok {
+		t.Errorf("b.txt planned separately from its rename")
+	}
+
+	// Renames whose old file was modified locally, or is unknown locally, are downloaded
+	if p := planned["moved.txt"]; p.Action != ACTION_DOWNLOADED {
+		t.Errorf("moved.txt planned %+v, want downloaded", p)
+	}
+	if p := planned["other.txt"]; p.Action != ACTION_DOWNLOADED {
+		t.Errorf("other.txt planned %+v, want downloaded", p)
+	}
+	if p := planned["unrelated.txt"]; p.Action != ACTION_UPLOADED {
+		t.Errorf("unrelated.txt planned %+v, want uploaded", p)
+	}
+	if plan.Count(ACTION_RENAMED) != 2 {
+		t.Errorf("%d renames planned, want 2", plan.Count(ACTION_RENAMED))
+	}
+}
@@ -0,0 +1,90 @@
+package servestore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrSyncIncomplete is returned by Syncer.Sync and ClientSync when at least one file failed to sync.
+// The other files are still synced and recorded in the returned SyncReport.
+var ErrSyncIncomplete = errors.New("ErrSyncIncomplete")
+
+// SyncAction describes what a sync did with a single file
+type SyncAction string
+
+const (
+	ACTION_UPLOADED   SyncAction = "uploaded"
+	ACTION_DOWNLOADED SyncAction = "downloaded"
+	ACTION_DELETED    SyncAction = "deleted"
+	ACTION_CONFLICT   SyncAction = "conflict"
+	ACTION_SKIPPED    SyncAction = "skipped"
+	ACTION_RENAMED    SyncAction = "renamed"
+)
+
+// SyncDirection describes which side of a sync was changed by an action
+type SyncDirection string
+
+const (
+	DIRECTION_NONE SyncDirection = ""
+	DIRECTION_UP   SyncDirection = "up"   // The remote MetaStore/BlockStore was changed
+	DIRECTION_DOWN SyncDirection = "down" // The local base directory was changed
+)
+
+// FileReport records the outcome of syncing a single file
+type FileReport struct {
+	Filename        string        `json:"filename"`
+	Action          SyncAction    `json:"action"`
+	Direction       SyncDirection `json:"direction,omitempty"`
+	Version         int32         `json:"version"`
+	BytesUploaded   int64         `json:"bytesUploaded"`
+	BytesDownloaded int64         `json:"bytesDownloaded"`
+	RenamedFrom     string        `json:"renamedFrom,omitempty"` // Previous name of a renamed file
+	Error           string        `json:"error,omitempty"`
+}
+
+// SyncReport records the outcome of every file handled by a sync
+type SyncReport struct {
+	Files []*FileReport `json:"files"`
+}
+
+func (r *SyncReport) add(fileReport *FileReport) {
+	r.Files = append(r.Files, fileReport)
+}
+
+// sortFiles orders the report by filename so output does not depend on scan order
+func (r *SyncReport) sortFiles() {
+	sort.SliceStable(r.Files, func(i, j int) bool {
+		return r.Files[i].Filename < r.Files[j].Filename
+	})
+}
+
+// Failed returns the reports of files that could not be synced
+func (r *SyncReport) Failed() []*FileReport {
+	failed := make([]*FileReport, 0)
+	for _, fileReport := range r.Files {
+		if fileReport.Error != "" {
+			failed = append(failed, fileReport)
+		}
+	}
+	return failed
+}
+
+// Count returns the number of files synced with the given action
+func (r *SyncReport) Count(action SyncAction) int {
+	count := 0
+	for _, fileReport := range r.Files {
+		if fileReport.Action == action && fileReport.Error == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// Err returns an error wrapping ErrSyncIncomplete if any file failed to sync
+func (r *SyncReport) Err() error {
+	if failed := r.Failed(); len(failed) > 0 {
+		return fmt.Errorf("%w: %d file(s) failed to sync", ErrSyncIncomplete, len(failed))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package servestore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSyncReport(t *testing.T) {
+	report := &SyncReport{Files: make([]*FileReport, 0)}
+	report.add(&FileReport{Filename: "c.txt", Action: ACTION_UPLOADED, Direction: DIRECTION_UP, Version: 2})
+	report.add(&FileReport{Filename: "a.txt", Action: ACTION_DOWNLOADED, Direction: DIRECTION_DOWN, Version: 1})
+	report.add(&FileReport{Filename: "b.txt", Action: ACTION_UPLOADED, Direction: DIRECTION_UP, Error: "connection refused"})
+	report.add(&FileReport{Filename: "d.txt", Action: ACTION_UPLOADED, Direction: DIRECTION_UP, Version: 1})
+
+	// Failed files are not counted as synced
+	if count := report.Count(ACTION_UPLOADED); count != 2 {
+		t.Errorf("%d files uploaded, want 2", count)
+	}
+	if count := report.Count(ACTION_CONFLICT); count != 0 {
+		t.Errorf("%d conflicts, want 0", count)
+	}
+	if failed := report.Failed(); len(failed) != 1 || failed[0].Filename != "b.txt" {
+		t.Errorf("failed files %+v, want b.txt", failed)
+	}
+	if err := report.Err(); !errors.Is(err, ErrSyncIncomplete) {
+		t.Errorf("error of a report with a failed file: %v, want ErrSyncIncomplete", err)
+	}
+
+	report.sortFiles()
+	for i, filename := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		if report.Files[i].Filename != filename {
+			t.Errorf("file %d of the sorted report is %s, want %s", i, report.Files[i].Filename, filename)
+		}
+	}
+
+	succeeded := &SyncReport{Files: []*FileReport{{Filename: "a.txt", Action: ACTION_CONFLICT, Direction: DIRECTION_DOWN}}}
+	if err := succeeded.Err(); err != nil {
+		t.Errorf("error of a report without failed files: %v", err)
+	}
+	if err := (&SyncReport{}).Err(); err != nil {
+		t.Errorf("error of an empty report: %v", err)
+	}
+}
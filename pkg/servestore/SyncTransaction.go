@@ -0,0 +1,135 @@
+package servestore
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrTransactionAborted is recorded for the files of a transaction that was not committed
+// because other files of it were updated by another client. They are retried by the next sync.
+var ErrTransactionAborted = errors.New("ErrTransactionAborted")
+
+// pendingCommit is an update of the remote metadata of a file held back to be committed in
+// the transaction of its directory
+type pendingCommit struct {
+	fileMetaData *FileMetaData
+	action       SyncAction
+	committed    func(latestVersion int32)
+}
+
+func (s *Syncer) holdCommit(commit *pendingCommit) {
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	directory := path.Dir(commit.fileMetaData.GetFilename())
+	s.pendingCommits[directory] = append(s.pendingCommits[directory], commit)
+}
+
+// commitTransactions commits the held back updates of each directory in a single
+// UpdateFiles transaction, in directory order. Files the plan found to conflict with
+// another client's updates abort the transaction of their directory.
+func (s *Syncer) commitTransactions(plan *SyncPlan) {
+	plannedConflicts := make(map[string][]string)
+	for _, plannedFile := range plan.Files {
+		if plannedFile.Action == ACTION_CONFLICT {
+			directory := path.Dir(plannedFile.Filename)
+			plannedConflicts[directory] = append(plannedConflicts[directory], plannedFile.Filename)
+		}
+	}
+
+	directories := make([]string, 0, len(s.pendingCommits))
+	for directory := range s.pendingCommits {
+		directories = append(directories, directory)
+	}
+	sort.Strings(directories)
+
+	for _, directory := range directories {
+		if conflictFilenames := plannedConflicts[directory]; len(conflictFilenames) > 0 {
+			log.Println("Not committing", directory, "since files in it were updated by another client")
+			s.failCommits(s.pendingCommits[directory], abortedError(conflictFilenames))
+			continue
+		}
+		s.commitTransaction(directory, s.pendingCommits[directory])
+	}
+	s.pendingCommits = nil
+}
+
+// commitTransaction commits the updates of a directory. If another client updated any of the
+// files first, none is committed: the files that conflict are downloaded, and the others are
+// recorded as failed with ErrTransactionAborted.
+func (s *Syncer) commitTransaction(directory string, commits []*pendingCommit) {
+	log.Println("Committing", len(commits), "file(s) in", directory)
+
+	files := make([]*FileMetaData, 0, len(commits))
+	for _, commit := range commits {
+		if err := s.journal.recordPending(commit.fileMetaData); err != nil {
+			s.failCommits(commits, err)
+			return
+		}
+		files = append(files, commit.fileMetaData)
+	}
+
+	var conflicts []*FileConflict
+	err := s.client.UpdateFiles(files, &conflicts)
+	if status.Code(err) == codes.Unimplemented {
+		log.Println("The MetaStore cannot update files in a transaction, committing", directory, "file by file")
+
+		runConcurrently(len(commits), s.options.concurrency(), func(i int) error {
+			latestVersion, err := s.updateRemoteFile(commits[i].fileMetaData)
+			if err != nil {
+				s.failFile(commits[i].fileMetaData.GetFilename(), commits[i].action, err)
+			} else {
+				commits[i].committed(latestVersion)
+			}
+			return nil
+		})
+		return
+	}
+	if err != nil {
+		s.failCommits(commits, fmt.Errorf("update remote metadata: %w", err))
+		return
+	}
+
+	conflicting := make(map[string]bool)
+	conflictFilenames := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		log.Printf("%s was updated by another client: version %d rejected, remote version is %d", conflict.GetFilename(), conflict.GetVersion(), conflict.GetLatestVersion())
+		conflicting[conflict.GetFilename()] = true
+		conflictFilenames = append(conflictFilenames, conflict.GetFilename())
+	}
+	aborted := abortedError(conflictFilenames)
+
+	runConcurrently(len(commits), s.options.concurrency(), func(i int) error {
+		commit, filename := commits[i], commits[i].fileMetaData.GetFilename()
+		switch {
+		case len(conflicts) == 0:
+			commit.committed(commit.fileMetaData.GetVersion())
+		case conflicting[filename]:
+			if err := s.refreshRemoteFile(filename); err != nil {
+				s.failFile(filename, commit.action, err)
+				return nil
+			}
+			commit.committed(-1)
+		default:
+			s.failFile(filename, commit.action, aborted)
+		}
+		return nil
+	})
+}
+
+func abortedError(conflictFilenames []string) error {
+	return fmt.Errorf("%w: %s updated by another client", ErrTransactionAborted, strings.Join(conflictFilenames, ", "))
+}
+
+func (s *Syncer) failCommits(commits []*pendingCommit, err error) {
+	for _, commit := range commits {
+		s.failFile(commit.fileMetaData.GetFilename(), commit.action, err)
+	}
+}
@@ -0,0 +1,157 @@
+package servestore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// transactionCluster records the files of every UpdateFiles transaction, and calls
+// beforeUpdate, if set, before committing one
+type transactionCluster struct {
+	*fakeCluster
+	transactions [][]string
+	renames      int
+	beforeUpdate func()
+}
+
+func (c *transactionCluster) UpdateFiles(files []*FileMetaData, conflicts *[]*FileConflict) error {
+	filenames := make([]string, 0, len(files))
+	for _, fileMetaData := range files {
+		filenames = append(filenames, fileMetaData.GetFilename())
+	}
+	sort.Strings(filenames)
+	c.mu.Lock()
+	c.transactions = append(c.transactions, filenames)
+	c.mu.Unlock()
+
+	if c.beforeUpdate != nil {
+		c.beforeUpdate()
+	}
+	return c.fakeCluster.UpdateFiles(files, conflicts)
+}
+
+func (c *transactionCluster) RenameFile(fileRename *FileRename, latestVersion *int32) error {
+	c.renames++
+	return c.fakeCluster.RenameFile(fileRename, latestVersion)
+}
+
+func newAtomicTestSyncer(t *testing.T, files map[string]string) (*transactionCluster, *Syncer, string) {
+	cluster := &transactionCluster{fakeCluster: newFakeCluster()}
+	baseDir := t.TempDir()
+	writeTestFiles(t, baseDir, files)
+	syncer := NewSyncer(cluster, baseDir, SyncOptions{Chunker: NewFixedSizeChunker(4), Atomic: true})
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	return cluster, syncer, baseDir
+}
+
+func TestAtomicSyncCommitsEachDirectoryInOneTransaction(t *testing.T) {
+	cluster, _, _ := newAtomicTestSyncer(t, map[string]string{"docs/a.txt": "aaaa", "docs/b.txt": "bbbb", "c.txt": "cccc"})
+
+	want := [][]string{{"c.txt"}, {"docs/a.txt", "docs/b.txt"}}
+	if len(cluster.transactions) != 2 || !equalHashLists(cluster.transactions[0], want[0]) || !equalHashLists(cluster.transactions[1], want[1]) {
+		t.Errorf("transactions %v, want %v", cluster.transactions, want)
+	}
+	for _, filename := range []string{"docs/a.txt", "docs/b.txt", "c.txt"} {
+		if cluster.files[filename].GetVersion() != 1 {
+			t.Errorf("%s committed as %v", filename, cluster.files[filename])
+		}
+	}
+}
+
+func TestAtomicSyncAbortsDirectoryOnConflict(t *testing.T) {
+	cluster, syncer, baseDir := newAtomicTestSyncer(t, map[string]string{"docs/a.txt": "aaaa", "docs/b.txt": "bbbb", "c.txt": "cccc"})
+	writeTestFiles(t, baseDir, map[string]string{"docs/b.txt": "BBBB", "docs/new.txt": "nnnn", "c.txt": "CCCC"})
+	if err := os.Remove(filepath.Join(baseDir, "docs", "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another client updates docs/b.txt once the blocks are uploaded
+	cluster.beforeUpdate = func() {
+		if cluster.files["docs/b.txt"].GetVersion() == 1 {
+			cluster.putFile("docs/b.txt", "remote b", 4, true).Version = 2
+		}
+	}
+	report, err := syncer.Sync()
+	if !errors.Is(err, ErrSyncIncomplete) {
+		t.Fatalf("sync: %v, %+v", err, report)
+	}
+
+	for _, fileReport := range report.Files {
+		switch fileReport.Filename {
+		case "docs/a.txt", "docs/new.txt":
+			if !strings.HasPrefix(fileReport.Error, ErrTransactionAborted.Error()) {
+				t.Errorf("%s: %+v, want ErrTransactionAborted", fileReport.Filename, fileReport)
+			}
+		case "docs/b.txt":
+			if fileReport.Action != ACTION_CONFLICT || fileReport.Error != "" {
+				t.Errorf("docs/b.txt: %+v, want a conflict", fileReport)
+			}
+		case "c.txt":
+			if fileReport.Action != ACTION_UPLOADED || fileReport.Version != 2 {
+				t.Errorf("c.txt: %+v, want uploaded", fileReport)
+			}
+		}
+	}
+
+	// None of the other changes to docs were committed
+	if a := cluster.files["docs/a.txt"]; a.GetVersion() != 1 || isTombstone(a) {
+		t.Errorf("docs/a.txt committed as %v", a)
+	}
+	if _, exists := cluster.files["docs/new.txt"]; exists {
+		t.Error("docs/new.txt committed")
+	}
+	assertTestFiles(t, baseDir, map[string]string{"docs/b.txt": "remote b", "docs/new.txt": "nnnn", "c.txt": "CCCC"})
+
+	// The next sync commits them
+	cluster.beforeUpdate = nil
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !isTombstone(cluster.files["docs/a.txt"]) || cluster.files["docs/new.txt"].GetVersion() != 1 {
+		t.Errorf("retried docs as %v and %v", cluster.files["docs/a.txt"], cluster.files["docs/new.txt"])
+	}
+}
+
+func TestAtomicSyncCommitsRenamesInTransactions(t *testing.T) {
+	cluster, syncer, baseDir := newAtomicTestSyncer(t, map[string]string{"docs/a.txt": "aaaaaaaa", "docs/b.txt": "bbbb"})
+	if err := os.Mkdir(filepath.Join(baseDir, "moved"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(baseDir, "docs", "a.txt"), filepath.Join(baseDir, "moved", "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, baseDir, map[string]string{"docs/b.txt": "BBBB"})
+	cluster.transactions = nil
+
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"docs/a.txt", "docs/b.txt"}, {"moved/a.txt"}}
+	if cluster.renames != 0 || len(cluster.transactions) != 2 || !equalHashLists(cluster.transactions[0], want[0]) || !equalHashLists(cluster.transactions[1], want[1]) {
+		t.Errorf("%d renames, transactions %v, want %v", cluster.renames, cluster.transactions, want)
+	}
+	if !isTombstone(cluster.files["docs/a.txt"]) || cluster.files["moved/a.txt"].GetSize() != 8 {
+		t.Errorf("renamed to %v, leaving %v", cluster.files["moved/a.txt"], cluster.files["docs/a.txt"])
+	}
+}
+
+func TestAtomicSyncSkipsDirectoryWithPlannedConflict(t *testing.T) {
+	cluster, syncer, baseDir := newAtomicTestSyncer(t, map[string]string{"docs/a.txt": "aaaa", "docs/b.txt": "bbbb"})
+	writeTestFiles(t, baseDir, map[string]string{"docs/a.txt": "AAAA", "docs/b.txt": "BBBB"})
+	cluster.putFile("docs/b.txt", "remote b", 4, true).Version = 2
+	cluster.transactions = nil
+
+	report, err := syncer.Sync()
+	if !errors.Is(err, ErrSyncIncomplete) || report.Count(ACTION_CONFLICT) != 1 || len(cluster.transactions) != 0 {
+		t.Fatalf("sync: %v, %+v, transactions %v", err, report, cluster.transactions)
+	}
+	if a := cluster.files["docs/a.txt"]; a.GetVersion() != 1 {
+		t.Errorf("docs/a.txt committed as %v", a)
+	}
+}
@@ -0,0 +1,290 @@
+package servestore
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// SyncOptions are the policies of a Syncer
+type SyncOptions struct {
+	// Cuts files into blocks, blocks of DEFAULT_BLOCK_SIZE bytes if nil
+	Chunker Chunker
+
+	// Gitignore-style patterns of paths to leave out of, or bring back into, a sync.
+	// See IgnoreRules.
+	Excludes []string
+	Includes []string
+
+	// Sync extended attributes (Linux only) in addition to file mode and modification time
+	Xattrs bool
+
+	// How symbolic links are synced, LINK_PRESERVE if empty
+	LinkPolicy LinkPolicy
+
+	// Read and hash every file, instead of trusting the stat cache of files whose size,
+	// modification time, status change time and inode are unchanged since the last sync
+	Rehash bool
+
+	// Number of files hashed or synced, and of blocks of a file transferred, at once.
+	// DEFAULT_CONCURRENCY if zero.
+	Concurrency int
+
+	// Bytes of blocks held in memory at once while hashing, uploading and downloading files,
+	// across all files. Files are streamed, so this bounds the client's memory use however
+	// large the files are. DEFAULT_BUFFER_SIZE if zero.
+	BufferSize int64
+
+	// Commit the uploads and remote deletions of the files of each directory in a single
+	// transaction, so other clients see all or none of them. Renames are committed as an
+	// upload and a deletion.
+	Atomic bool
+
+	// Called with every SyncEvent of a sync or plan, one event at a time, from the goroutine
+	// that made progress, so it should return quickly. To receive the events on a channel,
+	// send them from it.
+	Events func(event SyncEvent)
+}
+
+func (options *SyncOptions) concurrency() int {
+	if options.Concurrency < 1 {
+		return DEFAULT_CONCURRENCY
+	}
+	return options.Concurrency
+}
+
+func (options *SyncOptions) bufferSize() int64 {
+	if options.BufferSize < 1 {
+		return DEFAULT_BUFFER_SIZE
+	}
+	return options.BufferSize
+}
+
+// Syncer syncs a base directory with the MetaStore of a client. It owns the state of its
+// syncs, so Syncers of different base directories can run at the same time, in the same
+// process. The syncs of a single Syncer run one at a time.
+type Syncer struct {
+	client  ClientInterface
+	baseDir string
+	options SyncOptions
+
+	// Held for the whole of a sync or plan
+	running sync.Mutex
+
+	localIndex          map[string]*FileMetaData
+	localStats          map[string]*FileStat
+	localIndexWriteTime time.Time
+	remoteIndex         map[string]*FileMetaData
+	scannedIndex        map[string]*FileMetaData
+	scannedStats        map[string]*FileStat
+	scanErrors          map[string]error
+	scanJobs            []*scanJob
+	scannedHardlinks    []*FileMetaData
+	ignoreRules         *IgnoreRules
+	ignoredIndex        map[string]*FileMetaData
+	skippedLinks        map[string]bool
+	hardlinks           map[fileID]string
+	blockPlacement      *BlockPlacement
+	syncedLocalIndex    map[string]*FileMetaData
+	syncedStats         map[string]*FileStat
+	interrupted         *interruptedSync
+	journal             *SyncJournal
+	report              *SyncReport
+
+	// Guards the state shared by files synced concurrently: syncedLocalIndex, syncedStats,
+	// report, remoteIndex and pendingCommits
+	syncMutex sync.Mutex
+
+	// Bounds the bytes of blocks held in memory while hashing, uploading and downloading files
+	bufferLimiter *byteLimiter
+
+	// The updates held back to be committed in the transaction of their directory, by
+	// slash-separated directory name
+	pendingCommits map[string][]*pendingCommit
+
+	// Serializes the calls to SyncOptions.Events, which are made from concurrent transfers
+	eventMutex sync.Mutex
+}
+
+// NewSyncer creates a Syncer of baseDir with the MetaStore and BlockStores of client
+func NewSyncer(client ClientInterface, baseDir string, options SyncOptions) *Syncer {
+	if options.Chunker == nil {
+		options.Chunker = NewFixedSizeChunker(DEFAULT_BLOCK_SIZE)
+	}
+	if options.LinkPolicy == "" {
+		options.LinkPolicy = LINK_PRESERVE
+	}
+	return &Syncer{client: client, baseDir: baseDir, options: options}
+}
+
+// syncer returns a Syncer of the client's base directory with the policies set on it
+func (surfClient *RPCClient) syncer() *Syncer {
+	options := surfClient.SyncOptions
+	if options.Chunker == nil {
+		options.Chunker = NewFixedSizeChunker(surfClient.BlockSize)
+	}
+	return NewSyncer(surfClient, surfClient.BaseDir, options)
+}
+
+func (s *Syncer) blockSize() int {
+	return s.options.Chunker.BlockSize()
+}
+
+// Sync syncs the base directory with the MetaStore.
+// Files are synced independently: a file that fails to sync is recorded in the
+// returned SyncReport and keeps its previous local index entry, and Sync
+// returns an error wrapping ErrSyncIncomplete. Any other error means the sync
+// could not be run at all.
+//
+// Progress is recorded in a SyncJournal, so a sync that is interrupted (e.g. by a
+// crash) is resumed by the next one, and downloads are written to temporary files
+// that only replace the local file once complete.
+func (s *Syncer) Sync() (*SyncReport, error) {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	plan, err := s.planSync()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.interrupted != nil {
+		if err := s.recoverInterruptedSync(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.journal, err = createSyncJournal(s.baseDir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { s.journal = nil }()
+
+	s.syncedLocalIndex = make(map[string]*FileMetaData) // Store synced local index file metadata
+	s.syncedStats = make(map[string]*FileStat)
+	s.report = &SyncReport{Files: make([]*FileReport, 0)}
+
+	s.blockPlacement, err = FetchBlockPlacement(s.client) // Get remote BlockStores
+	if err != nil {
+		return nil, err
+	}
+
+	// Carry out the planned action for every file, syncing up to SyncOptions.Concurrency files
+	// at once. Hard links are synced last, so that the files they link to are up to date
+	// locally and can be linked to.
+	plannedFiles, plannedHardlinks := make([]*PlannedFile, 0), make([]*PlannedFile, 0)
+	for _, plannedFile := range plan.Files {
+		if plannedFile.remote.GetHardlinkTarget() == "" {
+			plannedFiles = append(plannedFiles, plannedFile)
+		} else {
+			plannedHardlinks = append(plannedHardlinks, plannedFile)
+		}
+	}
+	s.pendingCommits = make(map[string][]*pendingCommit)
+	for _, plannedFiles := range [][]*PlannedFile{plannedFiles, plannedHardlinks} {
+		plannedFiles := plannedFiles
+		runConcurrently(len(plannedFiles), s.options.concurrency(), func(i int) error {
+			s.syncPlannedFile(plannedFiles[i])
+			return nil
+		})
+	}
+	s.commitTransactions(plan)
+
+	// Ignored files keep their local index entries untouched
+	for filename, fileMetaData := range s.ignoredIndex {
+		s.syncedLocalIndex[filename] = fileMetaData
+		if fileStat, exists := s.localStats[filename]; exists {
+			s.syncedStats[filename] = fileStat
+		}
+	}
+
+	// Update local index with synced local index, after which the journal is no longer needed
+	if err := WriteLocalIndex(&LocalIndex{Files: s.syncedLocalIndex, Stats: s.syncedStats}, s.baseDir); err != nil {
+		return nil, err
+	}
+	if err := s.journal.remove(); err != nil {
+		return nil, err
+	}
+
+	s.report.sortFiles()
+	return s.report, s.report.Err()
+}
+
+// Plan computes what Sync would do without uploading, downloading or changing any file,
+// local or remote.
+func (s *Syncer) Plan() (*SyncPlan, error) {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	return s.planSync()
+}
+
+func (s *Syncer) planSync() (*SyncPlan, error) {
+	// Clear the state of the previous sync
+	s.scannedIndex = make(map[string]*FileMetaData)
+	s.scannedStats = make(map[string]*FileStat)
+	s.scanErrors = make(map[string]error)
+	s.scanJobs = make([]*scanJob, 0)
+	s.scannedHardlinks = make([]*FileMetaData, 0)
+	s.ignoreRules = NewIgnoreRules(s.options.Excludes, s.options.Includes)
+	s.ignoredIndex = make(map[string]*FileMetaData)
+	s.skippedLinks = make(map[string]bool)
+	s.hardlinks = make(map[fileID]string)
+	s.bufferLimiter = newByteLimiter(s.options.bufferSize())
+
+	loadedLocalIndex, err := getLocalIndex(s.baseDir) // Get local FileMetaInfo map from local index file (index.txt)
+	if err != nil {
+		return nil, err
+	}
+	s.localIndex, s.localStats, s.localIndexWriteTime = loadedLocalIndex.Files, loadedLocalIndex.Stats, loadedLocalIndex.WriteTime
+
+	// Files synced by an interrupted sync are resumed from their journaled local index entries
+	s.interrupted, err = loadSyncJournal(s.baseDir)
+	if err != nil {
+		return nil, err
+	}
+	if s.interrupted != nil {
+		log.Println("Resuming interrupted sync")
+
+		for filename, fileMetaData := range s.interrupted.done {
+			s.localIndex[filename] = fileMetaData
+			delete(s.localStats, filename)
+			if fileStat, exists := s.interrupted.doneStats[filename]; exists {
+				s.localStats[filename] = fileStat
+			}
+		}
+	}
+
+	s.remoteIndex, err = s.getRemoteIndex() // Get remote FileMetaInfo map from server
+	if err != nil {
+		return nil, err
+	}
+
+	// Scan all files in client's base directory
+	if err := s.scanFiles(s.baseDir); err != nil {
+		return nil, err
+	}
+
+	if s.interrupted != nil {
+		s.resolvePendingFiles()
+	}
+
+	// Leave ignored files and skipped links out of the plan: they are never deleted remotely or downloaded
+	for filename, fileMetaData := range s.localIndex {
+		if s.ignoreRules.Ignored(filename, false) || s.skippedLinks[filename] {
+			log.Println("Ignoring local index entry:", filename)
+			s.ignoredIndex[filename] = fileMetaData
+			delete(s.localIndex, filename)
+		}
+	}
+	for filename, fileMetaData := range s.remoteIndex {
+		if s.ignoreRules.Ignored(filename, false) || s.skippedLinks[filename] || isClientFile(filename) || isSymlink(fileMetaData) && s.options.LinkPolicy == LINK_SKIP {
+			log.Println("Ignoring remote file:", filename)
+			delete(s.remoteIndex, filename)
+		}
+	}
+
+	plan := buildPlan(s.scannedIndex, s.scanErrors, s.localIndex, s.remoteIndex)
+	s.emitPlannedEvent(plan)
+	return plan, nil
+}
@@ -0,0 +1,60 @@
+package servestore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSyncersRunConcurrently(t *testing.T) {
+	clusters := []*fakeCluster{newFakeCluster(), newFakeCluster()}
+	dirs := []string{t.TempDir(), t.TempDir()}
+	for i, dir := range dirs {
+		for j := 0; j < 5; j++ {
+			content := fmt.Sprintf("root %d file %d", i, j)
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.txt", j)), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	reports := make([]*SyncReport, len(dirs))
+	errs := make([]error, len(dirs))
+	for i := range dirs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			syncer := NewSyncer(clusters[i], dirs[i], SyncOptions{Chunker: NewFixedSizeChunker(3 + i)})
+			reports[i], errs[i] = syncer.Sync()
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range dirs {
+		if errs[i] != nil || reports[i].Count(ACTION_UPLOADED) != 5 {
+			t.Fatalf("sync of root %d: %v, %+v", i, errs[i], reports[i])
+		}
+		if remote := clusters[i].files["f0.txt"]; remote.GetBlockSize() != int32(3+i) || remote.GetSize() != 13 {
+			t.Errorf("root %d uploaded %v", i, remote)
+		}
+		if len(clusters[i].files) != 5 {
+			t.Errorf("root %d uploaded %d files, want 5", i, len(clusters[i].files))
+		}
+	}
+
+	// A new root syncing with the first cluster downloads its files
+	dir := t.TempDir()
+	report, err := NewSyncer(clusters[0], dir, SyncOptions{Chunker: NewFixedSizeChunker(3)}).Sync()
+	if err != nil || report.Count(ACTION_DOWNLOADED) != 5 {
+		t.Fatalf("download: %v, %+v", err, report)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "f3.txt")); err != nil || string(content) != "root 0 file 3" {
+		t.Errorf("downloaded %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DEFAULT_META_FILENAME)); err != nil {
+		t.Errorf("local index: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package servestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+)
+
+var ErrInvalidTransferLimits = errors.New("ErrInvalidTransferLimits")
+
+// TransferLimits caps the bytes per second a client uploads to and downloads from the
+// BlockStores, across all its concurrent block transfers. Zero means unlimited.
+type TransferLimits struct {
+	UploadBytesPerSecond   int64 `json:"uploadBytesPerSecond,omitempty"`
+	DownloadBytesPerSecond int64 `json:"downloadBytesPerSecond,omitempty"`
+
+	// Limits used instead while the MetaStore is reached through one of the metered
+	// interfaces (e.g. a mobile hotspot), by name. Unset metered limits fall back to the
+	// limits above.
+	MeteredUploadBytesPerSecond   int64    `json:"meteredUploadBytesPerSecond,omitempty"`
+	MeteredDownloadBytesPerSecond int64    `json:"meteredDownloadBytesPerSecond,omitempty"`
+	MeteredInterfaces             []string `json:"meteredInterfaces,omitempty"`
+}
+
+// LoadTransferLimits reads and validates a JSON transfer limits file
+func LoadTransferLimits(path string) (*TransferLimits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read transfer limits: %w", err)
+	}
+
+	limits := &TransferLimits{}
+	if err := json.Unmarshal(data, limits); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTransferLimits, path, err)
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, path)
+	}
+	return limits, nil
+}
+
+// Validate checks that no limit is negative
+func (limits *TransferLimits) Validate() error {
+	for _, limit := range []int64{limits.UploadBytesPerSecond, limits.DownloadBytesPerSecond,
+		limits.MeteredUploadBytesPerSecond, limits.MeteredDownloadBytesPerSecond} {
+		if limit < 0 {
+			return fmt.Errorf("%w: negative limit %d", ErrInvalidTransferLimits, limit)
+		}
+	}
+	return nil
+}
+
+// rates returns the upload and download limits that apply on a metered or unmetered interface
+func (limits *TransferLimits) rates(metered bool) (int64, int64) {
+	upload, download := limits.UploadBytesPerSecond, limits.DownloadBytesPerSecond
+	if metered && limits.MeteredUploadBytesPerSecond > 0 {
+		upload = limits.MeteredUploadBytesPerSecond
+	}
+	if metered && limits.MeteredDownloadBytesPerSecond > 0 {
+		download = limits.MeteredDownloadBytesPerSecond
+	}
+	return upload, download
+}
+
+// SetTransferLimits applies the limits to the client's block transfers, including those
+// already waiting on its limiters. The metered limits are used if the MetaStore is reached
+// through one of the metered interfaces, which is checked again on every call so that it
+// can follow a change of network. Returns whether the metered limits were used.
+func (surfClient *RPCClient) SetTransferLimits(limits *TransferLimits) (bool, error) {
+	if err := limits.Validate(); err != nil {
+		return false, err
+	}
+
+	metered := false
+	if len(limits.MeteredInterfaces) > 0 {
+		name, err := routeInterface(surfClient.MetaStoreAddr)
+		if err != nil {
+			return false, fmt.Errorf("find the interface to the MetaStore: %w", err)
+		}
+		for _, meteredName := range limits.MeteredInterfaces {
+			if name == meteredName {
+				metered = true
+			}
+		}
+	}
+
+	upload, download := limits.rates(metered)
+	if surfClient.UploadLimiter == nil {
+		surfClient.UploadLimiter = NewRateLimiter(0)
+	}
+	if surfClient.DownloadLimiter == nil {
+		surfClient.DownloadLimiter = NewRateLimiter(0)
+	}
+	surfClient.UploadLimiter.SetRate(upload)
+	surfClient.DownloadLimiter.SetRate(download)
+	log.Printf("Transfer limits: %d bytes/s up, %d bytes/s down (metered: %v)", upload, download, metered)
+	return metered, nil
+}
+
+// routeInterface returns the name of the network interface traffic to addr leaves through
+func routeInterface(addr string) (string, error) {
+	// Connecting a UDP socket picks the route without sending anything
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return "", err
+	}
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, ifaceAddr := range addrs {
+			if ipNet, ok := ifaceAddr.(*net.IPNet); ok && ipNet.IP.Equal(localIP) {
+				return iface.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no interface has address %s", localIP)
+}
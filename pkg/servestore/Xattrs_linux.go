@@ -0,0 +1,88 @@
+package servestore
+
+import (
+	"strings"
+	"syscall"
+)
+
+// Only extended attributes in the user namespace are synced: the others are either
+// managed by the kernel or require privileges to set
+const XATTR_NAMESPACE string = "user."
+
+func getXattrs(path string) (map[string][]byte, error) {
+	names, err := listXattrs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	xattrs := make(map[string][]byte)
+	for _, name := range names {
+		size, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size)
+		size, err = syscall.Getxattr(path, name, value)
+		if err != nil {
+			return nil, err
+		}
+		xattrs[name] = value[:size]
+	}
+
+	if len(xattrs) == 0 {
+		return nil, nil
+	}
+	return xattrs, nil
+}
+
+func setXattrs(path string, xattrs map[string][]byte) error {
+	names, err := listXattrs(path)
+	if err != nil {
+		return err
+	}
+
+	// Remove attributes that are no longer present
+	for _, name := range names {
+		if _, exists := xattrs[name]; !exists {
+			if err := syscall.Removexattr(path, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, value := range xattrs {
+		if !strings.HasPrefix(name, XATTR_NAMESPACE) {
+			continue
+		}
+		if err := syscall.Setxattr(path, name, value, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listXattrs returns the names of the file's extended attributes in the user namespace
+func listXattrs(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil {
+		if err == syscall.ENOTSUP {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if strings.HasPrefix(name, XATTR_NAMESPACE) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
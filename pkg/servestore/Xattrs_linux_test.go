@@ -0,0 +1,45 @@
+package servestore
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestXattrsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"src": "content", "dst": "content"})
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := syscall.Setxattr(src, "user.color", []byte("blue"), 0); err != nil {
+		t.Skipf("the file system of %s lacks user extended attributes: %v", dir, err)
+	}
+	for path, name := range map[string]string{src: "user.tag", dst: "user.stale"} {
+		if err := syscall.Setxattr(path, name, []byte("value"), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanned := statAttributes(t, src, true)
+	if len(scanned.GetXattrs()) != 2 || string(scanned.GetXattrs()["user.color"]) != "blue" {
+		t.Fatalf("scanned extended attributes %v", scanned.GetXattrs())
+	}
+	if withoutXattrs := statAttributes(t, src, false); withoutXattrs.GetXattrs() != nil {
+		t.Errorf("extended attributes read without being asked for: %v", withoutXattrs.GetXattrs())
+	}
+
+	// Applying them replaces the attributes of the file, removing stale ones
+	if err := applyFileAttributes(dst, scanned, true); err != nil {
+		t.Fatal(err)
+	}
+	applied := statAttributes(t, dst, true)
+	if !equalXattrs(applied.GetXattrs(), scanned.GetXattrs()) || attributesChanged(applied, scanned) {
+		t.Errorf("applied extended attributes %v, want %v", applied.GetXattrs(), scanned.GetXattrs())
+	}
+
+	if err := syscall.Setxattr(dst, "user.color", []byte("red"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if !attributesChanged(statAttributes(t, dst, true), scanned) {
+		t.Error("extended attribute change not detected")
+	}
+}
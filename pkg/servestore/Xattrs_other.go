@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package servestore
+
+// Extended attributes are only synced on Linux
+
+func getXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func setXattrs(path string, xattrs map[string][]byte) error {
+	return nil
+}
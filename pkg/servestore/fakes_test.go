@@ -0,0 +1,232 @@
+package servestore
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeCluster is a ClientInterface holding the files of a MetaStore and the blocks of each
+// BlockStore in memory. Its ring holds the single BlockStore "a:1"; placements built by tests
+// may use any address. BlockStores in down cannot be reached.
+type fakeCluster struct {
+	mu     sync.Mutex
+	files  map[string]*FileMetaData
+	blocks map[string]map[string]*Block
+	down   map[string]bool
+}
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{
+		files:  make(map[string]*FileMetaData),
+		blocks: make(map[string]map[string]*Block),
+		down:   make(map[string]bool),
+	}
+}
+
+func (f *fakeCluster) GetFileInfoMap(serverFileInfoMap *map[string]*FileMetaData) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for filename, fileMetaData := range f.files {
+		(*serverFileInfoMap)[filename] = proto.Clone(fileMetaData).(*FileMetaData)
+	}
+	return nil
+}
+
+// accepts reports whether fileMetaData can update its file, as MetaStore.accepts does. The
+// caller must hold mu.
+func (f *fakeCluster) accepts(fileMetaData *FileMetaData) bool {
+	current, exists := f.files[fileMetaData.GetFilename()]
+	return !exists || fileMetaData.GetVersion() == current.GetVersion()+1
+}
+
+func (f *fakeCluster) UpdateFile(fileMetaData *FileMetaData, latestVersion *int32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	*latestVersion = -1
+	if f.accepts(fileMetaData) {
+		f.files[fileMetaData.GetFilename()] = proto.Clone(fileMetaData).(*FileMetaData)
+		*latestVersion = fileMetaData.GetVersion()
+	}
+	return nil
+}
+
+func (f *fakeCluster) UpdateFiles(files []*FileMetaData, conflicts *[]*FileConflict) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	filenames := make(map[string]bool)
+	for _, fileMetaData := range files {
+		if filenames[fileMetaData.GetFilename()] {
+			return errors.New("ErrDuplicateFile")
+		}
+		filenames[fileMetaData.GetFilename()] = true
+		if !f.accepts(fileMetaData) {
+			*conflicts = append(*conflicts, &FileConflict{
+				Filename:      fileMetaData.GetFilename(),
+				Version:       fileMetaData.GetVersion(),
+				LatestVersion: f.files[fileMetaData.GetFilename()].GetVersion(),
+			})
+		}
+	}
+	if len(*conflicts) == 0 {
+		for _, fileMetaData := range files {
+			f.files[fileMetaData.GetFilename()] = proto.Clone(fileMetaData).(*FileMetaData)
+		}
+	}
+	return nil
+}
+
+func (f *fakeCluster) RenameFile(fileRename *FileRename, latestVersion *int32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	*latestVersion = -1
+	fileMetaData := fileRename.GetFileMetaData()
+	old, ok := f.files[fileRename.GetOldFilename()]
+	if !ok || isTombstone(old) || old.GetVersion() != fileRename.GetOldVersion() || !f.accepts(fileMetaData) {
+		return nil
+	}
+	renamed := proto.Clone(fileMetaData).(*FileMetaData)
+	renamed.RenamedFrom = old.GetFilename()
+	f.files[renamed.GetFilename()] = renamed
+	f.files[old.GetFilename()] = &FileMetaData{Filename: old.GetFilename(), Version: old.GetVersion() + 1, BlockHashList: []string{TOMBSTONE_HASH}, RenamedTo: renamed.GetFilename()}
+	*latestVersion = renamed.GetVersion()
+	return nil
+}
+
+func (f *fakeCluster) GetBlockStoreAddr(blockStoreAddr *string) error {
+	*blockStoreAddr = "a:1"
+	return nil
+}
+
+func (f *fakeCluster) GetBlockStoreRing(blockStoreRing *BlockStoreRing) error {
+	*blockStoreRing = *NewClusterConfig([]string{"a:1"}).BlockStoreRing()
+	return nil
+}
+
+func (f *fakeCluster) GetRingEpoch(epoch *int64) error {
+	*epoch = 0
+	return nil
+}
+
+func (f *fakeCluster) RegisterBlockStore(blockStoreAddr string, weight int, epoch *int64) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeCluster) Heartbeat(blockStoreAddr string, weight int, epoch *int64) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeCluster) GetMembership(membership *Membership) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeCluster) GetFileVersions(filename string, versions *[]*FileMetaData) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeCluster) GetBlock(blockHash string, blockStoreAddr string, block *Block) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down[blockStoreAddr] {
+		return errors.New("connection refused")
+	}
+	stored, ok := f.blocks[blockStoreAddr][blockHash]
+	if !ok {
+		return errors.New("ErrBlockNotFound")
+	}
+	block.BlockData, block.BlockSize = stored.BlockData, stored.BlockSize
+	return nil
+}
+
+func (f *fakeCluster) PutBlock(block *Block, blockStoreAddr string, succ *bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down[blockStoreAddr] {
+		return errors.New("connection refused")
+	}
+	if f.blocks[blockStoreAddr] == nil {
+		f.blocks[blockStoreAddr] = make(map[string]*Block)
+	}
+	f.blocks[blockStoreAddr][GetBlockHashString(block.GetBlockData())] = block
+	*succ = true
+	return nil
+}
+
+func (f *fakeCluster) HasBlocks(blockHashesIn []string, blockStoreAddr string, blockHashesOut *[]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down[blockStoreAddr] {
+		return errors.New("connection refused")
+	}
+	for _, blockHash := range blockHashesIn {
+		if _, ok := f.blocks[blockStoreAddr][blockHash]; ok {
+			*blockHashesOut = append(*blockHashesOut, blockHash)
+		}
+	}
+	return nil
+}
+
+func (f *fakeCluster) GetBlockSizes(blockHashesIn []string, blockStoreAddr string, blockSizesOut *map[string]int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down[blockStoreAddr] {
+		return errors.New("connection refused")
+	}
+	for _, blockHash := range blockHashesIn {
+		if block, ok := f.blocks[blockStoreAddr][blockHash]; ok {
+			(*blockSizesOut)[blockHash] = int64(block.GetBlockSize())
+		}
+	}
+	return nil
+}
+
+func (f *fakeCluster) ListBlocks(start string, end string, blockStoreAddr string, blockHashesOut *[]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down[blockStoreAddr] {
+		return errors.New("connection refused")
+	}
+	for blockHash := range f.blocks[blockStoreAddr] {
+		if inHashRange(blockHash, start, end) {
+			*blockHashesOut = append(*blockHashesOut, blockHash)
+		}
+	}
+	return nil
+}
+
+// holders returns the number of BlockStores holding a block
+func (f *fakeCluster) holders(blockHash string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	held := 0
+	for _, blocks := range f.blocks {
+		if _, ok := blocks[blockHash]; ok {
+			held++
+		}
+	}
+	return held
+}
+
+// putFile stores content cut into blocks of blockSize, recording the block size if record is set
+func (f *fakeCluster) putFile(filename string, content string, blockSize int, record bool) *FileMetaData {
+	fileMetaData := &FileMetaData{Filename: filename, Version: 1, Size: int64(len(content)), Mtime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()}
+	if record {
+		fileMetaData.BlockSize = int32(blockSize)
+	}
+	for start := 0; start < len(content); start += blockSize {
+		end := start + blockSize
+		if end > len(content) {
+			end = len(content)
+		}
+		block := &Block{BlockData: []byte(content[start:end]), BlockSize: int32(end - start)}
+		var success bool
+		f.PutBlock(block, "a:1", &success)
+		fileMetaData.BlockHashList = append(fileMetaData.BlockHashList, GetBlockHashString(block.BlockData))
+	}
+	f.mu.Lock()
+	f.files[filename] = fileMetaData
+	f.mu.Unlock()
+	return fileMetaData
+}
@@ -0,0 +1,260 @@
+// Package servestoretest runs a ServeStore cluster in-process for integration tests: a
+// MetaStore and any number of BlockStores served over gRPC on ephemeral localhost ports,
+// with helpers to create client directories, sync them, and check the remote and local
+// files.
+package servestoretest
+
+import (
+	"bytes"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"rcjng/pkg/servestore"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// Cluster is a MetaStore and its BlockStores, each served on its own localhost port until
+// the test ends
+type Cluster struct {
+	MetaStoreAddr   string
+	BlockStoreAddrs []string
+	MetaStore       *servestore.MetaStore
+	BlockStores     []*servestore.BlockStore
+
+	// Block size of the clients returned by Client, servestore.DEFAULT_BLOCK_SIZE unless changed
+	BlockSize int
+
+	t                 testing.TB
+	metaStoreServer   *grpc.Server
+	blockStoreServers []*grpc.Server
+}
+
+// NewCluster starts a MetaStore and numBlockStores BlockStores, storing each block on
+// replicationFactor of them (1 if zero). BlockStores do not send heartbeats, so they are
+// never suspected of being down. The cluster is stopped when the test ends.
+func NewCluster(t testing.TB, numBlockStores int, replicationFactor int) *Cluster {
+	t.Helper()
+
+	c := &Cluster{BlockSize: servestore.DEFAULT_BLOCK_SIZE, t: t}
+	t.Cleanup(c.stop)
+
+	for i := 0; i < numBlockStores; i++ {
+		blockStore := servestore.NewBlockStore()
+		server := grpc.NewServer()
+		servestore.RegisterBlockStoreServer(server, blockStore)
+		c.BlockStores = append(c.BlockStores, blockStore)
+		c.BlockStoreAddrs = append(c.BlockStoreAddrs, c.serve(server))
+		c.blockStoreServers = append(c.blockStoreServers, server)
+	}
+
+	c.MetaStore = servestore.NewMetaStoreFromConfig(&servestore.ClusterConfig{BlockStoreAddrs: c.BlockStoreAddrs, ReplicationFactor: replicationFactor})
+	c.metaStoreServer = grpc.NewServer()
+	servestore.RegisterMetaStoreServer(c.metaStoreServer, c.MetaStore)
+	c.MetaStoreAddr = c.serve(c.metaStoreServer)
+	return c
+}
+
+// serve serves server on an ephemeral localhost port, and returns its address
+func (c *Cluster) serve(server *grpc.Server) string {
+	c.t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		c.t.Fatalf("listen: %v", err)
+	}
+	go server.Serve(listener)
+	return listener.Addr().String()
+}
+
+func (c *Cluster) stop() {
+	if c.metaStoreServer != nil {
+		c.metaStoreServer.Stop()
+	}
+	for _, server := range c.blockStoreServers {
+		server.Stop()
+	}
+}
+
+// StopBlockStore stops the i-th BlockStore, as if it crashed
+func (c *Cluster) StopBlockStore(i int) {
+	c.blockStoreServers[i].Stop()
+}
+
+// Client returns an RPCClient of the cluster syncing baseDir
+func (c *Cluster) Client(baseDir string) servestore.RPCClient {
+	return servestore.NewServeStoreRPCClient(c.MetaStoreAddr, baseDir, c.BlockSize)
+}
+
+// NewClientDir creates a temporary client base directory holding files, by slash-separated
+// name
+func (c *Cluster) NewClientDir(files map[string]string) string {
+	c.t.Helper()
+
+	dir := c.t.TempDir()
+	WriteFiles(c.t, dir, files)
+	return dir
+}
+
+// Sync syncs baseDir with the cluster, failing the test if any file could not be synced
+func (c *Cluster) Sync(baseDir string) *servestore.SyncReport {
+	c.t.Helper()
+
+	report, err := servestore.ClientSync(c.Client(baseDir))
+	if err != nil {
+		c.t.Fatalf("sync %s: %v", baseDir, err)
+	}
+	return report
+}
+
+// FileInfoMap returns the metadata of every remote file, deleted files included
+func (c *Cluster) FileInfoMap() map[string]*servestore.FileMetaData {
+	c.t.Helper()
+
+	fileInfoMap := make(map[string]*servestore.FileMetaData)
+	client := c.Client("")
+	if err := client.GetFileInfoMap(&fileInfoMap); err != nil {
+		c.t.Fatalf("get remote index: %v", err)
+	}
+	return fileInfoMap
+}
+
+// RemoteFiles returns the content of every remote file that is not deleted, by name
+func (c *Cluster) RemoteFiles() map[string]string {
+	c.t.Helper()
+
+	files := make(map[string]string)
+	client := c.Client("")
+	for filename, fileMetaData := range c.FileInfoMap() {
+		if isTombstone(fileMetaData) {
+			continue
+		}
+		var content bytes.Buffer
+		if _, err := servestore.CatFile(client, filename, &content); err != nil {
+			c.t.Fatalf("read remote file %s: %v", filename, err)
+		}
+		files[filename] = content.String()
+	}
+	return files
+}
+
+// AssertRemoteFiles checks that the remote files that are not deleted are exactly files
+func (c *Cluster) AssertRemoteFiles(files map[string]string) {
+	c.t.Helper()
+
+	assertFiles(c.t, "remote", c.RemoteFiles(), files)
+}
+
+// AssertRemoteVersion checks the version of a remote file
+func (c *Cluster) AssertRemoteVersion(filename string, version int32) {
+	c.t.Helper()
+
+	fileMetaData, exists := c.FileInfoMap()[filename]
+	if !exists || fileMetaData.GetVersion() != version {
+		c.t.Errorf("remote %s is %v, want version %d", filename, fileMetaData, version)
+	}
+}
+
+// AssertLocalFiles checks that the files of baseDir, apart from the client's own, are
+// exactly files
+func (c *Cluster) AssertLocalFiles(baseDir string, files map[string]string) {
+	c.t.Helper()
+
+	assertFiles(c.t, "local", LocalFiles(c.t, baseDir), files)
+}
+
+// AssertSynced checks that the files of every base directory are the remote files
+func (c *Cluster) AssertSynced(baseDirs ...string) {
+	c.t.Helper()
+
+	remoteFiles := c.RemoteFiles()
+	for _, baseDir := range baseDirs {
+		assertFiles(c.t, baseDir, LocalFiles(c.t, baseDir), remoteFiles)
+	}
+}
+
+// WriteFiles writes files, by slash-separated name, under dir, creating their directories
+func WriteFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+
+	for filename, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(filename))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// LocalFiles returns the content of the files under baseDir, by slash-separated name,
+// leaving out the client's local index, journal and temporary files
+func LocalFiles(t testing.TB, baseDir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.WalkDir(baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		filename, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+		filename = filepath.ToSlash(filename)
+		if filename == servestore.DEFAULT_META_FILENAME || filename == servestore.JOURNAL_FILENAME || strings.HasPrefix(d.Name(), servestore.TEMP_FILE_PREFIX) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filename] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("read local files of %s: %v", baseDir, err)
+	}
+	return files
+}
+
+func assertFiles(t testing.TB, side string, got map[string]string, want map[string]string) {
+	t.Helper()
+
+	filenames := make(map[string]bool)
+	for filename := range got {
+		filenames[filename] = true
+	}
+	for filename := range want {
+		filenames[filename] = true
+	}
+	sorted := make([]string, 0, len(filenames))
+	for filename := range filenames {
+		sorted = append(sorted, filename)
+	}
+	sort.Strings(sorted)
+
+	for _, filename := range sorted {
+		gotContent, exists := got[filename]
+		wantContent, wanted := want[filename]
+		switch {
+		case !wanted:
+			t.Errorf("%s file %s exists, want none", side, filename)
+		case !exists:
+			t.Errorf("%s file %s missing, want %q", side, filename, wantContent)
+		case gotContent != wantContent:
+			t.Errorf("%s file %s is %q, want %q", side, filename, gotContent, wantContent)
+		}
+	}
+}
+
+func isTombstone(fileMetaData *servestore.FileMetaData) bool {
+	blockHashList := fileMetaData.GetBlockHashList()
+	return len(blockHashList) == 1 && blockHashList[0] == servestore.TOMBSTONE_HASH
+}
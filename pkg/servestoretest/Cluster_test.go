@@ -0,0 +1,46 @@
+package servestoretest
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"rcjng/pkg/servestore"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestClusterSyncsClients(t *testing.T) {
+	c := NewCluster(t, 3, 2)
+	c.BlockSize = 4
+
+	dirA := c.NewClientDir(map[string]string{"a.txt": "aaaaaaaaaa", "docs/b.txt": "bbbb"})
+	report := c.Sync(dirA)
+	if report.Count(servestore.ACTION_UPLOADED) != 2 {
+		t.Errorf("first sync: %+v", report.Files)
+	}
+	c.AssertRemoteFiles(map[string]string{"a.txt": "aaaaaaaaaa", "docs/b.txt": "bbbb"})
+	c.AssertRemoteVersion("a.txt", 1)
+
+	dirB := c.NewClientDir(nil)
+	c.Sync(dirB)
+	c.AssertLocalFiles(dirB, map[string]string{"a.txt": "aaaaaaaaaa", "docs/b.txt": "bbbb"})
+
+	// Each block is stored on two of the three BlockStores, so one can be lost
+	WriteFiles(t, dirB, map[string]string{"a.txt": "changed by B"})
+	if err := os.Remove(filepath.Join(dirB, "docs", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	c.Sync(dirB)
+	c.StopBlockStore(0)
+	c.Sync(dirA)
+
+	c.AssertSynced(dirA, dirB)
+	c.AssertLocalFiles(dirA, map[string]string{"a.txt": "changed by B"})
+	c.AssertRemoteVersion("a.txt", 2)
+	c.AssertRemoteVersion("docs/b.txt", 2)
+}